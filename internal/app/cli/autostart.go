@@ -149,6 +149,10 @@ func enableSystemd() error {
 // - systemd automatically manages logs via journald (use 'journalctl -u sing-helm' to view)
 // - Application log (/var/log/sing-helm/sing-helm.log): Main business logic logs
 //
+// Signals:
+// - 'systemctl reload sing-helm' sends SIGHUP, which rebuilds and reloads the configuration
+// - SIGUSR1 reopens the application log after external rotation
+//
 // This ensures compatibility regardless of installation method (apt, manual, brew, etc.)
 func getSystemdUnitContent() (string, error) {
 	exe, err := os.Executable()
@@ -170,6 +174,7 @@ Type=simple
 Restart=on-failure
 RestartSec=2
 ExecStart=` + exe + ` run --home ` + appHome + ` --log ` + appLog + `
+ExecReload=/bin/kill -HUP $MAINPID
 RuntimeDirectory=sing-helm
 RuntimeDirectoryMode=0755
 LogsDirectory=sing-helm
@@ -198,6 +203,9 @@ func disableSystemd() error {
 //     Written by internal/logger, this is where you should look for normal operation logs.
 //
 // This separation ensures we never miss critical startup failures while keeping business logs clean.
+//
+// Signals: launchd has no reload action; use 'launchctl kill SIGHUP system/com.kyson.sing-helm'
+// to reload the configuration and SIGUSR1 to reopen the application log.
 func getLaunchdPlistContent() (string, error) {
 	exe, err := os.Executable()
 	if err != nil {
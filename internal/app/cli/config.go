@@ -1,11 +1,15 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
+	coredaemon "github.com/kyson-dev/sing-helm/internal/app/daemon"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/subscription"
 	"github.com/kyson-dev/sing-helm/internal/sys/paths"
 	"github.com/spf13/cobra"
@@ -21,7 +25,9 @@ Available subcommands:
   list     - List base and subscription configs
   add      - Add a subscription config
   edit     - Edit base config or a subscription file
-  refresh  - Refresh subscription cache`,
+  refresh  - Refresh subscription cache
+  generations - List previously applied configs
+  rollback - Restart sing-box on a previous config generation`,
 		// 不设置 RunE，让 cobra 在没有子命令时显示帮助
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -38,17 +44,21 @@ Available subcommands:
 		newConfigEditCommand(),
 		newConfigRefreshCommand(),
 		newConfigDeleteCommand(),
+		newConfigGenerationsCommand(),
+		newConfigRollbackCommand(),
 	)
 
 	return cmd
 }
 
 func newConfigListCommand() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List base and subscription configs",
 		RunE:  runConfigList,
 	}
+	cmd.Flags().BoolP("verbose", "v", false, "Show dedupe strategies and which nodes were merged")
+	return cmd
 }
 
 func newConfigAddCommand() *cobra.Command {
@@ -57,6 +67,8 @@ func newConfigAddCommand() *cobra.Command {
 		priority int
 		enabled  bool
 		dedupe   bool
+		strategy string
+		override string
 	)
 	cmd := &cobra.Command{
 		Use:   "add [name] [url]",
@@ -74,6 +86,19 @@ func newConfigAddCommand() *cobra.Command {
 			if url == "" {
 				return fmt.Errorf("url cannot be empty")
 			}
+			dedupeStrategy, err := model.ParseDedupeStrategy(strategy)
+			if err != nil {
+				return err
+			}
+			var overrideMap map[string]any
+			if override != "" {
+				if err := json.Unmarshal([]byte(override), &overrideMap); err != nil {
+					return fmt.Errorf("invalid --override JSON: %w", err)
+				}
+				if err := subscription.ValidateOverride(overrideMap); err != nil {
+					return err
+				}
+			}
 
 			p := paths.Get()
 			if err := os.MkdirAll(p.SubConfigDir, 0755); err != nil {
@@ -101,6 +126,10 @@ func newConfigAddCommand() *cobra.Command {
 				Priority: priority,
 				Enabled:  &enabled,
 				Dedupe:   &dedupe,
+				Override: overrideMap,
+			}
+			if cmd.Flags().Changed("dedupe-strategy") {
+				source.DedupeStrategy = dedupeStrategy
 			}
 			if err := subscription.SaveSource(p.SubConfigDir, source); err != nil {
 				return err
@@ -114,6 +143,8 @@ func newConfigAddCommand() *cobra.Command {
 	cmd.Flags().IntVar(&priority, "priority", 0, "Priority for dedupe (higher wins)")
 	cmd.Flags().BoolVar(&enabled, "enabled", true, "Enable this subscription")
 	cmd.Flags().BoolVar(&dedupe, "dedupe", true, "Enable dedupe for this subscription")
+	cmd.Flags().StringVar(&strategy, "dedupe-strategy", model.DedupeStrict, "Dedupe strategy: strict, endpoint, or name")
+	cmd.Flags().StringVar(&override, "override", "", `JSON merged into every node's outbound, e.g. '{"tcp_fast_open":true}'`)
 	return cmd
 }
 
@@ -192,3 +223,40 @@ func newConfigDeleteCommand() *cobra.Command {
 		},
 	}
 }
+
+func newConfigGenerationsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "generations",
+		Short: "List previously applied configs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var resp coredaemon.ConfigGenerationsResponse
+			if err := callDaemon(cmd.Context(), "config.generations", nil, &resp); err != nil {
+				return err
+			}
+			printGenerations(cmd, resp.Generations, resp.Current)
+			return nil
+		},
+	}
+}
+
+func newConfigRollbackCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rollback <id>",
+		Short: "Restart sing-box on a previous config generation",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(strings.TrimSpace(args[0]))
+			if err != nil || id <= 0 {
+				return fmt.Errorf("invalid generation id: %s", args[0])
+			}
+			var resp coredaemon.ConfigRollbackResponse
+			if err := callDaemon(cmd.Context(), "config.rollback", coredaemon.ConfigRollbackRequest{ID: id}, &resp); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Rolled back to generation %d.\n", id)
+			printUnrestoredSelections(cmd, resp.ReloadInfo)
+			return nil
+		},
+	}
+}
@@ -2,18 +2,23 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/subscription"
 	"github.com/kyson-dev/sing-helm/internal/sys/paths"
 	"github.com/spf13/cobra"
 )
 
 func runConfigList(cmd *cobra.Command, args []string) error { //nolint:unparam
+	verbose, _ := cmd.Flags().GetBool("verbose")
 	paths := paths.Get()
 	fmt.Fprintf(cmd.OutOrStdout(), "Base Config: %s\n", paths.ConfigFile)
 
@@ -39,8 +44,53 @@ func runConfigList(cmd *cobra.Command, args []string) error { //nolint:unparam
 
 		fmt.Fprintf(cmd.OutOrStdout(), "  - %s (%s, P%d): %s [%s]\n",
 			source.Name, status, source.Priority, source.URL, cacheInfo)
+		if verbose {
+			dedupe := source.DedupeStrategyValue()
+			if !source.DedupeValue() {
+				dedupe = "off"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "      dedupe: %s\n", dedupe)
+			if len(source.Override) > 0 {
+				data, _ := json.Marshal(source.Override)
+				fmt.Fprintf(cmd.OutOrStdout(), "      override: %s\n", data)
+			}
+		}
+	}
+
+	if verbose {
+		return printMergeReport(cmd)
+	}
+	return nil
+}
+
+// printMergeReport 输出去重报告：哪些节点被合并到了哪个规范 tag
+func printMergeReport(cmd *cobra.Command) error {
+	nodes, merges, err := config.InspectNodes()
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "\nNodes: %d generated, %d merged as duplicates\n", len(nodes), len(merges))
+	if len(merges) == 0 {
+		return nil
 	}
 
+	// 按规范 tag 聚合，保持首次出现的顺序
+	var order []string
+	byTag := make(map[string][]nodeProvider.NodeMerge)
+	for _, m := range merges {
+		if _, ok := byTag[m.Tag]; !ok {
+			order = append(order, m.Tag)
+		}
+		byTag[m.Tag] = append(byTag[m.Tag], m)
+	}
+	for _, tag := range order {
+		fmt.Fprintf(out, "  %s\n", tag)
+		for _, m := range byTag[tag] {
+			fmt.Fprintf(out, "    <- %s/%s (%s)\n", m.Source, m.Name, m.Strategy)
+		}
+	}
 	return nil
 }
 
@@ -82,6 +132,9 @@ func refreshAllSubscriptions(cmd *cobra.Command, configDir, cacheDir string) err
 			fmt.Fprintf(cmd.OutOrStdout(), "Skipping disabled subscription: %s\n", source.Name)
 			continue
 		}
+		if source.IsManual() {
+			continue
+		}
 		if err := subscription.Refresh(context.Background(), source, cacheDir); err != nil {
 			fmt.Fprintf(cmd.ErrOrStderr(), "Failed to refresh %s: %v\n", source.Name, err)
 			continue
@@ -152,3 +205,31 @@ func deleteOneSubscription(cmd *cobra.Command, name, configDir, cacheDir string)
 	fmt.Fprintf(cmd.OutOrStdout(), "Deleted subscription: %s\n", name)
 	return nil
 }
+
+// printGenerations 输出配置版本列表，current 为当前运行的版本
+func printGenerations(cmd *cobra.Command, gens []config.Generation, current int) {
+	out := cmd.OutOrStdout()
+	if len(gens) == 0 {
+		fmt.Fprintf(out, "No config generations recorded yet.\n")
+		return
+	}
+	fmt.Fprintf(out, "  %-5s %-20s %-8s %-8s %-7s %-14s %s\n", "ID", "CREATED", "MODE", "ROUTE", "NODES", "PROFILE", "SUBSCRIPTIONS")
+	for _, gen := range gens {
+		mark := " "
+		if gen.ID == current {
+			mark = "*"
+		}
+		profile := gen.Inputs.Profile
+		if len(profile) > 12 {
+			profile = profile[:12]
+		}
+		var subs []string
+		for name, version := range gen.Inputs.Subscriptions {
+			subs = append(subs, name+"@"+version)
+		}
+		sort.Strings(subs)
+		fmt.Fprintf(out, "%s %-5d %-20s %-8s %-8s %-7d %-14s %s\n",
+			mark, gen.ID, gen.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			gen.RunOptions.ProxyMode, gen.RunOptions.RouteMode, gen.Nodes, profile, strings.Join(subs, ", "))
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"path/filepath"
 	"strings"
 
+	coredaemon "github.com/kyson-dev/sing-helm/internal/app/daemon"
 	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
 	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+	"github.com/spf13/cobra"
 )
 
 var errDaemonUnavailable = errors.New("daemon unavailable")
@@ -43,26 +45,100 @@ func ResetCommandSenderFactory() {
 	commandSenderFactory = defaultCommandSenderFactory
 }
 
-// dispatchToDaemon sends a command message to the daemon, returning errDaemonUnavailable when the socket is unreachable.
-func dispatchToDaemon(ctx context.Context, name string, payload map[string]any) (ipc.CommandResult, error) {
+// callDaemon sends a command with a typed request (nil for none) and decodes the response into resp (nil to discard it).
+// It returns errDaemonUnavailable when the socket is unreachable; daemon errors keep their ipc error code.
+func callDaemon(ctx context.Context, name string, req, resp any) error {
+	msg, err := ipc.NewCommand(name, req)
+	if err != nil {
+		return err
+	}
 	sender := commandSenderFactory()
-	resp, err := sender.Send(ctx, ipc.CommandMessage{Name: name, Payload: payload})
+	result, err := sender.Send(ctx, msg)
 	if err != nil {
 		if isDaemonUnavailable(err) {
-			return ipc.CommandResult{}, errDaemonUnavailable
+			return errDaemonUnavailable
 		}
-		return ipc.CommandResult{}, fmt.Errorf("ipc send failed: %w", err)
+		return fmt.Errorf("ipc send failed: %w", err)
 	}
-	if resp.Status == "" {
-		resp.Status = "ok"
-	}
-	if resp.Status != "ok" {
-		if resp.Error != "" {
-			return resp, fmt.Errorf("daemon error: %s", resp.Error)
+	if err := result.Err(); err != nil {
+		if isUnknownCommand(err) {
+			return unsupportedCommand(ctx, sender, name)
 		}
-		return resp, fmt.Errorf("daemon responded with status %s", resp.Status)
+		return fmt.Errorf("daemon error: %w", err)
+	}
+	if resp == nil {
+		return nil
+	}
+	if err := result.Decode(resp); err != nil {
+		return fmt.Errorf("invalid %s response: %w", name, err)
+	}
+	return nil
+}
+
+// isUnknownCommand reports whether the daemon rejected a command it does not implement.
+// Daemons predating error codes only return the message.
+func isUnknownCommand(err error) bool {
+	switch ipc.ErrorCode(err) {
+	case ipc.CodeUnknownCommand:
+		return true
+	case "":
+		return strings.HasPrefix(err.Error(), "unknown command")
+	}
+	return false
+}
+
+// isNotRunning reports whether the daemon failed because sing-box is not running.
+func isNotRunning(err error) bool {
+	switch ipc.ErrorCode(err) {
+	case ipc.CodeNotRunning:
+		return true
+	case "":
+		return err != nil && strings.Contains(err.Error(), "sing-box not running")
+	}
+	return false
+}
+
+// unsupportedCommand explains that the running daemon is older than this client, using hello when the daemon knows it.
+func unsupportedCommand(ctx context.Context, sender ipc.CommandSender, name string) error {
+	hello, err := daemonHello(ctx, sender)
+	if err != nil {
+		return ipc.Errorf(ipc.CodeUnknownCommand,
+			"the running daemon is older than this client and does not support %q; restart it with 'sing-helm stop && sing-helm start'", name)
+	}
+	return ipc.Errorf(ipc.CodeUnknownCommand,
+		"the running daemon (%s, protocol %d) does not support %q, this client speaks protocol %d; restart it with 'sing-helm stop && sing-helm start'",
+		hello.Version, hello.Protocol, name, ipc.ProtocolVersion)
+}
+
+// daemonHello performs the hello handshake, failing for daemons that predate it.
+func daemonHello(ctx context.Context, sender ipc.CommandSender) (coredaemon.HelloResponse, error) {
+	var hello coredaemon.HelloResponse
+	msg, err := ipc.NewCommand("hello", coredaemon.HelloRequest{Protocol: ipc.ProtocolVersion})
+	if err != nil {
+		return hello, err
+	}
+	result, err := sender.Send(ctx, msg)
+	if err != nil {
+		return hello, err
+	}
+	if err := result.Err(); err != nil {
+		return hello, err
+	}
+	if err := result.Decode(&hello); err != nil {
+		return hello, err
+	}
+	return hello, nil
+}
+
+// printUnrestoredSelections reports node selections the daemon could not re-apply after a reload.
+func printUnrestoredSelections(cmd *cobra.Command, info coredaemon.ReloadInfo) {
+	if len(info.Unrestored) == 0 {
+		return
+	}
+	cmd.PrintErrln("Warning: some node selections could not be restored:")
+	for _, item := range info.Unrestored {
+		cmd.PrintErrf("  - %s\n", item)
 	}
-	return resp, nil
 }
 
 func isDaemonUnavailable(err error) bool {
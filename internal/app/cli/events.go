@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	coredaemon "github.com/kyson-dev/sing-helm/internal/app/daemon"
+	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+	"github.com/spf13/cobra"
+)
+
+func newEventsCommand() *cobra.Command {
+	var since uint64
+	var types []string
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Stream daemon events",
+		Long: `Stream daemon events in real-time: mode and route changes, reloads,
+subscription updates, node switches, failover actions and errors.
+
+By default only new events are shown. Use --since 0 to replay the events
+the daemon still keeps, and --type to show only some event types.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := coredaemon.SubscribeRequest{Types: types}
+			if cmd.Flags().Changed("since") {
+				req.Since = &since
+			}
+			out := cmd.OutOrStdout()
+			return subscribeToDaemon(cmd.Context(), req, func(raw json.RawMessage) error {
+				if asJSON {
+					_, err := fmt.Fprintln(out, string(raw))
+					return err
+				}
+				var ev coredaemon.Event
+				if err := json.Unmarshal(raw, &ev); err != nil {
+					return fmt.Errorf("invalid event: %w", err)
+				}
+				_, err := fmt.Fprintln(out, formatEvent(ev))
+				return err
+			})
+		},
+	}
+
+	cmd.Flags().Uint64Var(&since, "since", 0, "Replay events after this sequence number (0 replays all kept events)")
+	cmd.Flags().StringSliceVar(&types, "type", nil, "Only show these event types (e.g. reload,failover)")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print events as newline-delimited JSON")
+
+	return cmd
+}
+
+// subscribeToDaemon opens the daemon event stream and calls fn for every event until ctx is cancelled.
+func subscribeToDaemon(ctx context.Context, req coredaemon.SubscribeRequest, fn func(json.RawMessage) error) error {
+	sender := commandSenderFactory()
+	subscriber, ok := sender.(ipc.CommandSubscriber)
+	if !ok {
+		return fmt.Errorf("event streaming is not supported by this connection")
+	}
+	msg, err := ipc.NewCommand("subscribe", req)
+	if err != nil {
+		return err
+	}
+	err = subscriber.Subscribe(ctx, msg, fn)
+	if isDaemonUnavailable(err) {
+		return errDaemonUnavailable
+	}
+	if isUnknownCommand(err) {
+		return unsupportedCommand(ctx, sender, "subscribe")
+	}
+	return err
+}
+
+// formatEvent renders an event as a single line: time, type and message.
+func formatEvent(ev coredaemon.Event) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s [%s] %s", ev.Time.Local().Format("15:04:05"), ev.Type, ev.Message)
+	if reasons, ok := ev.Data["reasons"].([]any); ok && len(reasons) > 0 {
+		names := make([]string, 0, len(reasons))
+		for _, r := range reasons {
+			names = append(names, fmt.Sprint(r))
+		}
+		fmt.Fprintf(&b, " (%s)", strings.Join(names, ", "))
+	}
+	return b.String()
+}
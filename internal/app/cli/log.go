@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	coredaemon "github.com/kyson-dev/sing-helm/internal/app/daemon"
 	"github.com/kyson-dev/sing-helm/internal/sys/logger"
 	"github.com/kyson-dev/sing-helm/internal/sys/paths"
 	"github.com/nxadm/tail"
@@ -48,13 +49,13 @@ Use --all to view all logs together.`,
 }
 
 func showAppLog(cmd *cobra.Command) {
-	resp, err := dispatchToDaemon(cmd.Context(), "log", nil)
-	if err != nil {
+	var resp coredaemon.LogResponse
+	if err := callDaemon(cmd.Context(), "log", nil, &resp); err != nil {
 		logger.Error("Failed to resolve log path", "error", err)
 		return
 	}
-	logPath, ok := resp.Data["path"].(string)
-	if !ok || logPath == "" {
+	logPath := resp.Path
+	if logPath == "" {
 		logger.Error("Missing log path from daemon")
 		return
 	}
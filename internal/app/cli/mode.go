@@ -2,8 +2,8 @@ package cli
 
 import (
 	"fmt"
-	"strings"
 
+	coredaemon "github.com/kyson-dev/sing-helm/internal/app/daemon"
 	"github.com/spf13/cobra"
 )
 
@@ -20,31 +20,32 @@ Note: This will restart sing-box to apply the new mode.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				resp, err := dispatchToDaemon(cmd.Context(), "status", nil)
-				if err != nil {
+				var status coredaemon.StatusResponse
+				if err := callDaemon(cmd.Context(), "status", nil, &status); err != nil {
 					return err
 				}
 
-				if mode, ok := resp.Data["proxy_mode"].(string); ok && mode != "" {
-					cmd.Printf("Current proxy mode: %s\n", mode)
+				if status.ProxyMode != "" {
+					cmd.Printf("Current proxy mode: %s\n", status.ProxyMode)
 					return nil
 				}
 				return fmt.Errorf("missing proxy mode in daemon status")
 			}
 
 			mode := args[0]
-			resp, err := dispatchToDaemon(cmd.Context(), "mode", map[string]any{"mode": mode})
-			if err != nil {
-				if strings.Contains(err.Error(), "sing-box not running") {
+			var resp coredaemon.ModeResponse
+			if err := callDaemon(cmd.Context(), "mode", coredaemon.ModeRequest{Mode: mode}, &resp); err != nil {
+				if isNotRunning(err) {
 					return fmt.Errorf("sing-box is not running")
 				}
 				return err
 			}
 
-			if newMode, ok := resp.Data["proxy_mode"].(string); ok && newMode != "" {
-				mode = newMode
+			if resp.ProxyMode != "" {
+				mode = resp.ProxyMode
 			}
 			cmd.Printf("Proxy mode switched to: %s\n", mode)
+			printUnrestoredSelections(cmd, resp.ReloadInfo)
 			return nil
 		},
 	}
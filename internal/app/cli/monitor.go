@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	tea "github.com/charmbracelet/bubbletea"
+	coredaemon "github.com/kyson-dev/sing-helm/internal/app/daemon"
 	"github.com/kyson-dev/sing-helm/internal/app/tui/monitor"
 	"github.com/kyson-dev/sing-helm/internal/sys/logger"
 	"github.com/spf13/cobra"
@@ -16,22 +17,20 @@ func newMonitorCommand() *cobra.Command {
 		Short: "Monitor Sing-box traffic",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if host == "" {
-				resp, err := dispatchToDaemon(cmd.Context(), "status", nil)
-				if err != nil {
+				var status coredaemon.StatusResponse
+				if err := callDaemon(cmd.Context(), "status", nil, &status); err != nil {
 					return fmt.Errorf("failed to fetch daemon status: %w", err)
 				}
-				if running, _ := resp.Data["running"].(bool); !running {
+				if !status.Running {
 					return fmt.Errorf("sing-box is not running")
 				}
-				listenAddr, _ := resp.Data["listen_addr"].(string)
-				apiPort, ok := asInt(resp.Data["api_port"])
-				if !ok || apiPort == 0 {
+				if status.APIPort == 0 {
 					return fmt.Errorf("failed to resolve API port from daemon status")
 				}
-				if listenAddr == "" {
+				if status.ListenAddr == "" {
 					return fmt.Errorf("failed to resolve listen address from daemon status")
 				}
-				host = fmt.Sprintf("%s:%d", listenAddr, apiPort)
+				host = fmt.Sprintf("%s:%d", status.ListenAddr, status.APIPort)
 			}
 			logger.Info("run monitor command", "host", host)
 			model := monitor.NewModel(host)
@@ -47,14 +46,3 @@ func newMonitorCommand() *cobra.Command {
 	cmd.Flags().StringVarP(&host, "host", "H", "", "Sing-box API host")
 	return cmd
 }
-func asInt(val any) (int, bool) {
-	switch v := val.(type) {
-	case float64:
-		return int(v), true
-	case int:
-		return v, true
-	case int64:
-		return int(v), true
-	}
-	return 0, false
-}
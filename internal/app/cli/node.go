@@ -1,12 +1,16 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
+	coredaemon "github.com/kyson-dev/sing-helm/internal/app/daemon"
 	"github.com/kyson-dev/sing-helm/internal/proxy/clashapi"
+	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
 	"github.com/kyson-dev/sing-helm/internal/sys/logger"
 	"github.com/spf13/cobra"
 )
@@ -24,6 +28,15 @@ func newNodeCommand() *cobra.Command {
 	// 注册子命令
 	cmd.AddCommand(newListCommand())
 	cmd.AddCommand(newUseCommand())
+	cmd.AddCommand(newNodeTestCommand())
+	cmd.AddCommand(newNodeInfoCommand())
+	cmd.AddCommand(newNodeSpeedtestCommand())
+	cmd.AddCommand(newNodeAddCommand())
+	cmd.AddCommand(newNodeDisableCommand())
+	cmd.AddCommand(newNodeEnableCommand())
+	cmd.AddCommand(newNodeFavoriteCommand())
+	cmd.AddCommand(newNodeUnfavoriteCommand())
+	cmd.AddCommand(newNodeOrderCommand())
 
 	// 定义 PersistentFlag，让子命令都能用到
 	cmd.PersistentFlags().StringVar(&apiAddr, "api", "", "API address")
@@ -31,24 +44,43 @@ func newNodeCommand() *cobra.Command {
 	return cmd
 }
 
+// nodeListOutput 是 node list --json 的输出结构
+type nodeListOutput struct {
+	Groups []nodeListGroup                 `json:"groups"`
+	Nodes  []nodeProvider.NodeEntry        `json:"nodes"`
+	Stats  map[string]coredaemon.NodeStats `json:"stats,omitempty"`
+}
+
+type nodeListGroup struct {
+	Name  string   `json:"name"`
+	Type  string   `json:"type"`
+	Now   string   `json:"now"`
+	Nodes []string `json:"nodes"`
+}
+
 // 1. 实现 List 命令
 func newListCommand() *cobra.Command {
-	return &cobra.Command{
+	var jsonOutput bool
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all proxy groups and nodes",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			payload := map[string]any{}
-			if apiAddr != "" {
-				payload["api"] = apiAddr
-			}
-			resp, err := dispatchToDaemon(cmd.Context(), "node.list", payload)
-			if err != nil {
+			var resp coredaemon.NodeListResponse
+			if err := callDaemon(cmd.Context(), "node.list", coredaemon.NodeListRequest{API: apiAddr}, &resp); err != nil {
 				return fmt.Errorf("failed to list proxies: %w (tip: is sing-helm running?)", err)
 			}
+			proxies := resp.Proxies
+			if proxies == nil {
+				return fmt.Errorf("missing proxies data")
+			}
 
-			proxies, err := decodeProxies(resp.Data["proxies"])
-			if err != nil {
-				return fmt.Errorf("failed to decode proxies: %w", err)
+			// 延迟统计仅用于展示，获取失败不影响列表输出
+			var statsResp coredaemon.NodeStatsResponse
+			_ = callDaemon(cmd.Context(), "node.stats", nil, &statsResp)
+			stats := statsResp.Stats
+
+			if jsonOutput {
+				return printNodeListJSON(cmd, proxies, resp.Nodes, stats)
 			}
 
 			// 简单的美化输出
@@ -64,8 +96,8 @@ func newListCommand() *cobra.Command {
 
 			for _, name := range keys {
 				p := proxies[name]
-				// 我们只关心 Selector 类型的组，因为它们可以切换
-				if p.Type == "Selector" {
+				// 我们只关心 Selector 类型的组，因为它们可以切换（probe 为测速内部组）
+				if p.Type == "Selector" && name != "probe" {
 					fmt.Printf("%-20s %-15s \033[32m%s\033[0m\n", name, p.Type, p.Now)
 					// 可选：打印该组下所有可选节点 (缩进显示)
 					for _, node := range p.All {
@@ -73,6 +105,10 @@ func newListCommand() *cobra.Command {
 						if node == p.Now {
 							mark = "*"
 						}
+						if st, ok := stats[node]; ok {
+							fmt.Printf("  %s %-32s %s\n", mark, node, formatNodeStats(st))
+							continue
+						}
 						fmt.Printf("  %s %s\n", mark, node)
 					}
 				}
@@ -80,6 +116,30 @@ func newListCommand() *cobra.Command {
 			return nil
 		},
 	}
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output groups and nodes (with stable IDs) as JSON")
+	return cmd
+}
+
+// printNodeListJSON 输出 selector 组及节点索引（包含稳定 ID）
+func printNodeListJSON(cmd *cobra.Command, proxies map[string]clashapi.ProxyData, nodes []nodeProvider.NodeEntry, stats map[string]coredaemon.NodeStats) error {
+	out := nodeListOutput{Groups: []nodeListGroup{}, Nodes: nodes, Stats: stats}
+	if out.Nodes == nil {
+		out.Nodes = []nodeProvider.NodeEntry{}
+	}
+	for name, p := range proxies {
+		if p.Type != "Selector" || name == "probe" {
+			continue
+		}
+		out.Groups = append(out.Groups, nodeListGroup{Name: name, Type: p.Type, Now: p.Now, Nodes: p.All})
+	}
+	sort.Slice(out.Groups, func(i, j int) bool { return out.Groups[i].Name < out.Groups[j].Name })
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
 }
 
 // 2. 实现 Use 命令
@@ -93,14 +153,8 @@ func newUseCommand() *cobra.Command {
 			group := args[0]
 			node := args[1]
 
-			payload := map[string]any{
-				"group": group,
-				"node":  node,
-			}
-			if apiAddr != "" {
-				payload["api"] = apiAddr
-			}
-			if _, err := dispatchToDaemon(cmd.Context(), "node.use", payload); err != nil {
+			req := coredaemon.NodeUseRequest{API: apiAddr, Group: group, Node: node}
+			if err := callDaemon(cmd.Context(), "node.use", req, nil); err != nil {
 				return fmt.Errorf("failed to switch node: %w", err)
 			}
 
@@ -110,17 +164,302 @@ func newUseCommand() *cobra.Command {
 	}
 }
 
-func decodeProxies(raw any) (map[string]clashapi.ProxyData, error) {
-	if raw == nil {
-		return nil, fmt.Errorf("missing proxies data")
+// 3. 实现 Test 命令
+func newNodeTestCommand() *cobra.Command {
+	var (
+		all         bool
+		testURL     string
+		timeout     time.Duration
+		concurrency int
+		jsonOutput  bool
+		selectBest  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "test [group]",
+		Short: "Test latency of nodes in a group (or all nodes) concurrently",
+		Example: `  sing-helm node test proxy
+  sing-helm node test --all --concurrency 16 --timeout 5s
+  sing-helm node test proxy --select-best`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := coredaemon.NodeTestRequest{
+				API:         apiAddr,
+				URL:         testURL,
+				Timeout:     int(timeout / time.Millisecond),
+				Concurrency: concurrency,
+				SelectBest:  selectBest,
+			}
+			switch {
+			case len(args) == 1:
+				req.Group = args[0]
+			case all:
+				req.All = true
+			default:
+				return fmt.Errorf("specify a group or --all")
+			}
+			if selectBest && len(args) == 0 {
+				return fmt.Errorf("--select-best requires a group")
+			}
+
+			// 批量测速耗时较长，放宽 IPC 等待时间
+			ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute)
+			defer cancel()
+			var resp coredaemon.NodeTestResponse
+			if err := callDaemon(ctx, "node.test", req, &resp); err != nil {
+				return fmt.Errorf("failed to test nodes: %w", err)
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(resp, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			printLatencyTable(resp.Results)
+			if resp.Selected != "" {
+				fmt.Printf("\nSelected %s for %s\n", resp.Selected, args[0])
+			}
+			return nil
+		},
 	}
-	data, err := json.Marshal(raw)
-	if err != nil {
-		return nil, err
+	cmd.Flags().BoolVar(&all, "all", false, "Test all nodes instead of a single group")
+	cmd.Flags().StringVar(&testURL, "url", "http://www.gstatic.com/generate_204", "URL used for latency testing")
+	cmd.Flags().DurationVar(&timeout, "timeout", 3*time.Second, "Timeout per node")
+	cmd.Flags().IntVar(&concurrency, "concurrency", 8, "Maximum number of nodes tested at once")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output results as JSON")
+	cmd.Flags().BoolVar(&selectBest, "select-best", false, "Switch the group to the fastest node")
+	return cmd
+}
+
+// 4. 实现 Info 命令
+func newNodeInfoCommand() *cobra.Command {
+	var (
+		reveal     bool
+		jsonOutput bool
+	)
+	cmd := &cobra.Command{
+		Use:   "info <tag|id>",
+		Short: "Show details of a generated node",
+		Long: `Show where a generated node comes from and how it connects.
+
+Secrets (uuid, password, private keys, ...) are masked unless --reveal is passed.`,
+		Example: "  sing-helm node info 'HK 01 (sub2)'",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var resp coredaemon.NodeInfoResponse
+			if err := callDaemon(cmd.Context(), "node.info", coredaemon.NodeInfoRequest{Tag: args[0], Reveal: reveal}, &resp); err != nil {
+				return fmt.Errorf("failed to get node info: %w", err)
+			}
+
+			if jsonOutput {
+				data, err := json.MarshalIndent(resp, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+			printNodeInfo(resp)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&reveal, "reveal", false, "Show secrets instead of masking them")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output as JSON")
+	return cmd
+}
+
+// printNodeInfo 以键值形式输出节点详情，嵌套配置以 JSON 展示
+func printNodeInfo(info coredaemon.NodeInfoResponse) {
+	field := func(label string, v any) {
+		if v == nil || v == "" {
+			return
+		}
+		switch v.(type) {
+		case map[string]any, []any:
+			raw, err := json.MarshalIndent(v, "  ", "  ")
+			if err != nil {
+				return
+			}
+			fmt.Printf("%-12s %s\n", label+":", raw)
+		default:
+			fmt.Printf("%-12s %v\n", label+":", v)
+		}
+	}
+
+	field("Tag", info.Tag)
+	field("ID", info.ID)
+	field("Source", info.Source)
+	field("Name", info.Name)
+	field("Protocol", info.Type)
+	field("Server", info.Server)
+	field("Port", info.ServerPort)
+	field("Transport", info.Transport)
+	field("TLS", info.TLS)
+	field("Detour", info.Detour)
+	if len(info.Aliases) > 0 {
+		fmt.Printf("%-12s\n", "Aliases:")
+		for _, a := range info.Aliases {
+			fmt.Printf("  - %s\n", a)
+		}
+	}
+	if info.Outbound != nil {
+		fmt.Println()
+		field("Outbound", info.Outbound)
+	}
+}
+
+// 5. 实现 Speedtest 命令
+func newNodeSpeedtestCommand() *cobra.Command {
+	var (
+		downloadURL string
+		uploadURL   string
+		uploadSize  int
+		timeout     time.Duration
+		jsonOutput  bool
+	)
+	cmd := &cobra.Command{
+		Use:   "speedtest [tag...]",
+		Short: "Measure download/upload throughput through specific nodes",
+		Long: `Measure time-to-first-byte and download/upload throughput through specific nodes.
+
+Traffic is routed through a dedicated loopback inbound, so the active proxy
+selection is not affected. Results are saved by the daemon; run without
+arguments to show the saved results. Use --upload-url "" to skip the upload test.`,
+		Example: `  sing-helm node speedtest 'HK 01' 'JP 01'
+  sing-helm node speedtest 'HK 01' --download-url http://127.0.0.1:8080/100mb --upload-url http://127.0.0.1:8080/upload`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var req coredaemon.NodeSpeedtestRequest
+			if len(args) > 0 {
+				req = coredaemon.NodeSpeedtestRequest{
+					Nodes:       args,
+					DownloadURL: downloadURL,
+					UploadURL:   &uploadURL,
+					UploadBytes: int64(uploadSize),
+					Timeout:     int(timeout / time.Millisecond),
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), time.Duration(len(args)+1)*(2*timeout+10*time.Second))
+			defer cancel()
+			var resp coredaemon.NodeSpeedtestResponse
+			if err := callDaemon(ctx, "node.speedtest", req, &resp); err != nil {
+				return fmt.Errorf("failed to run speed test: %w", err)
+			}
+
+			results := resp.Results
+			if jsonOutput {
+				if results == nil {
+					results = []coredaemon.SpeedResult{}
+				}
+				data, err := json.MarshalIndent(results, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			if len(results) == 0 {
+				fmt.Println("No speed test results.")
+				return nil
+			}
+			printSpeedTable(results)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&downloadURL, "download-url", "https://speed.cloudflare.com/__down?bytes=25000000", "URL downloaded to measure throughput")
+	cmd.Flags().StringVar(&uploadURL, "upload-url", "https://speed.cloudflare.com/__up", "URL receiving the upload (empty to skip)")
+	cmd.Flags().IntVar(&uploadSize, "upload-size", 5<<20, "Upload size in bytes")
+	cmd.Flags().DurationVar(&timeout, "timeout", 15*time.Second, "Time limit for each download/upload")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output results as JSON")
+	return cmd
+}
+
+// printSpeedTable 按下载速度降序输出，失败的排在最后
+func printSpeedTable(results []coredaemon.SpeedResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Error == "") != (b.Error == "") {
+			return a.Error == ""
+		}
+		if a.DownloadMbps != b.DownloadMbps {
+			return a.DownloadMbps > b.DownloadMbps
+		}
+		return a.Tag < b.Tag
+	})
+
+	fmt.Printf("%-32s %8s %12s %12s  %s\n", "NODE", "TTFB", "DOWNLOAD", "UPLOAD", "TESTED")
+	fmt.Println(strings.Repeat("-", 90))
+	for _, r := range results {
+		tested := r.TestedAt.Local().Format("01-02 15:04")
+		if r.Error != "" {
+			fmt.Printf("%-32s \033[31m%s\033[0m  %s\n", r.Tag, r.Error, tested)
+			continue
+		}
+		upload := "-"
+		if r.UploadMbps > 0 {
+			upload = fmt.Sprintf("%.1f Mbps", r.UploadMbps)
+		}
+		fmt.Printf("%-32s %6dms %7.1f Mbps %12s  %s\n", r.Tag, r.TTFBMs, r.DownloadMbps, upload, tested)
 	}
-	var proxies map[string]clashapi.ProxyData
-	if err := json.Unmarshal(data, &proxies); err != nil {
-		return nil, err
+}
+
+// formatNodeStats 输出一行可靠性摘要：p50/p95、成功率、最后存活时间
+func formatNodeStats(st coredaemon.NodeStats) string {
+	rate := fmt.Sprintf("%3.0f%%", st.SuccessRate*100)
+	switch {
+	case st.SuccessRate >= 0.95:
+		rate = "\033[32m" + rate + "\033[0m"
+	case st.SuccessRate >= 0.7:
+		rate = "\033[33m" + rate + "\033[0m"
+	default:
+		rate = "\033[31m" + rate + "\033[0m"
+	}
+	latency := "    -/-    "
+	if st.P50 > 0 {
+		latency = fmt.Sprintf("%4d/%-4dms", st.P50, st.P95)
+	}
+	alive := "never"
+	if !st.LastAlive.IsZero() {
+		alive = formatAgo(time.Since(st.LastAlive))
+	}
+	return fmt.Sprintf("%s %s ok (%d)  alive %s", latency, rate, st.Samples, alive)
+}
+
+// formatAgo 将时间间隔格式化为 "3m ago" 形式
+func formatAgo(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// printLatencyTable 按延迟升序输出结果，失败的排在最后
+func printLatencyTable(results []coredaemon.LatencyResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Error == "") != (b.Error == "") {
+			return a.Error == ""
+		}
+		return a.Error == "" && a.Delay < b.Delay
+	})
+
+	fmt.Printf("%-4s %-40s %s\n", "#", "NODE", "LATENCY")
+	fmt.Println(strings.Repeat("-", 60))
+	for i, r := range results {
+		latency := fmt.Sprintf("\033[32m%dms\033[0m", r.Delay)
+		if r.Error != "" {
+			latency = "\033[31mfailed\033[0m"
+		}
+		fmt.Printf("%-4d %-40s %s\n", i+1, r.Tag, latency)
 	}
-	return proxies, nil
 }
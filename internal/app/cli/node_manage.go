@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	coredaemon "github.com/kyson-dev/sing-helm/internal/app/daemon"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/subscription"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+	"github.com/spf13/cobra"
+)
+
+// newNodeAddCommand 将单个分享链接加入本地 manual 源
+func newNodeAddCommand() *cobra.Command {
+	var name string
+	cmd := &cobra.Command{
+		Use:     "add <uri>",
+		Short:   "Add a single node from a share link",
+		Example: "  sing-helm node add 'vless://uuid@example.com:443?security=tls#My Node'\n  sing-helm node add 'ss://...' --name backup",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p := paths.Get()
+			n, err := subscription.AddManualNode(p.SubConfigDir, p.SubCacheDir, args[0], name)
+			if err != nil {
+				return fmt.Errorf("failed to add node: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Added %s node %q (id %s) to source %q\n", n.Type, n.Name, n.ID, subscription.ManualSourceName)
+			reloadAfterNodeChange(cmd)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&name, "name", "", "Node name (defaults to the name in the link)")
+	return cmd
+}
+
+// newNodeToggleCommand 生成 disable/enable/favorite/unfavorite 命令，均按稳定 ID 记录到 settings.json
+func newNodeToggleCommand(use, short, done string, apply func(s *model.NodeSettings, id string) bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " <tag|id>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entry, ok, err := config.FindNode(args[0])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("node not found: %s", args[0])
+			}
+
+			settingsPath := paths.Get().SettingsFile
+			settings, err := config.LoadSettings(settingsPath)
+			if err != nil {
+				return err
+			}
+			if !apply(&settings.Nodes, entry.ID) {
+				fmt.Fprintf(cmd.OutOrStdout(), "Node %s is already %s.\n", entry.Tag, done)
+				return nil
+			}
+			if err := config.SaveSettings(settingsPath, settings); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Node %s (id %s) %s.\n", entry.Tag, entry.ID, done)
+			reloadAfterNodeChange(cmd)
+			return nil
+		},
+	}
+}
+
+func newNodeDisableCommand() *cobra.Command {
+	return newNodeToggleCommand("disable", "Hide a node from all generated groups", "disabled",
+		func(s *model.NodeSettings, id string) bool { return s.SetDisabled(id, true) })
+}
+
+func newNodeEnableCommand() *cobra.Command {
+	return newNodeToggleCommand("enable", "Re-enable a disabled node", "enabled",
+		func(s *model.NodeSettings, id string) bool { return s.SetDisabled(id, false) })
+}
+
+func newNodeFavoriteCommand() *cobra.Command {
+	return newNodeToggleCommand("favorite", "Pin a node to the top of the proxy selector", "favorited",
+		func(s *model.NodeSettings, id string) bool { return s.SetFavorite(id, true) })
+}
+
+func newNodeUnfavoriteCommand() *cobra.Command {
+	return newNodeToggleCommand("unfavorite", "Remove a node from favorites", "unfavorited",
+		func(s *model.NodeSettings, id string) bool { return s.SetFavorite(id, false) })
+}
+
+// reloadAfterNodeChange 通知运行中的 daemon 重新生成配置；daemon 未运行时下次启动生效
+func reloadAfterNodeChange(cmd *cobra.Command) {
+	var resp coredaemon.ReloadResponse
+	if err := callDaemon(cmd.Context(), "reload", nil, &resp); err != nil {
+		if errors.Is(err, errDaemonUnavailable) {
+			fmt.Fprintln(cmd.OutOrStdout(), "Daemon not running; changes apply on next start.")
+			return
+		}
+		cmd.PrintErrf("Warning: failed to reload daemon: %v\n", err)
+		return
+	}
+	printUnrestoredSelections(cmd, resp.ReloadInfo)
+}
+
+// newNodeOrderCommand 查看或设置生成组内的节点排序策略
+func newNodeOrderCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:       "order [policy]",
+		Short:     "Show or set the node order of generated groups (name, source, region, latency, default)",
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: append([]string{"default"}, nodeProvider.OrderPolicies...),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settingsPath := paths.Get().SettingsFile
+			settings, err := config.LoadSettings(settingsPath)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				order := settings.Groups.Order
+				if order == nodeProvider.OrderDefault {
+					order = "default"
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), order)
+				return nil
+			}
+
+			policy := args[0]
+			if policy == "default" {
+				policy = nodeProvider.OrderDefault
+			}
+			if !nodeProvider.ValidOrderPolicy(policy) {
+				return fmt.Errorf("unknown order policy %q (expected one of: default, %s)", args[0], strings.Join(nodeProvider.OrderPolicies, ", "))
+			}
+			settings.Groups.Order = policy
+			if err := config.SaveSettings(settingsPath, settings); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Group order set to %s.\n", args[0])
+			reloadAfterNodeChange(cmd)
+			return nil
+		},
+	}
+}
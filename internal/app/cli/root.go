@@ -54,6 +54,7 @@ func NewRootCommand() *cobra.Command {
 		newStartCommand(),
 		newStopCommand(),
 		newLogCommand(),
+		newEventsCommand(),
 		newAutostartCommand(),
 		newServeCommand(),
 	)
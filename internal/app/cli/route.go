@@ -2,8 +2,8 @@ package cli
 
 import (
 	"fmt"
-	"strings"
 
+	coredaemon "github.com/kyson-dev/sing-helm/internal/app/daemon"
 	"github.com/spf13/cobra"
 )
 
@@ -21,31 +21,32 @@ Note: This will restart sing-box to apply the new mode.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 {
-				resp, err := dispatchToDaemon(cmd.Context(), "status", nil)
-				if err != nil {
+				var status coredaemon.StatusResponse
+				if err := callDaemon(cmd.Context(), "status", nil, &status); err != nil {
 					return err
 				}
 
-				if mode, ok := resp.Data["route_mode"].(string); ok && mode != "" {
-					cmd.Printf("Current route mode: %s\n", mode)
+				if status.RouteMode != "" {
+					cmd.Printf("Current route mode: %s\n", status.RouteMode)
 					return nil
 				}
 				return fmt.Errorf("missing route mode in daemon status")
 			}
 
 			mode := args[0]
-			resp, err := dispatchToDaemon(cmd.Context(), "route", map[string]any{"route": mode})
-			if err != nil {
-				if strings.Contains(err.Error(), "sing-box not running") {
+			var resp coredaemon.RouteResponse
+			if err := callDaemon(cmd.Context(), "route", coredaemon.RouteRequest{Route: mode}, &resp); err != nil {
+				if isNotRunning(err) {
 					return fmt.Errorf("sing-box is not running")
 				}
 				return err
 			}
 
-			if newMode, ok := resp.Data["route_mode"].(string); ok && newMode != "" {
-				mode = newMode
+			if resp.RouteMode != "" {
+				mode = resp.RouteMode
 			}
 			cmd.Printf("Route mode switched to: %s\n", mode)
+			printUnrestoredSelections(cmd, resp.ReloadInfo)
 			return nil
 		},
 	}
@@ -25,24 +25,24 @@ func newRunCommand() *cobra.Command {
 		Use:   "run",
 		Short: "Run sing-box",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			payload := map[string]any{}
+			var req coredaemon.RunRequest
 			if cmd.Flags().Changed("mode") {
-				payload["mode"] = mode
+				req.Mode = mode
 			}
 			if cmd.Flags().Changed("route") {
-				payload["route"] = rule
+				req.Route = rule
 			}
 			if cmd.Flags().Changed("api-port") {
-				payload["api_port"] = apiPort
+				req.APIPort = apiPort
 			}
 			if cmd.Flags().Changed("mixed-port") {
-				payload["mixed_port"] = mixPort
+				req.MixedPort = mixPort
 			}
 
 			// 无论是前台还是后台运行，都启动 Daemon + IPC + Sing-box
 			// 前台运行：阻塞终端
 			// 后台运行：由 start 命令触发，这里也是阻塞（但在后台进程中）
-			return runAsDaemon(cmd.Context(), payload)
+			return runAsDaemon(cmd.Context(), req)
 		},
 	}
 	cmd.Flags().StringVarP(&mode, "mode", "m", "", "Proxy mode: system, tun, or default")
@@ -53,7 +53,7 @@ func newRunCommand() *cobra.Command {
 }
 
 // runAsDaemon 以 daemon 模式运行：启动 sing-box 和 IPC 服务器
-func runAsDaemon(ctx context.Context, payload map[string]any) error {
+func runAsDaemon(ctx context.Context, req coredaemon.RunRequest) error {
 	logger.Info("Starting in daemon mode")
 
 	// 创建独立的 context，不依赖于命令的 context
@@ -61,21 +61,39 @@ func runAsDaemon(ctx context.Context, payload map[string]any) error {
 	runCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	// 监听系统信号（Ctrl+C, kill）
+	// 创建 daemon 实例
+	d := coredaemon.NewDaemon()
+
+	// 监听系统信号：Ctrl+C/kill 退出，SIGHUP 重新加载配置，SIGUSR1 重新打开日志文件
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1)
 	defer signal.Stop(sigCh)
 
-	// 在后台监听信号，收到信号时取消 context
 	go func() {
-		sig := <-sigCh
-		logger.Info("Received signal, initiating shutdown...", "signal", sig)
-		cancel()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case sig := <-sigCh:
+				switch sig {
+				case syscall.SIGHUP:
+					// reload 可能排队等待，不阻塞后续信号（如 SIGTERM）的处理
+					go reloadOnSignal(runCtx, d)
+				case syscall.SIGUSR1:
+					if err := logger.Reopen(); err != nil {
+						logger.Error("Failed to reopen log file", "error", err)
+						continue
+					}
+					logger.Info("Reopened log file", "signal", sig)
+				default:
+					logger.Info("Received signal, initiating shutdown...", "signal", sig)
+					cancel()
+					return
+				}
+			}
+		}
 	}()
 
-	// 创建 daemon 实例
-	d := coredaemon.NewDaemon()
-
 	// 在后台启动 sing-box 服务（通过 IPC run 命令）
 	go func() {
 		// 等待 IPC 服务器启动
@@ -84,20 +102,18 @@ func runAsDaemon(ctx context.Context, payload map[string]any) error {
 		// 现在（正确）
 		//TODO: 这里可以改进为等待 IPC 服务器真正启动，然后通过统一的dispatchToDaemon发送命令
 		sender := ipc.NewUnixSender(paths.Get().SocketFile)
-		resp, err := sender.Send(context.Background(), ipc.CommandMessage{
-			Name:    "run",
-			Payload: payload,
-		})
+		msg, err := ipc.NewCommand("run", req)
+		if err != nil {
+			logger.Error("Failed to encode run command", "error", err)
+			return
+		}
+		resp, err := sender.Send(context.Background(), msg)
 		if err != nil {
 			logger.Error("Failed to send run command", "error", err)
 			return
 		}
-		if resp.Status != "" && resp.Status != "ok" {
-			if resp.Error != "" {
-				logger.Error("Run command failed", "error", resp.Error)
-			} else {
-				logger.Error("Run command failed", "status", resp.Status)
-			}
+		if err := resp.Err(); err != nil {
+			logger.Error("Run command failed", "code", resp.Code, "error", err)
 			return
 		}
 		logger.Info("Run command sent successfully")
@@ -114,3 +130,19 @@ func runAsDaemon(ctx context.Context, payload map[string]any) error {
 	logger.Info("Daemon stopped gracefully")
 	return nil
 }
+
+// reloadOnSignal 处理 SIGHUP：与 IPC reload 命令走同一路径
+func reloadOnSignal(ctx context.Context, d *coredaemon.Daemon) {
+	logger.Info("Received SIGHUP, reloading configuration")
+	result := d.Handle(ctx, ipc.CommandMessage{Name: "reload"})
+	if err := result.Err(); err != nil {
+		logger.Error("Reload on SIGHUP failed", "error", err)
+		return
+	}
+	var resp coredaemon.ReloadResponse
+	if err := result.Decode(&resp); err == nil && resp.Reloaded != nil && !*resp.Reloaded {
+		logger.Info("Configuration unchanged, sing-box was not reloaded")
+		return
+	}
+	logger.Info("Configuration reloaded on SIGHUP")
+}
@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"time"
 
+	coredaemon "github.com/kyson-dev/sing-helm/internal/app/daemon"
 	"github.com/kyson-dev/sing-helm/internal/sys/logger"
 	"github.com/kyson-dev/sing-helm/internal/sys/paths"
 	"github.com/spf13/cobra"
@@ -18,8 +19,9 @@ func newStartCommand() *cobra.Command {
 		Use:   "start",
 		Short: "Start sing-helm in background",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if resp, err := dispatchToDaemon(cmd.Context(), "status", nil); err == nil {
-				if running, _ := resp.Data["running"].(bool); running {
+			var status coredaemon.StatusResponse
+			if err := callDaemon(cmd.Context(), "status", nil, &status); err == nil {
+				if status.Running {
 					return fmt.Errorf("sing-helm is already running")
 				}
 			}
@@ -88,10 +90,10 @@ func newStartCommand() *cobra.Command {
 					}
 					return fmt.Errorf("daemon failed to start; check logs%s or run with sudo", logHint)
 				case <-ticker.C:
-					resp, err := dispatchToDaemon(cmd.Context(), "status", nil)
-					if err == nil {
+					var status coredaemon.StatusResponse
+					if err := callDaemon(cmd.Context(), "status", nil, &status); err == nil {
 						sawStatus = true
-						if running, _ := resp.Data["running"].(bool); running {
+						if status.Running {
 							fmt.Printf("SingHelm started [PID: %d]\n", command.Process.Pid)
 							if logFile != "" {
 								fmt.Printf("Log file: %s\n", logFile)
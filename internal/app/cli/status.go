@@ -1,9 +1,11 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"time"
 
-	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+	coredaemon "github.com/kyson-dev/sing-helm/internal/app/daemon"
 	"github.com/spf13/cobra"
 )
 
@@ -12,30 +14,51 @@ func newStatusCommand() *cobra.Command {
 		Use:   "status",
 		Short: "Show daemon status",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			resp, err := dispatchToDaemon(cmd.Context(), "status", nil)
-			if err != nil {
+			var status coredaemon.StatusResponse
+			if err := callDaemon(cmd.Context(), "status", nil, &status); err != nil {
 				return err
 			}
-			running, _ := resp.Data["running"].(bool)
-			fmt.Printf("Running: %v\n", running)
+			fmt.Printf("Running: %v\n", status.Running)
 
-			if pid, ok := ipc.AsInt(resp.Data["pid"]); ok && pid != 0 {
-				fmt.Printf("PID: %d\n", pid)
+			if status.PID != 0 {
+				fmt.Printf("PID: %d\n", status.PID)
 			}
-			if mode, ok := resp.Data["proxy_mode"].(string); ok && mode != "" {
-				fmt.Printf("Proxy mode: %s\n", mode)
+			if status.ProxyMode != "" {
+				fmt.Printf("Proxy mode: %s\n", status.ProxyMode)
 			}
-			if mode, ok := resp.Data["route_mode"].(string); ok && mode != "" {
-				fmt.Printf("Route mode: %s\n", mode)
+			if status.RouteMode != "" {
+				fmt.Printf("Route mode: %s\n", status.RouteMode)
 			}
-			if addr, ok := resp.Data["listen_addr"].(string); ok && addr != "" {
-				if apiPort, ok := ipc.AsInt(resp.Data["api_port"]); ok && apiPort != 0 {
-					fmt.Printf("API: %s:%d\n", addr, apiPort)
+			if status.ListenAddr != "" {
+				if status.APIPort != 0 {
+					fmt.Printf("API: %s:%d\n", status.ListenAddr, status.APIPort)
 				}
-				if mixedPort, ok := ipc.AsInt(resp.Data["mixed_port"]); ok && mixedPort != 0 {
-					fmt.Printf("Mixed: %s:%d\n", addr, mixedPort)
+				if status.MixedPort != 0 {
+					fmt.Printf("Mixed: %s:%d\n", status.ListenAddr, status.MixedPort)
 				}
 			}
+			if status.HTTPAddr != "" {
+				fmt.Printf("HTTP API: http://%s\n", status.HTTPAddr)
+			}
+			if status.Watch.Enabled {
+				fmt.Printf("Watch: enabled (%d automatic reloads)\n", status.Watch.Reloads)
+				if status.Watch.LastError != "" {
+					fmt.Printf("Watch: last reload failed: %s\n", status.Watch.LastError)
+				}
+			}
+			if sv := status.Supervisor; sv.Restarts > 0 || sv.LastCrashReason != "" {
+				fmt.Printf("Supervisor: %d restarts after crashes\n", sv.Restarts)
+				if sv.LastCrashReason != "" {
+					fmt.Printf("Supervisor: last crash at %s: %s\n", sv.LastCrash.Local().Format(time.DateTime), sv.LastCrashReason)
+				}
+				if sv.Crashed {
+					fmt.Printf("Supervisor: restarting in %s\n", max(time.Until(sv.NextRestart), 0).Round(time.Second))
+				}
+			}
+			// 旧版 daemon 不支持 hello，此时不显示版本
+			if hello, err := daemonHello(cmd.Context(), commandSenderFactory()); err == nil {
+				fmt.Printf("Daemon: %s (protocol %d)\n", hello.Version, hello.Protocol)
+			}
 
 			return nil
 		},
@@ -47,7 +70,7 @@ func newHealthCommand() *cobra.Command {
 		Use:   "health",
 		Short: "Check daemon health",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if _, err := dispatchToDaemon(cmd.Context(), "health", nil); err != nil {
+			if err := callDaemon(cmd.Context(), "health", nil, nil); err != nil {
 				return err
 			}
 			fmt.Println("ok")
@@ -57,15 +80,27 @@ func newHealthCommand() *cobra.Command {
 }
 
 func newReloadCommand() *cobra.Command {
-	return &cobra.Command{
+	var timeout time.Duration
+	cmd := &cobra.Command{
 		Use:   "reload",
 		Short: "Reload daemon configuration",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if _, err := dispatchToDaemon(cmd.Context(), "reload", nil); err != nil {
+			// 其他 reload 进行中时请求会排队，连接需要等到排队的 reload 完成
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout+5*time.Second)
+			defer cancel()
+			var resp coredaemon.ReloadResponse
+			if err := callDaemon(ctx, "reload", coredaemon.ReloadRequest{Timeout: timeout.String()}, &resp); err != nil {
 				return err
 			}
+			if resp.Reloaded != nil && !*resp.Reloaded {
+				fmt.Println("Configuration unchanged, sing-box was not reloaded.")
+				return nil
+			}
 			fmt.Println("Reloaded.")
+			printUnrestoredSelections(cmd, resp.ReloadInfo)
 			return nil
 		},
 	}
+	cmd.Flags().DurationVar(&timeout, "timeout", 60*time.Second, "How long to wait for queued reloads to finish")
+	return cmd
 }
@@ -13,7 +13,7 @@ func newStopCommand() *cobra.Command {
 		Short: "Stop the running daemon",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// 发送 stop 命令
-			if _, err := dispatchToDaemon(cmd.Context(), "stop", nil); err != nil {
+			if err := callDaemon(cmd.Context(), "stop", nil, nil); err != nil {
 				return fmt.Errorf("failed to stop daemon: %w", err)
 			}
 
@@ -30,8 +30,7 @@ func newStopCommand() *cobra.Command {
 					return fmt.Errorf("timeout waiting for daemon to stop")
 				case <-ticker.C:
 					// 尝试连接 daemon，如果连接失败说明已经停止
-					_, err := dispatchToDaemon(cmd.Context(), "status", nil)
-					if err != nil {
+					if err := callDaemon(cmd.Context(), "status", nil, nil); err != nil {
 						// daemon 已经停止
 						fmt.Println("Daemon stopped successfully.")
 						return nil
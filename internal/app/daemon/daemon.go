@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
 	"github.com/kyson-dev/sing-helm/internal/proxy/engine"
+	"github.com/kyson-dev/sing-helm/internal/proxy/history"
 	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
 	"github.com/kyson-dev/sing-helm/internal/sys/lock"
 	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/netmon"
 	"github.com/kyson-dev/sing-helm/internal/sys/paths"
 	"github.com/kyson-dev/sing-helm/internal/sys/sysnet"
 )
@@ -29,20 +32,34 @@ const tunDNSPlaceholder = "8.8.8.8"
 type ServiceRunner interface {
 	StartFromFile(context.Context, string) error
 	ReloadFromFile(context.Context, string) error
+	// CheckFromFile validates a config without touching the running instance.
+	CheckFromFile(context.Context, string) error
 	Stop()
 }
 
 // Daemon manages the sing-box service lifecycle and responds to IPC commands.
 type Daemon struct {
 	mu             sync.Mutex
+	ctx            context.Context // daemon 生命周期的 context，由 Serve 设置
 	cancelFunc     context.CancelFunc
 	service        ServiceRunner
 	serviceFactory func() ServiceRunner
 	lock           *lock.DaemonLock
 	running        bool
-	reloading      bool
+	reloadMu       sync.Mutex  // 串行化 reload 与回滚
+	reloads        reloadQueue // 待执行的 reload 请求
+	speedMu        sync.Mutex  // 串行化测速（共享 probe selector）
 	state          *RuntimeState
+	events         eventLog
+	history        *history.Store  // 节点延迟历史，首次使用时加载
+	historySave    *time.Timer     // 延迟历史的待执行写盘，见 scheduleHistorySave
+	built          builtConfig     // 当前 raw.json 的构建摘要，用于跳过无变化的 reload
+	watch          watchStatus     // 配置监听触发的 reload 统计
 	dnsMode        model.ProxyMode // 当前已生效的系统 DNS 覆盖所对应的代理模式，空值表示未设置
+	httpAddr       string          // HTTP 控制接口实际监听的地址，未启用时为空
+	supervisor     coreSupervisor  // sing-box 崩溃检测与自动重启
+	failoverWake   wakeup          // 让故障转移看门狗立即探测一轮（如网络切换后）
+	monitor        netmon.Monitor  // 网络变化监听，为 nil 时使用平台默认实现
 }
 
 // NewDaemon builds a daemon controller.
@@ -67,6 +84,13 @@ func (d *Daemon) SetServiceFactory(factory func() ServiceRunner) {
 	d.serviceFactory = factory
 }
 
+// SetNetworkMonitor overrides the network change monitor (useful for tests).
+func (d *Daemon) SetNetworkMonitor(m netmon.Monitor) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.monitor = m
+}
+
 // Serve starts the IPC server. Blocks until ctx is cancelled.
 func (d *Daemon) Serve(ctx context.Context) error {
 
@@ -81,13 +105,25 @@ func (d *Daemon) Serve(ctx context.Context) error {
 	})
 
 	ctx, cancel := context.WithCancel(ctx)
+	d.mu.Lock()
+	d.ctx = ctx
 	d.cancelFunc = cancel
+	d.mu.Unlock()
+	// 后台任务在 cleanup 之前退出，避免与停止 sing-box、保存状态交叉
+	var background sync.WaitGroup
 	defer func() {
 		logger.Info("Daemon shutting down")
 		cancel()
+		background.Wait()
 		d.cleanup()
 	}()
 
+	background.Go(func() { d.runFailoverWatchdog(ctx) })
+	background.Go(func() { d.runConfigWatcher(ctx) })
+	background.Go(func() { d.runHTTPServer(ctx) })
+	background.Go(func() { d.runSupervisor(ctx) })
+	background.Go(func() { d.runNetworkMonitor(ctx) })
+
 	logger.Info("Daemon started, listening for IPC commands")
 
 	if err := ipc.Serve(ctx, paths.Get().SocketFile, d, &ipc.ServerOptions{}); err != nil {
@@ -98,35 +134,17 @@ func (d *Daemon) Serve(ctx context.Context) error {
 
 // Handle routes IPC commands to the proper handlers.
 func (d *Daemon) Handle(ctx context.Context, cmd ipc.CommandMessage) ipc.CommandResult {
-	switch cmd.Name {
-	case "run":
-		return d.handleRun(ctx, cmd.Payload)
-	case "stop":
-		return d.handleStop()
-	case "status":
-		return d.handleStatus()
-	case "mode":
-		return d.handleMode(ctx, cmd.Payload)
-	case "route":
-		return d.handleRoute(ctx, cmd.Payload)
-	case "node.list":
-		return d.handleNodeList(cmd.Payload)
-	case "node.use":
-		return d.handleNodeUse(cmd.Payload)
-	case "log":
-		return d.handleLog()
-	case "health":
-		return d.handleHealth()
-	case "reload":
-		return d.handleReload(ctx)
-	default:
-		return ipc.CommandResult{Status: "error", Error: fmt.Sprintf("unknown command: %s", cmd.Name)}
+	run, ok := commands[cmd.Name]
+	if !ok {
+		return ipc.Fail(ipc.Errorf(ipc.CodeUnknownCommand, "unknown command: %s", cmd.Name))
 	}
+	return run(d, ctx, cmd)
 }
 
 // --- internal helpers ---
 
 func (d *Daemon) cleanup() {
+	d.flushHistory()
 	d.mu.Lock()
 	state := d.state
 
@@ -154,6 +172,8 @@ func (d *Daemon) cleanup() {
 	}
 	d.mu.Lock()
 	if state != nil {
+		// 修改副本：排队的 reload 可能仍在读取 d.state
+		state = state.clone()
 		state.PID = 0
 		if err := SaveState(state); err != nil {
 			logger.Error("Failed to save runtime state", "error", err)
@@ -188,11 +208,62 @@ func (d *Daemon) syncSystemDNS(mode model.ProxyMode) {
 	d.mu.Unlock()
 }
 
+// resetTUNDNS 在 TUN 模式下重新启动 sing-box 之前复位系统 DNS 覆盖（原因见 reloadRawConfig），
+// 启动后的 syncSystemDNS 会重新设置
+func (d *Daemon) resetTUNDNS(mode model.ProxyMode) {
+	if mode != model.ProxyModeTUN {
+		return
+	}
+	d.mu.Lock()
+	d.dnsMode = ""
+	d.mu.Unlock()
+	if err := sysnet.RestoreSystemDNS(); err != nil {
+		logger.Error("Failed to restore system DNS before restart", "error", err)
+	}
+}
+
+// wakeup 让后台循环提前执行一轮，处理前的多次唤醒合并为一次
+type wakeup struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+// C 返回收到唤醒的通道
+func (w *wakeup) C() <-chan struct{} {
+	w.once.Do(func() { w.ch = make(chan struct{}, 1) })
+	return w.ch
+}
+
+func (w *wakeup) notify() {
+	w.C()
+	select {
+	case w.ch <- struct{}{}:
+	default:
+	}
+}
+
 func (d *Daemon) newService() ServiceRunner {
+	var svc ServiceRunner
 	if d.serviceFactory != nil {
-		return d.serviceFactory()
+		svc = d.serviceFactory()
+	} else {
+		svc = engine.NewInstance()
+	}
+	if r, ok := svc.(errorReporter); ok {
+		r.SetErrorHandler(d.coreError)
+	}
+	return svc
+}
+
+// lifetime 返回 daemon 生命周期的 context。sing-box 的启动、reload 等比单个请求存活更久的操作
+// 使用它，而不是调用方的 context；Serve 之前（如直接调用 Handle）为 Background
+func (d *Daemon) lifetime() context.Context {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.ctx == nil {
+		return context.Background()
 	}
-	return engine.NewInstance()
+	return d.ctx
 }
 
 func (d *Daemon) currentState() (*RuntimeState, error) {
@@ -200,8 +271,7 @@ func (d *Daemon) currentState() (*RuntimeState, error) {
 	state := d.state
 	d.mu.Unlock()
 	if state != nil {
-		copyState := *state
-		return &copyState, nil
+		return state.clone(), nil
 	}
 	return nil, nil
 }
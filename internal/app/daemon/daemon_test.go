@@ -1,26 +1,40 @@
 package daemon_test
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/kyson-dev/sing-helm/internal/app/daemon"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
+	"github.com/kyson-dev/sing-helm/internal/proxy/engine"
+	"github.com/kyson-dev/sing-helm/internal/proxy/history"
 	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+	"github.com/kyson-dev/sing-helm/internal/sys/netmon"
 	"github.com/kyson-dev/sing-helm/internal/sys/paths"
 )
 
 type fakeService struct {
-	startPath  string
-	runPath    string
-	reloadPath string
-	runStarted chan struct{}
-	runStopped chan struct{}
-	stopCh     chan struct{}
+	startPath   string
+	runPath     string
+	reloadPath  string
+	reloads     int
+	reloadDelay time.Duration
+	checkErr    error
+	runStarted  chan struct{}
+	runStopped  chan struct{}
+	stopCh      chan struct{}
 }
 
 func newFakeService() *fakeService {
@@ -49,10 +63,19 @@ func (f *fakeService) StartFromFile(ctx context.Context, path string) error {
 }
 
 func (f *fakeService) ReloadFromFile(ctx context.Context, path string) error {
+	time.Sleep(f.reloadDelay)
 	f.reloadPath = path
+	f.reloads++
 	return nil
 }
 
+func (f *fakeService) CheckFromFile(ctx context.Context, path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	return f.checkErr
+}
+
 func (f *fakeService) Stop() {
 	select {
 	case <-f.stopCh:
@@ -61,6 +84,35 @@ func (f *fakeService) Stop() {
 	}
 }
 
+// ctxService 记录 sing-box 启动、reload 时使用的 context，用于检查它们不会随请求结束被取消
+type ctxService struct {
+	*fakeService
+	mu        sync.Mutex
+	startCtx  context.Context
+	reloadCtx context.Context
+}
+
+func (c *ctxService) StartFromFile(ctx context.Context, path string) error {
+	c.mu.Lock()
+	c.startCtx = ctx
+	c.mu.Unlock()
+	return c.fakeService.StartFromFile(ctx, path)
+}
+
+func (c *ctxService) ReloadFromFile(ctx context.Context, path string) error {
+	time.Sleep(c.reloadDelay)
+	c.mu.Lock()
+	c.reloadCtx = ctx
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *ctxService) contexts() (start, reload context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.startCtx, c.reloadCtx
+}
+
 func TestDaemonHandleCommands(t *testing.T) {
 	setupEnv(t)
 
@@ -75,13 +127,7 @@ func TestDaemonHandleCommands(t *testing.T) {
 	})
 
 	// 在后台启动 Daemon Serve，以便初始化上下文和锁
-	go func() {
-		// Serve 会阻塞，直到 ctx 取消
-		_ = d.Serve(ctx)
-	}()
-
-	// 等待 Daemon 启动（Serve 内部会初始化 ctx 和 lock）
-	time.Sleep(100 * time.Millisecond)
+	serveDaemon(t, ctx, d)
 
 	resp := d.Handle(ctx, ipc.CommandMessage{Name: "run", Payload: map[string]any{}})
 	if resp.Status != "ok" {
@@ -97,6 +143,10 @@ func TestDaemonHandleCommands(t *testing.T) {
 	if running, _ := statusResp.Data["running"].(bool); !running {
 		t.Fatalf("expected running true in status")
 	}
+	apiPort, _ := statusResp.Data["api_port"].(int)
+	if apiPort == 0 {
+		t.Fatalf("expected an allocated api port, got %v", statusResp.Data["api_port"])
+	}
 
 	healthResp := d.Handle(ctx, ipc.CommandMessage{Name: "health"})
 	if healthResp.Status != "ok" {
@@ -120,12 +170,26 @@ func TestDaemonHandleCommands(t *testing.T) {
 	if rm, ok := routeDirectResp.Data["route_mode"].(string); !ok || rm != "rule-direct" {
 		t.Fatalf("expected route_mode=rule-direct, got %v", routeDirectResp.Data["route_mode"])
 	}
+	// 自动分配的 API 端口在 reload 之间保持不变，已连接的监控不会断开
+	statusResp = d.Handle(ctx, ipc.CommandMessage{Name: "status"})
+	if port, _ := statusResp.Data["api_port"].(int); port != apiPort {
+		t.Fatalf("expected api port %d to be kept across reloads, got %v", apiPort, statusResp.Data["api_port"])
+	}
 
 	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch {
 		case r.Method == http.MethodGet && r.URL.Path == "/proxies":
 			w.Header().Set("Content-Type", "application/json")
-			_, _ = w.Write([]byte(`{"proxies":{"Proxy":{"type":"Selector","all":["A","B"],"now":"A"}}}`))
+			_, _ = w.Write([]byte(`{"proxies":{"Proxy":{"type":"Selector","all":["auto","A","B","C"],"now":"A"},"auto":{"type":"URLTest"},"A":{"type":"Trojan"},"B":{"type":"Trojan"},"C":{"type":"Trojan"}}}`))
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/delay"):
+			switch r.URL.Path {
+			case "/proxies/A/delay":
+				_, _ = w.Write([]byte(`{"delay":120}`))
+			case "/proxies/B/delay":
+				_, _ = w.Write([]byte(`{"delay":45}`))
+			default:
+				w.WriteHeader(http.StatusGatewayTimeout)
+			}
 		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/proxies/"):
 			w.WriteHeader(http.StatusNoContent)
 		default:
@@ -148,6 +212,33 @@ func TestDaemonHandleCommands(t *testing.T) {
 		t.Fatalf("expected node.use ok, got status=%s error=%s", useResp.Status, useResp.Error)
 	}
 
+	testResp := d.Handle(ctx, ipc.CommandMessage{Name: "node.test", Payload: map[string]any{"api": apiHost, "group": "Proxy", "concurrency": 2, "select_best": true}})
+	if testResp.Status != "ok" {
+		t.Fatalf("expected node.test ok, got status=%s error=%s", testResp.Status, testResp.Error)
+	}
+	results, ok := testResp.Data["results"].([]daemon.LatencyResult)
+	if !ok || len(results) != 3 {
+		t.Fatalf("expected 3 latency results (groups skipped), got %v", testResp.Data["results"])
+	}
+	if results[0].Tag != "B" || results[1].Tag != "A" || results[2].Tag != "C" || results[2].OK() {
+		t.Fatalf("expected results sorted by latency with failures last, got %+v", results)
+	}
+	if selected, _ := testResp.Data["selected"].(string); selected != "B" {
+		t.Fatalf("expected fastest node B to be selected, got %v", testResp.Data["selected"])
+	}
+
+	statsResp := d.Handle(ctx, ipc.CommandMessage{Name: "node.stats", Payload: map[string]any{"nodes": []any{"B", "C"}}})
+	if statsResp.Status != "ok" {
+		t.Fatalf("expected node.stats ok, got status=%s error=%s", statsResp.Status, statsResp.Error)
+	}
+	stats, _ := statsResp.Data["stats"].(map[string]daemon.NodeStats)
+	if b := stats["B"]; b.Samples != 1 || b.P50 != 45 || b.SuccessRate != 1 {
+		t.Fatalf("unexpected stats for B: %+v", b)
+	}
+	if c := stats["C"]; c.Samples != 1 || c.SuccessRate != 0 || !c.LastAlive.IsZero() {
+		t.Fatalf("unexpected stats for C: %+v", c)
+	}
+
 	logResp := d.Handle(ctx, ipc.CommandMessage{Name: "log"})
 	if logResp.Status != "ok" {
 		t.Fatalf("expected log ok, got status=%s error=%s", logResp.Status, logResp.Error)
@@ -163,6 +254,53 @@ func TestDaemonHandleCommands(t *testing.T) {
 	if fake.reloadPath == "" {
 		t.Fatalf("expected reload path to be set")
 	}
+	// 上次 route 切换后输入未变化，不应重新 reload sing-box
+	reloads := fake.reloads
+	if reloaded, ok := reloadResp.Data["reloaded"].(bool); !ok || reloaded {
+		t.Fatalf("expected reloaded=false for unchanged inputs, got %v", reloadResp.Data["reloaded"])
+	}
+
+	// profile 内容变化但生成的配置相同，同样跳过 reload
+	if err := os.WriteFile(paths.Get().ConfigFile, []byte("{ }\n"), 0644); err != nil {
+		t.Fatalf("rewrite profile.json: %v", err)
+	}
+	reloadResp = d.Handle(ctx, ipc.CommandMessage{Name: "reload"})
+	if reloaded, ok := reloadResp.Data["reloaded"].(bool); reloadResp.Status != "ok" || !ok || reloaded {
+		t.Fatalf("expected reload to be skipped for identical output, got %+v", reloadResp)
+	}
+
+	// 生成的配置变化时必须 reload
+	if err := os.WriteFile(paths.Get().ConfigFile, []byte(`{"outbounds":[{"type":"direct","tag":"lan"}]}`), 0644); err != nil {
+		t.Fatalf("rewrite profile.json: %v", err)
+	}
+	reloadResp = d.Handle(ctx, ipc.CommandMessage{Name: "reload"})
+	if reloadResp.Status != "ok" {
+		t.Fatalf("expected reload ok, got status=%s error=%s", reloadResp.Status, reloadResp.Error)
+	}
+	if _, skipped := reloadResp.Data["reloaded"]; skipped || fake.reloads != reloads+1 {
+		t.Fatalf("expected sing-box reload after config change, got %+v (reloads %d -> %d)", reloadResp.Data, reloads, fake.reloads)
+	}
+
+	// 新配置校验失败时拒绝 reload，运行中的配置保持不变
+	running, err := os.ReadFile(paths.Get().RawConfigFile)
+	if err != nil {
+		t.Fatalf("read raw.json: %v", err)
+	}
+	fake.checkErr = errors.New("outbound[0]: unknown type")
+	if err := os.WriteFile(paths.Get().ConfigFile, []byte(`{"outbounds":[{"type":"direct","tag":"lan2"}]}`), 0644); err != nil {
+		t.Fatalf("rewrite profile.json: %v", err)
+	}
+	reloadResp = d.Handle(ctx, ipc.CommandMessage{Name: "reload"})
+	if reloadResp.Status != "error" || !strings.Contains(reloadResp.Error, "unknown type") {
+		t.Fatalf("expected reload to be refused, got %+v", reloadResp)
+	}
+	if fake.reloads != reloads+1 {
+		t.Fatalf("expected no sing-box reload for a rejected config")
+	}
+	if current, _ := os.ReadFile(paths.Get().RawConfigFile); string(current) != string(running) {
+		t.Fatalf("raw.json must not change when the new config is rejected")
+	}
+	fake.checkErr = nil
 
 	stopResp := d.Handle(ctx, ipc.CommandMessage{Name: "stop"})
 	if stopResp.Status != "ok" {
@@ -171,6 +309,411 @@ func TestDaemonHandleCommands(t *testing.T) {
 	waitFor(t, fake.runStopped, "run stop")
 }
 
+func TestDaemonNodeStatsPrefersNodeID(t *testing.T) {
+	setupEnv(t)
+
+	// 旧版本以 tag 记录的延迟与当前以节点 ID 记录的延迟同时存在
+	store, err := history.Open(paths.Get().LatencyFile)
+	if err != nil {
+		t.Fatalf("open history: %v", err)
+	}
+	now := time.Now()
+	store.Record("HK 01", 300, now.Add(-time.Hour))
+	store.Record("abc", 40, now)
+	store.Record("manual", 80, now)
+	if err := store.Save(); err != nil {
+		t.Fatalf("save history: %v", err)
+	}
+	if err := config.SaveNodeIndex(paths.Get().NodeIndexFile, []nodeProvider.NodeEntry{{ID: "abc", Tag: "HK 01"}}); err != nil {
+		t.Fatalf("save node index: %v", err)
+	}
+
+	d := daemon.NewDaemon()
+	resp := d.Handle(context.Background(), ipc.CommandMessage{Name: "node.stats", Payload: map[string]any{"nodes": []any{"HK 01", "manual"}}})
+	if resp.Status != "ok" {
+		t.Fatalf("expected node.stats ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	stats, _ := resp.Data["stats"].(map[string]daemon.NodeStats)
+	if hk := stats["HK 01"]; hk.ID != "abc" || hk.P50 != 40 {
+		t.Fatalf("expected the ID-keyed stats for an indexed tag, got %+v", hk)
+	}
+	if manual := stats["manual"]; manual.ID != "" || manual.P50 != 80 {
+		t.Fatalf("expected tag-keyed stats for an outbound outside the index, got %+v", manual)
+	}
+}
+
+func TestDaemonConfigGenerations(t *testing.T) {
+	setupEnv(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	d := daemon.NewDaemon()
+	fake := newFakeService()
+	d.SetServiceFactory(func() daemon.ServiceRunner {
+		return fake
+	})
+	serveDaemon(t, ctx, d)
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "run", Payload: map[string]any{"route": "rule"}}); resp.Status != "ok" {
+		t.Fatalf("expected run ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStarted, "run start")
+	first, err := os.ReadFile(paths.Get().RawConfigFile)
+	if err != nil {
+		t.Fatalf("read raw.json: %v", err)
+	}
+
+	if err := os.WriteFile(paths.Get().ConfigFile, []byte(`{"outbounds":[{"type":"direct","tag":"lan"}]}`), 0644); err != nil {
+		t.Fatalf("rewrite profile.json: %v", err)
+	}
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "route", Payload: map[string]any{"route": "global"}}); resp.Status != "ok" {
+		t.Fatalf("expected route ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+
+	listResp := d.Handle(ctx, ipc.CommandMessage{Name: "config.generations"})
+	if listResp.Status != "ok" {
+		t.Fatalf("expected config.generations ok, got status=%s error=%s", listResp.Status, listResp.Error)
+	}
+	gens, _ := listResp.Data["generations"].([]config.Generation)
+	if len(gens) != 2 || gens[0].ID != 2 || gens[1].ID != 1 {
+		t.Fatalf("expected generations [2 1], got %+v", gens)
+	}
+	if current, _ := listResp.Data["current"].(int); current != 2 {
+		t.Fatalf("expected current generation 2, got %v", listResp.Data["current"])
+	}
+	if gens[1].Inputs.Profile == gens[0].Inputs.Profile || gens[1].RunOptions.RouteMode != "rule" {
+		t.Fatalf("expected generation 1 to record the original profile and route, got %+v", gens[1])
+	}
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "config.rollback", Payload: map[string]any{"id": 99}}); resp.Status != "error" {
+		t.Fatalf("expected rollback to an unknown generation to fail, got %+v", resp)
+	}
+
+	reloads := fake.reloads
+	rollbackResp := d.Handle(ctx, ipc.CommandMessage{Name: "config.rollback", Payload: map[string]any{"id": 1}})
+	if rollbackResp.Status != "ok" {
+		t.Fatalf("expected rollback ok, got status=%s error=%s", rollbackResp.Status, rollbackResp.Error)
+	}
+	if fake.reloads != reloads+1 {
+		t.Fatalf("expected sing-box to be reloaded on rollback")
+	}
+	if current, _ := os.ReadFile(paths.Get().RawConfigFile); string(current) != string(first) {
+		t.Fatalf("expected raw.json to be restored from generation 1")
+	}
+	statusResp := d.Handle(ctx, ipc.CommandMessage{Name: "status"})
+	if route := fmt.Sprint(statusResp.Data["route_mode"]); route != "rule" {
+		t.Fatalf("expected route mode of generation 1 after rollback, got %s", route)
+	}
+
+	// profile 已与版本 1 不同，reload 重新构建当前配置并记录为新版本
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "reload"}); resp.Status != "ok" {
+		t.Fatalf("expected reload ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	listResp = d.Handle(ctx, ipc.CommandMessage{Name: "config.generations"})
+	if current, _ := listResp.Data["current"].(int); current != 3 {
+		t.Fatalf("expected reload after rollback to record generation 3, got %v", listResp.Data["current"])
+	}
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "stop"}); resp.Status != "ok" {
+		t.Fatalf("expected stop ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStopped, "run stop")
+}
+
+func TestDaemonWatchReloadsOnProfileChange(t *testing.T) {
+	setupEnv(t)
+	if err := os.WriteFile(paths.Get().SettingsFile, []byte(`{"watch":{"enabled":true,"debounce":"50ms"}}`), 0644); err != nil {
+		t.Fatalf("write settings.json: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	d := daemon.NewDaemon()
+	fake := newFakeService()
+	d.SetServiceFactory(func() daemon.ServiceRunner {
+		return fake
+	})
+	serveDaemon(t, ctx, d)
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "run"}); resp.Status != "ok" {
+		t.Fatalf("expected run ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStarted, "run start")
+
+	if err := os.WriteFile(paths.Get().ConfigFile, []byte(`{"outbounds":[{"type":"direct","tag":"lan"}]}`), 0644); err != nil {
+		t.Fatalf("rewrite profile.json: %v", err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		resp := d.Handle(ctx, ipc.CommandMessage{Name: "events"})
+		events, _ := resp.Data["events"].([]daemon.Event)
+		if len(events) > 0 && events[len(events)-1].Type == daemon.EventReload {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a watcher-triggered reload event, got %+v", events)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if fake.reloads != 1 {
+		t.Fatalf("expected exactly one reload, got %d", fake.reloads)
+	}
+	// 计数在 reload 事件推送之后才更新
+	for {
+		watch, _ := d.Handle(ctx, ipc.CommandMessage{Name: "status"}).Data["watch"].(daemon.WatchInfo)
+		if watch.Reloads == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected status to report one automatic reload, got %v", watch)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "stop"}); resp.Status != "ok" {
+		t.Fatalf("expected stop ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStopped, "run stop")
+}
+
+func TestDaemonCoalescesConcurrentReloads(t *testing.T) {
+	setupEnv(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	d := daemon.NewDaemon()
+	fake := newFakeService()
+	fake.reloadDelay = 100 * time.Millisecond
+	d.SetServiceFactory(func() daemon.ServiceRunner {
+		return fake
+	})
+	serveDaemon(t, ctx, d)
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "run", Payload: map[string]any{"route": "rule"}}); resp.Status != "ok" {
+		t.Fatalf("expected run ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStarted, "run start")
+	if err := os.WriteFile(paths.Get().ConfigFile, []byte(`{"outbounds":[{"type":"direct","tag":"lan"}]}`), 0644); err != nil {
+		t.Fatalf("rewrite profile.json: %v", err)
+	}
+
+	// 第一个 reload 执行期间到达的请求排队，合并为下一轮 reload，而不是报错
+	cmds := []ipc.CommandMessage{
+		{Name: "reload"},
+		{Name: "route", Payload: map[string]any{"route": "global"}},
+		{Name: "reload"},
+	}
+	results := make(chan ipc.CommandResult, len(cmds))
+	for i, cmd := range cmds {
+		go func() {
+			results <- d.Handle(ctx, cmd)
+		}()
+		if i == 0 {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	for range cmds {
+		if resp := <-results; resp.Status != "ok" {
+			t.Fatalf("expected queued reloads to succeed, got status=%s error=%s", resp.Status, resp.Error)
+		}
+	}
+	if fake.reloads != 2 {
+		t.Fatalf("expected the queued requests to be coalesced into one reload, got %d reloads", fake.reloads)
+	}
+	statusResp := d.Handle(ctx, ipc.CommandMessage{Name: "status"})
+	if route := fmt.Sprint(statusResp.Data["route_mode"]); route != "global" {
+		t.Fatalf("expected the queued route change to be applied, got %s", route)
+	}
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "stop"}); resp.Status != "ok" {
+		t.Fatalf("expected stop ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStopped, "run stop")
+}
+
+func TestDaemonLastQueuedModeWins(t *testing.T) {
+	setupEnv(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	d := daemon.NewDaemon()
+	fake := newFakeService()
+	fake.reloadDelay = 100 * time.Millisecond
+	d.SetServiceFactory(func() daemon.ServiceRunner {
+		return fake
+	})
+	serveDaemon(t, ctx, d)
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "run", Payload: map[string]any{"route": "rule"}}); resp.Status != "ok" {
+		t.Fatalf("expected run ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStarted, "run start")
+	if err := os.WriteFile(paths.Get().ConfigFile, []byte(`{"outbounds":[{"type":"direct","tag":"lan"}]}`), 0644); err != nil {
+		t.Fatalf("rewrite profile.json: %v", err)
+	}
+
+	// reload 执行期间排队切换到 global，随后再请求当前已提交的 rule：最后接受的 rule 必须生效
+	results := make(chan ipc.CommandResult, 2)
+	for _, cmd := range []ipc.CommandMessage{
+		{Name: "reload"},
+		{Name: "route", Payload: map[string]any{"route": "global"}},
+	} {
+		go func() {
+			results <- d.Handle(ctx, cmd)
+		}()
+		time.Sleep(20 * time.Millisecond)
+	}
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "route", Payload: map[string]any{"route": "rule"}}); resp.Status != "ok" {
+		t.Fatalf("expected route ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	for range 2 {
+		if resp := <-results; resp.Status != "ok" {
+			t.Fatalf("expected queued commands to succeed, got status=%s error=%s", resp.Status, resp.Error)
+		}
+	}
+	statusResp := d.Handle(ctx, ipc.CommandMessage{Name: "status"})
+	if route := fmt.Sprint(statusResp.Data["route_mode"]); route != "rule" {
+		t.Fatalf("expected the last accepted route to win, got %s", route)
+	}
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "stop"}); resp.Status != "ok" {
+		t.Fatalf("expected stop ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStopped, "run stop")
+}
+
+func TestDaemonReloadOutlivesCallerContext(t *testing.T) {
+	setupEnv(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	d := daemon.NewDaemon()
+	svc := &ctxService{fakeService: newFakeService()}
+	svc.reloadDelay = 100 * time.Millisecond
+	d.SetServiceFactory(func() daemon.ServiceRunner {
+		return svc
+	})
+	serveDaemon(t, ctx, d)
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "run"}); resp.Status != "ok" {
+		t.Fatalf("expected run ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, svc.runStarted, "run start")
+	if err := os.WriteFile(paths.Get().ConfigFile, []byte(`{"outbounds":[{"type":"direct","tag":"lan"}]}`), 0644); err != nil {
+		t.Fatalf("rewrite profile.json: %v", err)
+	}
+
+	// 调用方在 reload 完成前放弃等待，排队的 reload 仍在 daemon 的 context 中完成
+	callerCtx, callerCancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer callerCancel()
+	if resp := d.Handle(callerCtx, ipc.CommandMessage{Name: "reload"}); resp.Status == "ok" {
+		t.Fatal("expected reload to report the cancelled wait")
+	}
+	var reloadCtx context.Context
+	for deadline := time.Now().Add(2 * time.Second); reloadCtx == nil; time.Sleep(20 * time.Millisecond) {
+		_, reloadCtx = svc.contexts()
+		if time.Now().After(deadline) {
+			t.Fatal("queued reload did not run")
+		}
+	}
+	if err := reloadCtx.Err(); err != nil {
+		t.Fatalf("expected reload to run on the daemon context, got cancelled context: %v", err)
+	}
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "stop"}); resp.Status != "ok" {
+		t.Fatalf("expected stop ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, svc.runStopped, "run stop")
+}
+
+// serveDaemon 在后台运行 d.Serve 并等待其初始化上下文和锁。
+// 测试结束时（ctx 已被取消）等待 Serve 退出，避免其清理过程与下一个测试的环境重置交叉
+func TestDaemonSubscribeStreamsEvents(t *testing.T) {
+	setupEnv(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	d := daemon.NewDaemon()
+	fake := newFakeService()
+	d.SetServiceFactory(func() daemon.ServiceRunner {
+		return fake
+	})
+	serveDaemon(t, ctx, d)
+
+	events := make(chan daemon.Event, 16)
+	subErr := make(chan error, 1)
+	subCtx, subCancel := context.WithCancel(ctx)
+	defer subCancel()
+	go func() {
+		sender := ipc.NewUnixSender(paths.Get().SocketFile)
+		payload := map[string]any{"types": []string{daemon.EventStarted, daemon.EventReload, daemon.EventRoute}}
+		subErr <- sender.Subscribe(subCtx, ipc.CommandMessage{Name: "subscribe", Payload: payload}, func(raw json.RawMessage) error {
+			var ev daemon.Event
+			if err := json.Unmarshal(raw, &ev); err != nil {
+				return err
+			}
+			events <- ev
+			return nil
+		})
+	}()
+	// 等待订阅建立
+	time.Sleep(100 * time.Millisecond)
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "run", Payload: map[string]any{"route": "rule"}}); resp.Status != "ok" {
+		t.Fatalf("expected run ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStarted, "run start")
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "route", Payload: map[string]any{"route": "global"}}); resp.Status != "ok" {
+		t.Fatalf("expected route ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+
+	// reload_start 等未订阅的类型被过滤
+	var got []string
+	for _, want := range []string{daemon.EventStarted, daemon.EventReload, daemon.EventRoute} {
+		select {
+		case ev := <-events:
+			got = append(got, ev.Type)
+			if ev.Type != want {
+				t.Fatalf("expected event %s, got %v", want, got)
+			}
+			if ev.Type == daemon.EventRoute && ev.Data["route"] != "global" {
+				t.Fatalf("expected route event for global, got %v", ev.Data)
+			}
+		case err := <-subErr:
+			t.Fatalf("subscription ended early: %v", err)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s, got %v", want, got)
+		}
+	}
+
+	subCancel()
+	if err := <-subErr; err != nil {
+		t.Fatalf("expected a cancelled subscription to end cleanly, got %v", err)
+	}
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "stop"}); resp.Status != "ok" {
+		t.Fatalf("expected stop ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStopped, "run stop")
+}
+
+func serveDaemon(t *testing.T, ctx context.Context, d *daemon.Daemon) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Serve 会阻塞，直到 ctx 取消
+		_ = d.Serve(ctx)
+	}()
+	t.Cleanup(func() { <-done })
+	time.Sleep(100 * time.Millisecond)
+}
+
 func setupEnv(t *testing.T) {
 	t.Helper()
 	paths.ResetForTest()
@@ -192,3 +735,390 @@ func waitFor(t *testing.T, ch <-chan struct{}, label string) {
 		t.Fatalf("timed out waiting for %s", label)
 	}
 }
+
+func TestDaemonProtocol(t *testing.T) {
+	setupEnv(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	d := daemon.NewDaemon()
+	fake := newFakeService()
+	d.SetServiceFactory(func() daemon.ServiceRunner {
+		return fake
+	})
+	serveDaemon(t, ctx, d)
+
+	var hello daemon.HelloResponse
+	if err := d.Handle(ctx, ipc.CommandMessage{Name: "hello"}).Decode(&hello); err != nil {
+		t.Fatalf("decode hello: %v", err)
+	}
+	if hello.Protocol != ipc.ProtocolVersion {
+		t.Fatalf("expected protocol %d, got %d", ipc.ProtocolVersion, hello.Protocol)
+	}
+	for _, name := range []string{"hello", "run", "status", "node.test", "config.rollback", "subscribe"} {
+		if !hello.Supports(name) {
+			t.Fatalf("expected hello to list %s, got %v", name, hello.Commands)
+		}
+	}
+
+	expectCode := func(resp ipc.CommandResult, code string) {
+		t.Helper()
+		if resp.Status != "error" || resp.Code != code {
+			t.Fatalf("expected error code %s, got status=%s code=%s error=%s", code, resp.Status, resp.Code, resp.Error)
+		}
+	}
+	expectCode(d.Handle(ctx, ipc.CommandMessage{Name: "bogus"}), ipc.CodeUnknownCommand)
+	expectCode(d.Handle(ctx, ipc.CommandMessage{Name: "mode", Payload: map[string]any{"mode": "tun"}}), ipc.CodeNotRunning)
+	expectCode(d.Handle(ctx, ipc.CommandMessage{Name: "run", Payload: map[string]any{"api_port": "high"}}), ipc.CodeInvalidRequest)
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "run"}); resp.Status != "ok" {
+		t.Fatalf("expected run ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStarted, "run start")
+
+	expectCode(d.Handle(ctx, ipc.CommandMessage{Name: "run"}), ipc.CodeAlreadyRunning)
+	expectCode(d.Handle(ctx, ipc.CommandMessage{Name: "mode", Payload: map[string]any{"mode": "bogus"}}), ipc.CodeInvalidRequest)
+	expectCode(d.Handle(ctx, ipc.CommandMessage{Name: "config.rollback", Payload: map[string]any{"id": 99}}), ipc.CodeNotFound)
+
+	var status daemon.StatusResponse
+	if err := d.Handle(ctx, ipc.CommandMessage{Name: "status"}).Decode(&status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if !status.Running || status.APIPort == 0 || status.Generation == 0 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "stop"}); resp.Status != "ok" {
+		t.Fatalf("expected stop ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStopped, "run stop")
+}
+
+func TestDaemonHTTPAPI(t *testing.T) {
+	setupEnv(t)
+	if err := os.WriteFile(paths.Get().SettingsFile, []byte(`{"http":{"enabled":true,"listen":"127.0.0.1:0"}}`), 0644); err != nil {
+		t.Fatalf("write settings.json: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	d := daemon.NewDaemon()
+	fake := newFakeService()
+	d.SetServiceFactory(func() daemon.ServiceRunner {
+		return fake
+	})
+	serveDaemon(t, ctx, d)
+
+	base, token := waitHTTPAPI(t, ctx, d)
+	if info, err := os.Stat(paths.Get().HTTPTokenFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected token file with mode 0600, got %v err=%v", info.Mode(), err)
+	}
+
+	call := func(method, path, body string, auth bool) (int, map[string]any) {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, method, base+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if auth {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var out map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("%s %s: decode: %v", method, path, err)
+		}
+		return resp.StatusCode, out
+	}
+
+	if code, body := call("GET", "/status", "", false); code != http.StatusUnauthorized || body["code"] != ipc.CodePermissionDenied {
+		t.Fatalf("expected 401 without token, got %d %v", code, body)
+	}
+	if code, body := call("GET", "/status", "", true); code != http.StatusOK || body["running"] != false {
+		t.Fatalf("expected status not running, got %d %v", code, body)
+	}
+	if code, body := call("POST", "/mode", `{"mode":"tun"}`, true); code != http.StatusConflict || body["code"] != ipc.CodeNotRunning {
+		t.Fatalf("expected 409 not_running, got %d %v", code, body)
+	}
+	if code, body := call("POST", "/mode", `not json`, true); code != http.StatusBadRequest || body["code"] != ipc.CodeInvalidRequest {
+		t.Fatalf("expected 400 for a malformed body, got %d %v", code, body)
+	}
+	if code, body := call("GET", "/nope", "", true); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown endpoint, got %d %v", code, body)
+	}
+
+	// 订阅事件流，之后的 run 会产生 started 事件
+	req, err := http.NewRequestWithContext(ctx, "GET", base+"/events?types=started&token="+token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /events: %v", err)
+	}
+	defer stream.Body.Close()
+	if ct := stream.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+
+	if code, body := call("POST", "/run", `{"route":"global"}`, true); code != http.StatusOK || body["route_mode"] != "global" {
+		t.Fatalf("expected run ok, got %d %v", code, body)
+	}
+	waitFor(t, fake.runStarted, "run start")
+
+	scanner := bufio.NewScanner(stream.Body)
+	var lines []string
+	for scanner.Scan() && scanner.Text() != "" {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "id: ") || lines[1] != "event: started" || !strings.HasPrefix(lines[2], "data: {") {
+		t.Fatalf("unexpected SSE frame: %q", lines)
+	}
+
+	if code, body := call("GET", "/config/generations", "", true); code != http.StatusOK || body["current"] == nil {
+		t.Fatalf("expected generations, got %d %v", code, body)
+	}
+	if code, body := call("POST", "/config/rollback/99", "", true); code != http.StatusNotFound || body["code"] != ipc.CodeNotFound {
+		t.Fatalf("expected 404 for an unknown generation, got %d %v", code, body)
+	}
+	if code, body := call("POST", "/stop", "", true); code != http.StatusOK {
+		t.Fatalf("expected stop ok, got %d %v", code, body)
+	}
+	waitFor(t, fake.runStopped, "run stop")
+}
+
+func TestDaemonHTTPCommandsOutliveRequest(t *testing.T) {
+	setupEnv(t)
+	if err := os.WriteFile(paths.Get().SettingsFile, []byte(`{"http":{"enabled":true,"listen":"127.0.0.1:0"}}`), 0644); err != nil {
+		t.Fatalf("write settings.json: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	d := daemon.NewDaemon()
+	svc := &ctxService{fakeService: newFakeService()}
+	d.SetServiceFactory(func() daemon.ServiceRunner {
+		return svc
+	})
+	serveDaemon(t, ctx, d)
+	base, token := waitHTTPAPI(t, ctx, d)
+
+	post := func(path, body string) {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, "POST", base+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("POST %s: expected 200, got %d", path, resp.StatusCode)
+		}
+	}
+
+	// sing-box 在请求结束后继续使用启动、reload 时的 context，它们不能随请求取消
+	post("/run", `{"route":"rule"}`)
+	waitFor(t, svc.runStarted, "run start")
+	post("/route", `{"route":"global"}`)
+	time.Sleep(50 * time.Millisecond)
+
+	start, reload := svc.contexts()
+	if start == nil || reload == nil {
+		t.Fatalf("expected run and reload to reach the service, got start=%v reload=%v", start, reload)
+	}
+	if err := start.Err(); err != nil {
+		t.Fatalf("expected sing-box start context to outlive the request, got %v", err)
+	}
+	if err := reload.Err(); err != nil {
+		t.Fatalf("expected sing-box reload context to outlive the request, got %v", err)
+	}
+	select {
+	case <-svc.runStopped:
+		t.Fatal("sing-box stopped when the HTTP request finished")
+	default:
+	}
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "stop"}); resp.Status != "ok" {
+		t.Fatalf("expected stop ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, svc.runStopped, "run stop")
+}
+
+// waitHTTPAPI 等待 HTTP API 开始监听，返回其地址与访问令牌
+func waitHTTPAPI(t *testing.T, ctx context.Context, d *daemon.Daemon) (base, token string) {
+	t.Helper()
+	var addr string
+	for deadline := time.Now().Add(2 * time.Second); addr == ""; time.Sleep(20 * time.Millisecond) {
+		addr, _ = d.Handle(ctx, ipc.CommandMessage{Name: "status"}).Data["http_addr"].(string)
+		if time.Now().After(deadline) {
+			t.Fatal("HTTP API did not start")
+		}
+	}
+	data, err := os.ReadFile(paths.Get().HTTPTokenFile)
+	if err != nil {
+		t.Fatalf("read token: %v", err)
+	}
+	return "http://" + addr, strings.TrimSpace(string(data))
+}
+
+// crashingService 模拟 reload 后无法启动的 sing-box：failStarts 次 StartFromFile 失败
+type crashingService struct {
+	mu         sync.Mutex
+	starts     int
+	failStarts int
+	reloadErr  error
+}
+
+func (c *crashingService) StartFromFile(ctx context.Context, path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.starts++
+	if c.failStarts > 0 {
+		c.failStarts--
+		return errors.New("listen tcp: address already in use")
+	}
+	return nil
+}
+
+func (c *crashingService) ReloadFromFile(ctx context.Context, path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reloadErr
+}
+
+func (c *crashingService) CheckFromFile(ctx context.Context, path string) error { return nil }
+
+func (c *crashingService) Stop() {}
+
+func (c *crashingService) crashOnNextReload() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failStarts = 1
+	c.reloadErr = &engine.ReloadError{Stage: engine.ReloadStageStart, Err: errors.New("start service: bind failed")}
+}
+
+func TestDaemonSupervisorRestartsCrashedCore(t *testing.T) {
+	setupEnv(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	d := daemon.NewDaemon()
+	svc := &crashingService{}
+	d.SetServiceFactory(func() daemon.ServiceRunner { return svc })
+	serveDaemon(t, ctx, d)
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "run", Payload: map[string]any{"route": "rule"}}); resp.Status != "ok" {
+		t.Fatalf("expected run ok, got %s", resp.Error)
+	}
+
+	// reload 后新实例与回退的旧版本都无法启动，sing-box 停止运行
+	svc.crashOnNextReload()
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "route", Payload: map[string]any{"route": "global"}}); resp.Status == "ok" {
+		t.Fatalf("expected route to fail")
+	}
+	status := d.Handle(ctx, ipc.CommandMessage{Name: "status"})
+	info := status.Data["supervisor"].(daemon.SupervisorInfo)
+	if !info.Crashed || !strings.Contains(info.LastCrashReason, "bind failed") {
+		t.Fatalf("expected a recorded crash, got %+v", info)
+	}
+
+	// 监护循环在退避间隔后重启 sing-box
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status = d.Handle(ctx, ipc.CommandMessage{Name: "status"})
+		info = status.Data["supervisor"].(daemon.SupervisorInfo)
+		if info.Restarts == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("sing-box was not restarted: %+v", info)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if running, _ := status.Data["running"].(bool); !running || info.Crashed {
+		t.Fatalf("expected sing-box running after restart, got running=%v %+v", running, info)
+	}
+
+	events := d.Handle(ctx, ipc.CommandMessage{Name: "events"})
+	var types []string
+	for _, ev := range events.Data["events"].([]daemon.Event) {
+		types = append(types, ev.Type)
+	}
+	if !slices.Contains(types, daemon.EventCrash) || !slices.Contains(types, daemon.EventRestart) {
+		t.Fatalf("expected crash and restart events, got %v", types)
+	}
+}
+
+func TestDaemonReloadsOnNetworkChange(t *testing.T) {
+	setupEnv(t)
+	if err := os.WriteFile(paths.Get().SettingsFile, []byte(`{"network":{"action":"reload","debounce":"50ms"}}`), 0644); err != nil {
+		t.Fatalf("write settings.json: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	d := daemon.NewDaemon()
+	fake := newFakeService()
+	d.SetServiceFactory(func() daemon.ServiceRunner { return fake })
+	mon := netmon.NewFake()
+	d.SetNetworkMonitor(mon)
+	serveDaemon(t, ctx, d)
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "run"}); resp.Status != "ok" {
+		t.Fatalf("expected run ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStarted, "run start")
+
+	// Wi-Fi 切换：一串连续变化只处理一次；TUN 设备的变化被忽略
+	mon.Emit(netmon.Change{Kind: netmon.KindLink, Interface: "wlan0"})
+	mon.Emit(netmon.Change{Kind: netmon.KindAddress, Interface: "wlan0"})
+	mon.Emit(netmon.Change{Kind: netmon.KindRoute, Interface: "wlan0"})
+	mon.Emit(netmon.Change{Kind: netmon.KindLink, Interface: "tun0"})
+
+	var networks []daemon.Event
+	var reload *daemon.Event
+	deadline := time.Now().Add(3 * time.Second)
+	for reload == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a reload after the network change")
+		}
+		time.Sleep(20 * time.Millisecond)
+		events, _ := d.Handle(ctx, ipc.CommandMessage{Name: "events"}).Data["events"].([]daemon.Event)
+		networks = nil
+		for _, ev := range events {
+			if ev.Type == daemon.EventNetwork {
+				networks = append(networks, ev)
+			} else if ev.Type == daemon.EventReload && len(networks) > 0 {
+				reload = &ev
+			}
+		}
+	}
+	if len(networks) != 1 {
+		t.Fatalf("expected the changes to be handled once, got %+v", networks)
+	}
+	network := networks[0]
+	if got := network.Data["changes"].([]netmon.Change); len(got) != 3 {
+		t.Fatalf("expected 3 merged changes, got %v", got)
+	}
+	if reload.Data["skipped"] != false || !slices.Contains(reload.Data["reasons"].([]string), "network") {
+		t.Fatalf("expected a forced reload for the network change, got %+v", reload.Data)
+	}
+
+	if resp := d.Handle(ctx, ipc.CommandMessage{Name: "stop"}); resp.Status != "ok" {
+		t.Fatalf("expected stop ok, got status=%s error=%s", resp.Status, resp.Error)
+	}
+	waitFor(t, fake.runStopped, "run stop")
+}
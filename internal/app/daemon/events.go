@@ -0,0 +1,163 @@
+package daemon
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+)
+
+// maxEvents 事件环形缓冲区容量
+const maxEvents = 256
+
+// 通用的事件类型，故障转移相关的见 failover.go
+const (
+	EventStarted      = "started"       // sing-box 已启动
+	EventError        = "error"         // 启动等操作失败
+	EventReloadStart  = "reload_start"  // 开始执行一轮 reload
+	EventReload       = "reload"        // reload 完成（data.skipped 表示配置无变化未重启 sing-box）
+	EventReloadFailed = "reload_failed" // reload 失败，旧配置继续运行
+	EventMode         = "mode"          // 代理模式已切换
+	EventRoute        = "route"         // 路由模式已切换
+	EventNode         = "node"          // 代理组选中的节点已切换
+	EventSubscription = "subscription"  // 订阅缓存已更新
+)
+
+// Event daemon 内部发生的值得用户关注的事件（如故障转移）
+type Event struct {
+	Seq     uint64         `json:"seq"`
+	Time    time.Time      `json:"time"`
+	Type    string         `json:"type"`
+	Message string         `json:"message"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// eventLog 保存最近的事件，超出容量时丢弃最旧的
+type eventLog struct {
+	mu     sync.Mutex
+	seq    uint64
+	events []Event
+	subs   map[chan struct{}]struct{} // 订阅者，有新事件时收到通知
+}
+
+func (l *eventLog) add(typ, message string, data map[string]any) Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	ev := Event{Seq: l.seq, Time: time.Now(), Type: typ, Message: message, Data: data}
+	l.events = append(l.events, ev)
+	if len(l.events) > maxEvents {
+		l.events = append([]Event(nil), l.events[len(l.events)-maxEvents:]...)
+	}
+	for ch := range l.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+	return ev
+}
+
+// subscribe 注册一个订阅者，返回的通道在有新事件时收到通知（多次通知可能合并为一次）
+func (l *eventLog) subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	l.mu.Lock()
+	if l.subs == nil {
+		l.subs = make(map[chan struct{}]struct{})
+	}
+	l.subs[ch] = struct{}{}
+	l.mu.Unlock()
+	return ch, func() {
+		l.mu.Lock()
+		delete(l.subs, ch)
+		l.mu.Unlock()
+	}
+}
+
+// last 返回最新事件的序号
+func (l *eventLog) last() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq
+}
+
+// since 返回序号大于 seq 的事件
+func (l *eventLog) since(seq uint64) []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]Event, 0, len(l.events))
+	for _, ev := range l.events {
+		if ev.Seq > seq {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// emit 记录一个事件
+func (d *Daemon) emit(typ, message string, data map[string]any) {
+	d.events.add(typ, message, data)
+}
+
+// handleEvents 返回最近的事件，since 可只取该序号之后的事件
+func (d *Daemon) handleEvents(ctx context.Context, req EventsRequest) (EventsResponse, error) {
+	return EventsResponse{Events: d.events.since(req.Since)}, nil
+}
+
+// StreamFor 实现 ipc.StreamHandler，subscribe 命令以流的形式持续推送事件
+func (d *Daemon) StreamFor(cmd ipc.CommandMessage) ipc.StreamFunc {
+	if cmd.Name != "subscribe" {
+		return nil
+	}
+	var req SubscribeRequest
+	if err := cmd.Decode(&req); err != nil {
+		return func(context.Context, ipc.CommandMessage, func(any) error) error { return err }
+	}
+	// 起点在回复客户端之前确定，客户端收到回复后发生的事件都不会遗漏
+	last := d.events.last()
+	if req.Since != nil {
+		last = *req.Since
+	}
+	types := eventTypes(req.Types)
+	return func(ctx context.Context, cmd ipc.CommandMessage, send func(any) error) error {
+		return d.streamEvents(ctx, last, types, send)
+	}
+}
+
+// streamEvents 推送序号大于 last 的事件直到连接关闭；types 非空时只推送其中的类型
+func (d *Daemon) streamEvents(ctx context.Context, last uint64, types map[string]bool, send func(any) error) error {
+	notify, cancel := d.events.subscribe()
+	defer cancel()
+
+	for {
+		for _, ev := range d.events.since(last) {
+			last = ev.Seq
+			if len(types) > 0 && !types[ev.Type] {
+				continue
+			}
+			if err := send(ev); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-notify:
+		}
+	}
+}
+
+// eventTypes 解析 subscribe 的 types 参数，每一项也可以是逗号分隔的多个类型
+func eventTypes(names []string) map[string]bool {
+	types := make(map[string]bool, len(names))
+	for _, item := range names {
+		for name := range strings.SplitSeq(item, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				types[name] = true
+			}
+		}
+	}
+	return types
+}
@@ -0,0 +1,220 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/clashapi"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
+	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+// 故障转移相关的事件类型
+const (
+	EventFailover = "failover" // 选中节点失效，已切换到 fallback
+	EventFailback = "failback" // 原节点恢复，已切回
+)
+
+// failoverGroup 单个 selector 的故障转移状态
+type failoverGroup struct {
+	preferred string // 用户选中的节点
+	failures  int    // preferred 连续探测失败次数
+	fallback  string // 当前切换到的成员，空表示未发生故障转移
+}
+
+// failoverWatchdog 周期性探测每个 selector 当前选中的节点，连续失败后切换到 fallback
+type failoverWatchdog struct {
+	groups map[string]*failoverGroup
+	emit   func(typ, message string, data map[string]any)
+	record func(results []LatencyResult) // 记录探测结果到延迟历史，可为 nil
+}
+
+func newFailoverWatchdog(emit func(typ, message string, data map[string]any), record func([]LatencyResult)) *failoverWatchdog {
+	return &failoverWatchdog{groups: make(map[string]*failoverGroup), emit: emit, record: record}
+}
+
+// runFailoverWatchdog 在 daemon 生命周期内运行看门狗，每轮重新读取 settings.json 使修改即时生效
+func (d *Daemon) runFailoverWatchdog(ctx context.Context) {
+	w := newFailoverWatchdog(d.emit, d.recordLatency)
+	interval := model.FailoverSettings{}.IntervalValue()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		case <-d.failoverWake.C():
+		}
+
+		settings, err := config.LoadSettings(paths.Get().SettingsFile)
+		if err != nil {
+			logger.Error("Failed to load settings", "error", err)
+		}
+		cfg := settings.Failover
+		interval = cfg.IntervalValue()
+		if !cfg.Enabled || !d.isRunning() {
+			w.reset()
+			continue
+		}
+		apiAddr, err := d.resolveAPIAddr("")
+		if err != nil {
+			continue
+		}
+		// 探测本身受 timeout 限制，HTTP 客户端需留出余量
+		c := clashapi.NewWithTimeout(apiAddr, cfg.TimeoutValue()+2*time.Second)
+		w.check(c, cfg)
+	}
+}
+
+func (w *failoverWatchdog) reset() {
+	w.groups = make(map[string]*failoverGroup)
+}
+
+// check 执行一轮探测
+func (w *failoverWatchdog) check(c *clashapi.Client, cfg model.FailoverSettings) {
+	proxies, err := c.GetProxies()
+	if err != nil {
+		logger.Debug("Failover watchdog: failed to get proxies", "error", err)
+		return
+	}
+
+	names := make([]string, 0, len(proxies))
+	for name, p := range proxies {
+		if p.Type == "Selector" && name != moduleUtils.TagProbe {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		seen[name] = true
+		w.checkGroup(c, cfg, proxies, name)
+	}
+	for name := range w.groups {
+		if !seen[name] {
+			delete(w.groups, name)
+		}
+	}
+}
+
+func (w *failoverWatchdog) checkGroup(c *clashapi.Client, cfg model.FailoverSettings, proxies map[string]clashapi.ProxyData, name string) {
+	g := proxies[name]
+	st, ok := w.groups[name]
+	if !ok {
+		st = &failoverGroup{}
+		w.groups[name] = st
+	}
+	timeout := int(cfg.TimeoutValue().Milliseconds())
+
+	// 故障转移期间用户手动切换了节点（或重载后恢复了选择），以当前选择为准
+	if st.fallback != "" && g.Now != st.fallback {
+		*st = failoverGroup{}
+	}
+
+	if st.fallback != "" {
+		if !cfg.SwitchBackValue() {
+			return
+		}
+		if !w.probe(c, st.preferred, cfg.URLValue(), timeout) {
+			return
+		}
+		if err := c.SelectProxy(name, st.preferred); err != nil {
+			logger.Error("Failover watchdog: failed to switch back", "group", name, "node", st.preferred, "error", err)
+			return
+		}
+		logger.Info("Preferred node recovered, switched back", "group", name, "node", st.preferred, "from", st.fallback)
+		w.emit(EventFailback, fmt.Sprintf("%s: %s recovered, switched back from %s", name, st.preferred, st.fallback), map[string]any{
+			"group": name,
+			"node":  st.preferred,
+			"from":  st.fallback,
+		})
+		*st = failoverGroup{preferred: st.preferred}
+		return
+	}
+
+	// 只看护具体节点；选中的是 auto 等策略组时由其自身负责健康检查
+	if g.Now == "" || nonNodeTypes[proxies[g.Now].Type] {
+		*st = failoverGroup{}
+		return
+	}
+	if st.preferred != g.Now {
+		*st = failoverGroup{preferred: g.Now}
+	}
+	if w.probe(c, st.preferred, cfg.URLValue(), timeout) {
+		st.failures = 0
+		return
+	}
+	st.failures++
+	logger.Debug("Failover watchdog: probe failed", "group", name, "node", st.preferred, "failures", st.failures)
+	if st.failures < cfg.FailuresValue() {
+		return
+	}
+
+	target := w.pickFallback(c, cfg, proxies, name, st.preferred)
+	if target == "" {
+		logger.Error("Failover watchdog: no healthy fallback", "group", name, "node", st.preferred)
+		return
+	}
+	if err := c.SelectProxy(name, target); err != nil {
+		logger.Error("Failover watchdog: failed to switch", "group", name, "node", target, "error", err)
+		return
+	}
+	logger.Info("Selected node is down, failed over", "group", name, "node", st.preferred, "to", target, "failures", st.failures)
+	w.emit(EventFailover, fmt.Sprintf("%s: %s is down, switched to %s", name, st.preferred, target), map[string]any{
+		"group":    name,
+		"node":     st.preferred,
+		"to":       target,
+		"failures": st.failures,
+	})
+	st.fallback = target
+	st.failures = 0
+}
+
+// pickFallback 选择故障转移目标：auto 策略优先使用组内的 auto，否则取组内最快的健康节点
+func (w *failoverWatchdog) pickFallback(c *clashapi.Client, cfg model.FailoverSettings, proxies map[string]clashapi.ProxyData, group, failed string) string {
+	g := proxies[group]
+	if cfg.FallbackValue() == model.FallbackAuto {
+		for _, member := range g.All {
+			if member == moduleUtils.TagAuto {
+				return member
+			}
+		}
+	}
+	var tags []string
+	for _, member := range g.All {
+		if member != failed && !nonNodeTypes[proxies[member].Type] {
+			tags = append(tags, member)
+		}
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	results := testLatency(c, tags, cfg.URLValue(), int(cfg.TimeoutValue().Milliseconds()), defaultLatencyConcurrency)
+	if w.record != nil {
+		w.record(results)
+	}
+	if !results[0].OK() {
+		return ""
+	}
+	return results[0].Tag
+}
+
+// probe 探测单个节点并记录结果
+func (w *failoverWatchdog) probe(c *clashapi.Client, tag, testURL string, timeout int) bool {
+	res := LatencyResult{Tag: tag}
+	delay, err := c.GetNodeDelay(tag, testURL, timeout)
+	if err != nil {
+		res.Error = err.Error()
+	} else {
+		res.Delay = delay
+	}
+	if w.record != nil {
+		w.record([]LatencyResult{res})
+	}
+	return res.OK()
+}
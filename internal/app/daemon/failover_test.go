@@ -0,0 +1,132 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/clashapi"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+)
+
+// fakeClash 模拟 Clash API：一个 selector 组 proxy，成员 A/B/C/auto
+type fakeClash struct {
+	mu    sync.Mutex
+	now   string
+	delay map[string]int // 0 表示探测失败
+}
+
+func (f *fakeClash) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/proxies")
+	switch {
+	case path == "":
+		proxies := map[string]clashapi.ProxyData{
+			"proxy": {Type: "Selector", All: []string{"A", "B", "C", "auto"}, Now: f.now},
+			"auto":  {Type: "URLTest", All: []string{"A", "B", "C"}, Now: "B"},
+			"A":     {Type: "Shadowsocks"},
+			"B":     {Type: "Shadowsocks"},
+			"C":     {Type: "Shadowsocks"},
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"proxies": proxies})
+	case strings.HasSuffix(path, "/delay"):
+		name := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/delay")
+		if d := f.delay[name]; d > 0 {
+			_ = json.NewEncoder(w).Encode(clashapi.DelayResult{Delay: d})
+			return
+		}
+		http.Error(w, "timeout", http.StatusGatewayTimeout)
+	case r.Method == http.MethodPut && path == "/proxy":
+		var body struct {
+			Name string `json:"name"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.now = body.Name
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeClash) selected() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClash) setDelay(name string, delay int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delay[name] = delay
+}
+
+func TestFailoverWatchdog(t *testing.T) {
+	fake := &fakeClash{now: "A", delay: map[string]int{"A": 0, "B": 80, "C": 40}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	c := clashapi.New(strings.TrimPrefix(srv.URL, "http://"))
+
+	var events []string
+	w := newFailoverWatchdog(func(typ, message string, data map[string]any) {
+		events = append(events, typ)
+	}, nil)
+	cfg := model.FailoverSettings{Enabled: true, Failures: 2}
+
+	w.check(c, cfg)
+	if got := fake.selected(); got != "A" {
+		t.Fatalf("switched after a single failure: now %q", got)
+	}
+	w.check(c, cfg)
+	if got := fake.selected(); got != "C" {
+		t.Fatalf("expected failover to fastest node C, now %q", got)
+	}
+
+	// 原节点未恢复时保持 fallback
+	w.check(c, cfg)
+	if got := fake.selected(); got != "C" {
+		t.Fatalf("expected to stay on C, now %q", got)
+	}
+
+	fake.setDelay("A", 100)
+	w.check(c, cfg)
+	if got := fake.selected(); got != "A" {
+		t.Fatalf("expected switch back to A, now %q", got)
+	}
+	if strings.Join(events, ",") != EventFailover+","+EventFailback {
+		t.Fatalf("unexpected events: %v", events)
+	}
+}
+
+func TestFailoverWatchdogAutoFallback(t *testing.T) {
+	fake := &fakeClash{now: "A", delay: map[string]int{"B": 80}}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	c := clashapi.New(strings.TrimPrefix(srv.URL, "http://"))
+
+	noSwitchBack := false
+	cfg := model.FailoverSettings{Enabled: true, Failures: 1, Fallback: model.FallbackAuto, SwitchBack: &noSwitchBack}
+	w := newFailoverWatchdog(func(string, string, map[string]any) {}, nil)
+
+	w.check(c, cfg)
+	if got := fake.selected(); got != "auto" {
+		t.Fatalf("expected failover to auto, now %q", got)
+	}
+	fake.setDelay("A", 50)
+	w.check(c, cfg)
+	if got := fake.selected(); got != "auto" {
+		t.Fatalf("switch_back disabled but switched to %q", got)
+	}
+
+	// 用户手动选择后重新以新节点为准
+	fake.mu.Lock()
+	fake.now = "B"
+	fake.mu.Unlock()
+	w.check(c, cfg)
+	if st := w.groups["proxy"]; st.preferred != "B" || st.fallback != "" {
+		t.Fatalf("expected state reset to B, got %+v", st)
+	}
+}
@@ -0,0 +1,110 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+// handleConfigGenerations 列出保存的配置版本（新的在前）及当前运行的版本
+func (d *Daemon) handleConfigGenerations(ctx context.Context, _ struct{}) (ConfigGenerationsResponse, error) {
+	list, err := config.DefaultGenerations().List()
+	if err != nil {
+		return ConfigGenerationsResponse{}, err
+	}
+	if list == nil {
+		list = []config.Generation{}
+	}
+	resp := ConfigGenerationsResponse{Generations: list}
+	if state, _ := d.currentState(); state != nil && d.isRunning() {
+		resp.Current = state.Generation
+	}
+	return resp, nil
+}
+
+// handleConfigRollback 以保存的配置版本重启 sing-box
+func (d *Daemon) handleConfigRollback(ctx context.Context, req ConfigRollbackRequest) (ConfigRollbackResponse, error) {
+	if req.ID <= 0 {
+		return ConfigRollbackResponse{}, ipc.Errorf(ipc.CodeInvalidRequest, "missing generation id")
+	}
+	result, err := d.rollback(ctx, req.ID)
+	if err != nil {
+		return ConfigRollbackResponse{}, err
+	}
+	return ConfigRollbackResponse{Generation: req.ID, ReloadInfo: result.info()}, nil
+}
+
+// rollback 校验并以 id 版本的配置 reload sing-box，RunOptions 一并恢复为该版本的设置。
+// 回滚后的构建记录对应该版本的输入，之后的 reload 若输入已变化会重新构建当前配置
+func (d *Daemon) rollback(ctx context.Context, id int) (reloadResult, error) {
+	var result reloadResult
+	if !d.isRunning() {
+		return result, errNotRunning
+	}
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	if d.service == nil {
+		return result, errors.New("service not available")
+	}
+	state, err := d.currentState()
+	if err != nil {
+		return result, err
+	}
+	if state == nil {
+		return result, errors.New("missing state")
+	}
+
+	gens := config.DefaultGenerations()
+	gen, err := gens.Get(id)
+	if errors.Is(err, config.ErrGenerationNotFound) {
+		return result, ipc.WithCode(ipc.CodeNotFound, err)
+	}
+	if err != nil {
+		return result, err
+	}
+	data, err := os.ReadFile(gens.ConfigPath(id))
+	if err != nil {
+		return result, fmt.Errorf("failed to read generation %d: %w", id, err)
+	}
+	if err := d.service.CheckFromFile(ctx, gens.ConfigPath(id)); err != nil {
+		return result, ipc.Errorf(ipc.CodeRejected, "generation %d rejected, sing-box keeps running the current config: %w", id, err)
+	}
+
+	logger.Info("Rolling back config", "generation", id, "from", state.Generation)
+	reasons := []string{"rollback"}
+	d.emit(EventReloadStart, fmt.Sprintf("rolling back to generation %d", id), map[string]any{"reasons": reasons, "generation": id})
+	err = gens.Restore(id, paths.Get().RawConfigFile)
+	if err == nil {
+		err = d.reloadRawConfig(ctx, gen.RunOptions.ProxyMode, state.Generation)
+	}
+	if err != nil {
+		d.emit(EventReloadFailed, fmt.Sprintf("rollback to generation %d failed: %v", id, err), map[string]any{
+			"reasons": reasons,
+			"error":   err.Error(),
+		})
+		return result, err
+	}
+
+	d.setBuilt(&config.Rendered{Data: data, InputHash: gen.InputHash})
+	previous := state.RunOptions
+	state.RunOptions = gen.RunOptions
+	state.Generation = id
+	d.commitState(state)
+
+	d.syncSystemDNS(state.RunOptions.ProxyMode)
+	result.Unrestored = d.restoreSelections()
+	events := map[string]any{"reasons": reasons, "skipped": false, "generation": id}
+	if len(result.Unrestored) > 0 {
+		events["unrestored_selections"] = result.Unrestored
+	}
+	d.emit(EventReload, fmt.Sprintf("rolled back to generation %d", id), events)
+	d.emitModeChanges(previous, state.RunOptions)
+	return result, nil
+}
@@ -8,57 +8,52 @@ import (
 	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
 )
 
-func (d *Daemon) handleMode(ctx context.Context, payload map[string]any) ipc.CommandResult {
+func (d *Daemon) handleMode(ctx context.Context, req ModeRequest) (ModeResponse, error) {
 	if !d.isRunning() {
-		return ipc.CommandResult{Status: "error", Error: "sing-box not running"}
+		return ModeResponse{}, errNotRunning
 	}
-	modeStr, ok := payload["mode"].(string)
-	if !ok || modeStr == "" {
-		return ipc.CommandResult{Status: "error", Error: "missing mode"}
+	if req.Mode == "" {
+		return ModeResponse{}, ipc.Errorf(ipc.CodeInvalidRequest, "missing mode")
 	}
-	proxyMode, err := model.ParseProxyMode(modeStr)
+	proxyMode, err := model.ParseProxyMode(req.Mode)
 	if err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+		return ModeResponse{}, ipc.WithCode(ipc.CodeInvalidRequest, err)
 	}
-	state, err := d.currentState()
+	// 与排队的修改生效后的模式比较：已提交的模式可能即将被排队的 mode 命令改变
+	desired, err := d.queuedRunOptions()
 	if err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+		return ModeResponse{}, err
 	}
-	if (proxyMode == model.ProxyModeTUN || state.RunOptions.ProxyMode == model.ProxyModeTUN) && os.Geteuid() != 0 {
-		return ipc.CommandResult{Status: "error", Error: "operating with TUN mode requires root permission"}
+	if (proxyMode == model.ProxyModeTUN || desired.ProxyMode == model.ProxyModeTUN) && os.Geteuid() != 0 {
+		return ModeResponse{}, ipc.Errorf(ipc.CodeInvalidRequest, "operating with TUN mode requires root permission")
 	}
-	if state.RunOptions.ProxyMode == proxyMode {
-		return ipc.CommandResult{Status: "ok", Data: map[string]any{"proxy_mode": string(proxyMode)}}
-	}
-	state.RunOptions.ProxyMode = proxyMode
-	if err := d.applyRunOptions(ctx, state); err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+	// 模式相同时也排队，保证最后接受的命令最终生效；输入没有变化时 applyRunOptions 会跳过重新构建
+	result, err := d.requestReload(ctx, "mode", func(opts *model.RunOptions) {
+		opts.ProxyMode = proxyMode
+	}, 0)
+	if err != nil {
+		return ModeResponse{}, err
 	}
-	return ipc.CommandResult{Status: "ok", Data: map[string]any{"proxy_mode": string(proxyMode)}}
+	return ModeResponse{ProxyMode: string(proxyMode), ReloadInfo: result.info()}, nil
 }
 
-func (d *Daemon) handleRoute(ctx context.Context, payload map[string]any) ipc.CommandResult {
+func (d *Daemon) handleRoute(ctx context.Context, req RouteRequest) (RouteResponse, error) {
 	if !d.isRunning() {
-		return ipc.CommandResult{Status: "error", Error: "sing-box not running"}
+		return RouteResponse{}, errNotRunning
 	}
-	routeStr, ok := payload["route"].(string)
-	if !ok || routeStr == "" {
-		return ipc.CommandResult{Status: "error", Error: "missing route"}
+	if req.Route == "" {
+		return RouteResponse{}, ipc.Errorf(ipc.CodeInvalidRequest, "missing route")
 	}
-	routeMode, err := model.ParseRouteMode(routeStr)
+	routeMode, err := model.ParseRouteMode(req.Route)
 	if err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+		return RouteResponse{}, ipc.WithCode(ipc.CodeInvalidRequest, err)
 	}
-	state, err := d.currentState()
+	// 与 mode 相同，路由模式未变化时也排队，输入没有变化时 applyRunOptions 会跳过重新构建
+	result, err := d.requestReload(ctx, "route", func(opts *model.RunOptions) {
+		opts.RouteMode = routeMode
+	}, 0)
 	if err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
-	}
-	if state.RunOptions.RouteMode == routeMode {
-		return ipc.CommandResult{Status: "ok", Data: map[string]any{"route_mode": string(routeMode)}}
-	}
-	state.RunOptions.RouteMode = routeMode
-	if err := d.applyRunOptions(ctx, state); err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+		return RouteResponse{}, err
 	}
-	return ipc.CommandResult{Status: "ok", Data: map[string]any{"route_mode": string(routeMode)}}
+	return RouteResponse{RouteMode: string(routeMode), ReloadInfo: result.info()}, nil
 }
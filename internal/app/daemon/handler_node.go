@@ -1,67 +1,73 @@
 package daemon
 
 import (
-	"errors"
+	"context"
 	"fmt"
 
 	"github.com/kyson-dev/sing-helm/internal/proxy/clashapi"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
 	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
 )
 
-func (d *Daemon) handleNodeList(payload map[string]any) ipc.CommandResult {
+func (d *Daemon) handleNodeList(ctx context.Context, req NodeListRequest) (NodeListResponse, error) {
 	if !d.isRunning() {
-		return ipc.CommandResult{Status: "error", Error: "sing-box not running"}
+		return NodeListResponse{}, errNotRunning
 	}
-	apiAddr, err := d.resolveAPIAddr(payload)
+	apiAddr, err := d.resolveAPIAddr(req.API)
 	if err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+		return NodeListResponse{}, err
 	}
 	c := clashapi.New(apiAddr)
 	proxies, err := c.GetProxies()
 	if err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+		return NodeListResponse{}, ipc.WithCode(ipc.CodeUnavailable, err)
 	}
-	return ipc.CommandResult{Status: "ok", Data: map[string]any{"proxies": proxies}}
+	resp := NodeListResponse{Proxies: proxies}
+	// 附带节点索引，便于客户端按稳定 ID 追踪节点
+	if idx, err := config.LoadNodeIndex(paths.Get().NodeIndexFile); err == nil {
+		resp.Nodes = idx.Nodes
+	}
+	return resp, nil
 }
 
-func (d *Daemon) handleNodeUse(payload map[string]any) ipc.CommandResult {
+func (d *Daemon) handleNodeUse(ctx context.Context, req NodeUseRequest) (NodeUseResponse, error) {
 	if !d.isRunning() {
-		return ipc.CommandResult{Status: "error", Error: "sing-box not running"}
+		return NodeUseResponse{}, errNotRunning
 	}
-	group, ok := payload["group"].(string)
-	if !ok || group == "" {
-		return ipc.CommandResult{Status: "error", Error: "missing group"}
+	if req.Group == "" {
+		return NodeUseResponse{}, ipc.Errorf(ipc.CodeInvalidRequest, "missing group")
 	}
-	node, ok := payload["node"].(string)
-	if !ok || node == "" {
-		return ipc.CommandResult{Status: "error", Error: "missing node"}
+	if req.Node == "" {
+		return NodeUseResponse{}, ipc.Errorf(ipc.CodeInvalidRequest, "missing node")
 	}
-	apiAddr, err := d.resolveAPIAddr(payload)
+	apiAddr, err := d.resolveAPIAddr(req.API)
 	if err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+		return NodeUseResponse{}, err
 	}
 	c := clashapi.New(apiAddr)
-	if err := c.SelectProxy(group, node); err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+	if err := c.SelectProxy(req.Group, req.Node); err != nil {
+		return NodeUseResponse{}, ipc.WithCode(ipc.CodeUnavailable, err)
 	}
-	return ipc.CommandResult{Status: "ok", Data: map[string]any{"group": group, "node": node}}
+	d.recordSelection(req.Group, req.Node)
+	d.emit(EventNode, fmt.Sprintf("%s: switched to %s", req.Group, req.Node), map[string]any{"group": req.Group, "node": req.Node})
+	return NodeUseResponse{Group: req.Group, Node: req.Node}, nil
 }
 
-func (d *Daemon) resolveAPIAddr(payload map[string]any) (string, error) {
-	if payload != nil {
-		if api, ok := payload["api"].(string); ok && api != "" {
-			return api, nil
-		}
+// resolveAPIAddr 返回 Clash API 地址，api 非空时优先使用
+func (d *Daemon) resolveAPIAddr(api string) (string, error) {
+	if api != "" {
+		return api, nil
 	}
 	state, err := d.currentState()
 	if err != nil {
 		return "", err
 	}
 	if state == nil {
-		return "", errors.New("missing state")
+		return "", errNotRunning
 	}
 	if state.RunOptions.APIPort == 0 {
-		return "", errors.New("api port unavailable")
+		return "", ipc.Errorf(ipc.CodeUnavailable, "api port unavailable")
 	}
 	listenAddr := state.RunOptions.ListenAddr
 	if listenAddr == "" {
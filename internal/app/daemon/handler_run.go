@@ -1,32 +1,34 @@
 package daemon
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"os"
 
 	"github.com/kyson-dev/sing-helm/internal/proxy/config"
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
 	"github.com/kyson-dev/sing-helm/internal/proxy/engine"
 	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
 	"github.com/kyson-dev/sing-helm/internal/sys/logger"
 	"github.com/kyson-dev/sing-helm/internal/sys/paths"
-	"github.com/kyson-dev/sing-helm/internal/sys/sysnet"
 )
 
 // handleRun 处理 IPC run 命令，启动 sing-box 服务
-func (d *Daemon) handleRun(ctx context.Context, payload map[string]any) ipc.CommandResult {
-	runops, err := d.parseRunOptions(payload)
+func (d *Daemon) handleRun(ctx context.Context, req RunRequest) (RunResponse, error) {
+	runops, err := d.parseRunOptions(req)
 	if err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+		return RunResponse{}, ipc.WithCode(ipc.CodeInvalidRequest, err)
 	}
 
 	// 检查并设置运行状态（原子操作）
 	d.mu.Lock()
 	if d.running {
 		d.mu.Unlock()
-		return ipc.CommandResult{Status: "error", Error: "sing-box is already running"}
+		return RunResponse{}, ipc.Errorf(ipc.CodeAlreadyRunning, "sing-box is already running")
 	}
 	// 立即设置为 running，防止并发请求
 	d.running = true
@@ -44,16 +46,36 @@ func (d *Daemon) handleRun(ctx context.Context, payload map[string]any) ipc.Comm
 
 	// 1. 构建配置
 	logger.Info("Building configuration", "mode", runops.ProxyMode, "route", runops.RouteMode)
-	if err := config.BuildConfig(paths.Get().RawConfigFile, &runops); err != nil {
-		return ipc.CommandResult{Status: "error", Error: fmt.Errorf("failed to build config: %w", err).Error()}
+	d.mu.Lock()
+	var allocated map[string]int
+	if d.state != nil {
+		allocated = d.state.clone().Ports
+	}
+	d.mu.Unlock()
+	ports := newPortAllocator(allocated, nil)
+	d.flushHistory()
+	rendered, err := config.Render(&runops, ports)
+	if err == nil {
+		err = rendered.Save(paths.Get().RawConfigFile)
 	}
+	if err != nil {
+		return d.runFailed(fmt.Errorf("failed to build config: %w", err))
+	}
+	d.setBuilt(rendered)
 
-	// 2. 启动 sing-box 服务
+	// 2. 启动 sing-box 服务；崩溃后遗留的实例可能仍占用端口，先关闭
+	d.mu.Lock()
+	stale := d.service
+	d.service = nil
+	d.mu.Unlock()
+	if stale != nil {
+		stale.Stop()
+	}
 	svc := d.newService()
 	rawPath := paths.Get().RawConfigFile
 	logger.Info("Starting sing-box", "config", rawPath)
 	if err := svc.StartFromFile(ctx, rawPath); err != nil {
-		return ipc.CommandResult{Status: "error", Error: fmt.Errorf("failed to start sing-box: %w", err).Error()}
+		return d.runFailed(fmt.Errorf("failed to start sing-box: %w", err))
 	}
 
 	// 启动成功，更新状态
@@ -64,19 +86,37 @@ func (d *Daemon) handleRun(ctx context.Context, payload map[string]any) ipc.Comm
 		d.state = &RuntimeState{}
 	}
 	d.state.RunOptions = runops
+	d.state.Ports = mergePorts(allocated, ports)
+	d.state.Generation = recordGeneration(rendered, runops)
+	generation := d.state.Generation
 	d.mu.Unlock()
 
+	d.supervisor.recovered()
 	d.syncSystemDNS(runops.ProxyMode)
 
 	logger.Info("Sing-box started successfully")
-	return ipc.CommandResult{Status: "ok", Data: map[string]any{
-		"proxy_mode": string(runops.ProxyMode),
-		"route_mode": string(runops.RouteMode),
-	}}
+	resp := RunResponse{
+		ProxyMode:  string(runops.ProxyMode),
+		RouteMode:  string(runops.RouteMode),
+		ReloadInfo: reloadResult{Unrestored: d.restoreSelections()}.info(),
+	}
+	d.emit(EventStarted, "sing-box started", map[string]any{
+		"mode":       string(runops.ProxyMode),
+		"route":      string(runops.RouteMode),
+		"generation": generation,
+	})
+	return resp, nil
+}
+
+// runFailed 推送启动失败事件并返回错误
+func (d *Daemon) runFailed(err error) (RunResponse, error) {
+	logger.Error("Failed to start", "error", err)
+	d.emit(EventError, err.Error(), map[string]any{"command": "run"})
+	return RunResponse{}, err
 }
 
 // parseRunOptions 解析 run 命令的参数
-func (d *Daemon) parseRunOptions(payload map[string]any) (model.RunOptions, error) {
+func (d *Daemon) parseRunOptions(req RunRequest) (model.RunOptions, error) {
 	// 1. 底层：硬编码默认值
 	runops := model.DefaultRunOptions()
 	d.mu.Lock()
@@ -93,129 +133,262 @@ func (d *Daemon) parseRunOptions(payload map[string]any) (model.RunOptions, erro
 
 	// 3. 最顶端层覆盖：本次 IPC 的 Payload (比如 sing-helm run --mode global)
 	// 这是最高优先级的动态指定
-	if payload == nil {
-		return runops, nil
-	}
-	if mode, ok := payload["mode"].(string); ok && mode != "" {
-		proxyMode, err := model.ParseProxyMode(mode)
+	if req.Mode != "" {
+		proxyMode, err := model.ParseProxyMode(req.Mode)
 		if err != nil {
 			return runops, err
 		}
 		runops.ProxyMode = proxyMode
 	}
-	if route, ok := payload["route"].(string); ok && route != "" {
-		routeMode, err := model.ParseRouteMode(route)
+	if req.Route != "" {
+		routeMode, err := model.ParseRouteMode(req.Route)
 		if err != nil {
 			return runops, err
 		}
 		runops.RouteMode = routeMode
 	}
-	if port, ok := ipc.AsInt(payload["api_port"]); ok && port > 0 {
-		runops.APIPort = port
+	if req.APIPort > 0 {
+		runops.APIPort = req.APIPort
 	}
-	if port, ok := ipc.AsInt(payload["mixed_port"]); ok && port > 0 {
-		runops.MixedPort = port
+	if req.MixedPort > 0 {
+		runops.MixedPort = req.MixedPort
 	}
 	return runops, nil
 }
 
-// applyRunOptions 重新构建配置并 reload sing-box
-func (d *Daemon) applyRunOptions(ctx context.Context, state *RuntimeState) error {
-	// 检查并设置 reloading 标志，防止并发 reload
+// reloadResult 汇总一次 applyRunOptions 的附带结果，供 IPC 响应使用
+type reloadResult struct {
+	Unrestored []string // reload 后未能恢复的节点选择
+	Skipped    bool     // 生成的配置与运行中的相同，未 reload sing-box
+}
+
+// info 转换为 IPC 响应中的 ReloadInfo
+func (r reloadResult) info() ReloadInfo {
+	info := ReloadInfo{Unrestored: r.Unrestored}
+	if r.Skipped {
+		reloaded := false
+		info.Reloaded = &reloaded
+	}
+	return info
+}
+
+// builtConfig 记录最近一次写入 raw.json 的构建输入摘要与输出摘要
+type builtConfig struct {
+	input  string
+	output [sha256.Size]byte
+}
+
+// setBuilt 记录已生效的构建结果；r 为 nil 时清空记录，下次必定重新构建并 reload
+func (d *Daemon) setBuilt(r *config.Rendered) {
 	d.mu.Lock()
-	if d.reloading {
-		d.mu.Unlock()
-		return errors.New("reload already in progress")
+	defer d.mu.Unlock()
+	if r == nil {
+		d.built = builtConfig{}
+		return
 	}
-	d.reloading = true
+	d.built = builtConfig{input: r.InputHash, output: sha256.Sum256(r.Data)}
+}
+
+// unchangedSinceBuild 判断构建输入与磁盘上的 raw.json 是否都与最近一次构建一致，
+// 此时重新构建只会得到相同的配置，可以整个跳过
+func (d *Daemon) unchangedSinceBuild(runops *model.RunOptions) bool {
+	d.mu.Lock()
+	built := d.built
 	d.mu.Unlock()
-	defer func() {
-		d.mu.Lock()
-		d.reloading = false
-		d.mu.Unlock()
-	}()
+	if built.input == "" {
+		return false
+	}
+	input, err := config.InputHash(runops)
+	if err != nil || input != built.input {
+		return false
+	}
+	current, err := os.ReadFile(paths.Get().RawConfigFile)
+	return err == nil && sha256.Sum256(current) == built.output
+}
 
-	backupPath, _ := backupConfig(paths.Get().RawConfigFile)
-	// BuildConfig 会将 MixedPort 等回填到 state.RunOptions
-	if err := config.BuildConfig(paths.Get().RawConfigFile, &state.RunOptions); err != nil {
-		return err
+// isRunningConfig 判断 data 是否就是 sing-box 当前运行的配置（且 raw.json 未被改动）
+func (d *Daemon) isRunningConfig(data []byte) bool {
+	d.mu.Lock()
+	built := d.built
+	d.mu.Unlock()
+	if built.input == "" || sha256.Sum256(data) != built.output {
+		return false
 	}
+	current, err := os.ReadFile(paths.Get().RawConfigFile)
+	return err == nil && bytes.Equal(current, data)
+}
+
+// applyRunOptions 重新构建配置并 reload sing-box，调用方需持有 reloadMu（见 reloadWith）
+func (d *Daemon) applyRunOptions(ctx context.Context, state *RuntimeState) (reloadResult, error) {
+	var result reloadResult
 	if d.service == nil {
 		err := errors.New("service not available")
-		return err
+		return result, err
+	}
+
+	// 输入未变化时不必重新构建；构建结果与运行中的 raw.json 相同时不必 reload。
+	// 延迟历史是 latency 排序的构建输入，先写入尚未落盘的探测结果
+	d.flushHistory()
+	if d.unchangedSinceBuild(&state.RunOptions) {
+		logger.Info("Config inputs unchanged, skipping rebuild and reload")
+		d.commitState(state)
+		result.Skipped = true
+		return result, nil
+	}
+	// Render 会将 MixedPort 等回填到 state.RunOptions；运行中的 sing-box 已监听的端口不算冲突
+	ports := newPortAllocator(state.Ports, d.listeningPorts())
+	rendered, err := config.Render(&state.RunOptions, ports)
+	if err != nil {
+		return result, err
+	}
+	state.Ports = mergePorts(state.Ports, ports)
+	if d.isRunningConfig(rendered.Data) {
+		logger.Info("Generated config unchanged, skipping reload")
+		if err := rendered.Save(paths.Get().RawConfigFile); err != nil {
+			return result, err
+		}
+		d.setBuilt(rendered)
+		d.commitState(state)
+		result.Skipped = true
+		return result, nil
+	}
+
+	// 关闭运行中的实例之前先校验新配置，校验失败时保持旧配置继续运行
+	if err := d.checkRendered(ctx, rendered); err != nil {
+		return result, err
+	}
+
+	if err := rendered.Save(paths.Get().RawConfigFile); err != nil {
+		return result, err
+	}
+	// 新实例启动失败时回退到当前运行的版本
+	if err := d.reloadRawConfig(ctx, state.RunOptions.ProxyMode, state.Generation); err != nil {
+		return result, err
+	}
+
+	state.Generation = recordGeneration(rendered, state.RunOptions)
+	d.setBuilt(rendered)
+	d.commitState(state)
+
+	d.syncSystemDNS(state.RunOptions.ProxyMode)
+	result.Unrestored = d.restoreSelections()
+	return result, nil
+}
+
+// checkRendered 将候选配置写入 raw.json 旁的临时文件并交给 sing-box 校验
+func (d *Daemon) checkRendered(ctx context.Context, rendered *config.Rendered) error {
+	candidate := paths.Get().RawConfigFile + ".candidate"
+	if err := config.WritePrivateFile(candidate, rendered.Data); err != nil {
+		return fmt.Errorf("failed to write candidate config: %w", err)
+	}
+	defer os.Remove(candidate)
+	if err := d.service.CheckFromFile(ctx, candidate); err != nil {
+		logger.Error("New config rejected, keeping the running one", "error", err)
+		return ipc.Errorf(ipc.CodeRejected, "new config rejected, sing-box keeps running the previous one: %w", err)
+	}
+	return nil
+}
+
+// newPortAllocator 创建端口分配器：上次自动分配的端口优先复用，owned 为当前 sing-box 已监听的端口
+func newPortAllocator(allocated map[string]int, owned map[int]bool) *moduleUtils.PortAllocator {
+	ports := moduleUtils.NewPortAllocator()
+	ports.Preferred = allocated
+	ports.Owned = owned
+	return ports
+}
+
+// listeningPorts 返回当前运行的 sing-box 已监听的端口
+func (d *Daemon) listeningPorts() map[int]bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.state == nil || !d.running {
+		return nil
+	}
+	owned := make(map[int]bool)
+	for _, port := range []int{d.state.RunOptions.APIPort, d.state.RunOptions.MixedPort, d.state.RunOptions.ProbePort} {
+		if port > 0 {
+			owned[port] = true
+		}
+	}
+	return owned
+}
+
+// mergePorts 合并本次自动分配的端口；本次未用到的用途（如 TUN 模式下的 mixed）保留原记录，
+// 切换回来时仍可复用，改为明确指定的用途则移除记录
+func mergePorts(previous map[string]int, ports *moduleUtils.PortAllocator) map[string]int {
+	merged := make(map[string]int, len(previous)+len(ports.Allocated))
+	for name, port := range previous {
+		if _, explicit := ports.Explicit[name]; !explicit {
+			merged[name] = port
+		}
+	}
+	for name, port := range ports.Allocated {
+		merged[name] = port
 	}
+	return merged
+}
 
+// commitState 将 reload 后的状态设为当前状态
+func (d *Daemon) commitState(state *RuntimeState) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// 构建期间 node.use 可能更新了选择记录，以当前记录为准
+	if d.state != nil {
+		state.Selections = d.state.clone().Selections
+	}
+	d.state = state
+}
+
+// reloadRawConfig 以 raw.json reload sing-box；新实例启动失败时以 fallback 版本重新启动
+func (d *Daemon) reloadRawConfig(ctx context.Context, mode model.ProxyMode, fallback int) error {
 	// TUN 模式下 reload 只是在同一进程内 Close 旧 box 再 Start 新 box，proxy_mode 本身
 	// 不变时 syncSystemDNS 会因为 mode == prev 而直接跳过，不会触发 networksetup 的
 	// DNS 变更事件。而这个事件正是 macOS 感知到新 TUN 接口、刷新路由/接口监听状态的
 	// 关键一步——完整的 stop+start 之所以能修复断网，就是因为 stop 时 RestoreSystemDNS
 	// 和 start 时 SetSystemDNS 各触发了一次该事件。这里强制复位 dnsMode，让 reload 后的
 	// syncSystemDNS 重新走一遍 restore -> re-apply,和 stop+start 保持一致。
-	if state.RunOptions.ProxyMode == model.ProxyModeTUN {
-		d.mu.Lock()
-		d.dnsMode = ""
-		d.mu.Unlock()
-		if err := sysnet.RestoreSystemDNS(); err != nil {
-			logger.Error("Failed to restore system DNS before reload", "error", err)
-		}
-	}
+	d.resetTUNDNS(mode)
 
 	if err := d.service.ReloadFromFile(ctx, paths.Get().RawConfigFile); err != nil {
+		d.setBuilt(nil)
 		var reloadErr *engine.ReloadError
 		if errors.As(err, &reloadErr) && reloadErr.Stage == engine.ReloadStageStart {
-			if backupPath != "" {
-				if retryErr := d.service.StartFromFile(ctx, backupPath); retryErr == nil {
-					if restoreErr := restoreConfig(backupPath, paths.Get().RawConfigFile); restoreErr != nil {
-						return restoreErr
-					}
-					d.setRunning(true)
-					_ = os.Remove(backupPath)
-				} else {
-					d.setRunning(false)
-				}
-			} else {
-				d.setRunning(false)
+			if !d.startGeneration(ctx, fallback) {
+				d.coreCrashed("sing-box failed to start after reload: " + reloadErr.Err.Error())
 			}
 		}
 		return err
 	}
-
-	d.mu.Lock()
-	d.state = state
-	d.mu.Unlock()
-
-	d.syncSystemDNS(state.RunOptions.ProxyMode)
 	return nil
 }
 
-func backupConfig(path string) (string, error) {
-	if path == "" {
-		return "", nil
+// startGeneration 以 id 版本的配置启动 sing-box 并将其恢复为 raw.json，成功时返回 true
+func (d *Daemon) startGeneration(ctx context.Context, id int) bool {
+	if id == 0 {
+		return false
 	}
-	if _, err := os.Stat(path); err != nil {
-		if os.IsNotExist(err) {
-			return "", nil
-		}
-		return "", err
+	gens := config.DefaultGenerations()
+	if _, err := gens.Get(id); err != nil {
+		logger.Error("No config generation to fall back to", "generation", id, "error", err)
+		return false
 	}
-	backup := path + ".bak"
-	input, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
+	if err := d.service.StartFromFile(ctx, gens.ConfigPath(id)); err != nil {
+		logger.Error("Failed to fall back to the previous config generation", "generation", id, "error", err)
+		return false
 	}
-	if err := os.WriteFile(backup, input, 0644); err != nil {
-		return "", err
+	if err := gens.Restore(id, paths.Get().RawConfigFile); err != nil {
+		logger.Error("Failed to restore raw.json from config generation", "generation", id, "error", err)
 	}
-	return backup, nil
+	logger.Info("Fell back to the previous config generation", "generation", id)
+	return true
 }
 
-func restoreConfig(backupPath, targetPath string) error {
-	if backupPath == "" || targetPath == "" {
-		return nil
-	}
-	data, err := os.ReadFile(backupPath)
+// recordGeneration 将生效的配置保存为新版本并返回版本号；保存失败不影响运行，只记录日志并返回 0
+func recordGeneration(rendered *config.Rendered, runops model.RunOptions) int {
+	gen, err := config.DefaultGenerations().Add(rendered, runops)
 	if err != nil {
-		return err
+		logger.Error("Failed to record config generation", "error", err)
+		return 0
 	}
-	return os.WriteFile(targetPath, data, 0644)
+	return gen.ID
 }
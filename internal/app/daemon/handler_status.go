@@ -2,79 +2,80 @@ package daemon
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
 	"github.com/kyson-dev/sing-helm/internal/sys/paths"
 )
 
-func (d *Daemon) handleStatus() ipc.CommandResult {
+func (d *Daemon) handleStatus(ctx context.Context, _ struct{}) (StatusResponse, error) {
 	running := d.isRunning()
 	state, err := d.currentState()
 	if err != nil && running {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
-	}
-	data := map[string]any{
-		"running": running,
+		return StatusResponse{}, err
 	}
+	resp := StatusResponse{Running: running, Watch: d.watchInfo(), Supervisor: d.supervisorInfo()}
+	d.mu.Lock()
+	resp.HTTPAddr = d.httpAddr
+	d.mu.Unlock()
 	if state != nil {
-		data["proxy_mode"] = state.RunOptions.ProxyMode
-		data["route_mode"] = state.RunOptions.RouteMode
-		data["pid"] = state.PID
-		data["api_port"] = state.RunOptions.APIPort
-		data["mixed_port"] = state.RunOptions.MixedPort
-		data["listen_addr"] = state.RunOptions.ListenAddr
+		resp.ProxyMode = string(state.RunOptions.ProxyMode)
+		resp.RouteMode = string(state.RunOptions.RouteMode)
+		resp.PID = state.PID
+		resp.APIPort = state.RunOptions.APIPort
+		resp.MixedPort = state.RunOptions.MixedPort
+		resp.ListenAddr = state.RunOptions.ListenAddr
+		resp.Generation = state.Generation
 	}
-	return ipc.CommandResult{Status: "ok", Data: data}
+	return resp, nil
 }
 
-func (d *Daemon) handleHealth() ipc.CommandResult {
-	running := d.isRunning()
-	data := map[string]any{"running": running}
+func (d *Daemon) handleHealth(ctx context.Context, _ struct{}) (HealthResponse, error) {
+	resp := HealthResponse{Running: d.isRunning()}
 	state, err := d.currentState()
 	if err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+		return resp, err
 	}
 	if state != nil {
-		data["pid"] = state.PID
+		resp.PID = state.PID
 	}
-	return ipc.CommandResult{Status: "ok", Data: data}
+	return resp, nil
 }
 
-func (d *Daemon) handleLog() ipc.CommandResult {
-	logPath := paths.Get().LogFile
-	return ipc.CommandResult{Status: "ok", Data: map[string]any{"path": logPath}}
+func (d *Daemon) handleLog(ctx context.Context, _ struct{}) (LogResponse, error) {
+	return LogResponse{Path: paths.Get().LogFile}, nil
 }
 
-func (d *Daemon) handleReload(ctx context.Context) ipc.CommandResult {
+// handleReload 按当前输入重新构建并 reload；req.Timeout（如 "30s"）为最长等待时间
+func (d *Daemon) handleReload(ctx context.Context, req ReloadRequest) (ReloadResponse, error) {
 	if !d.isRunning() {
-		return ipc.CommandResult{Status: "error", Error: "daemon not running"}
-	}
-	state, err := d.currentState()
-	if err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+		return ReloadResponse{}, errNotRunning
 	}
-	if state == nil {
-		return ipc.CommandResult{Status: "error", Error: "missing state"}
+	var wait time.Duration
+	if req.Timeout != "" {
+		parsed, err := time.ParseDuration(req.Timeout)
+		if err != nil {
+			return ReloadResponse{}, ipc.Errorf(ipc.CodeInvalidRequest, "invalid timeout: %s", req.Timeout)
+		}
+		wait = parsed
 	}
-	if err := d.applyRunOptions(ctx, state); err != nil {
-		return ipc.CommandResult{Status: "error", Error: err.Error()}
+	result, err := d.requestReload(ctx, "reload", nil, wait)
+	if err != nil {
+		return ReloadResponse{}, err
 	}
-	return ipc.CommandResult{Status: "ok"}
+	return ReloadResponse{ReloadInfo: result.info()}, nil
 }
 
-func (d *Daemon) handleStop() ipc.CommandResult {
+func (d *Daemon) handleStop(ctx context.Context, _ struct{}) (StopResponse, error) {
 	d.mu.Lock()
-	running := d.running
 	cancel := d.cancelFunc
 	d.mu.Unlock()
 
 	if cancel == nil {
-		if running {
-			return ipc.CommandResult{Status: "error", Error: "daemon not running"}
-		}
-		return ipc.CommandResult{Status: "error", Error: "daemon not running"}
+		return StopResponse{}, &ipc.Error{Code: ipc.CodeNotRunning, Err: errors.New("daemon not running")}
 	}
 	// 取消 daemon context 会触发所有子服务退出
 	cancel()
-	return ipc.CommandResult{Status: "ok"}
+	return StopResponse{}, nil
 }
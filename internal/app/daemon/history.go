@@ -0,0 +1,128 @@
+package daemon
+
+import (
+	"context"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	"github.com/kyson-dev/sing-helm/internal/proxy/history"
+	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+// historySaveDelay 最后一次记录之后多久写盘：并发的逐节点测速、看门狗的探测合并为一次写入
+const historySaveDelay = 2 * time.Second
+
+// NodeStats 节点的延迟统计（对应 node.stats）
+type NodeStats struct {
+	Tag string `json:"tag"`
+	ID  string `json:"id,omitempty"`
+	history.Stats
+}
+
+// latencyHistory 返回延迟历史存储，首次使用时从磁盘加载
+func (d *Daemon) latencyHistory() *history.Store {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.history == nil {
+		store, err := history.Open(paths.Get().LatencyFile)
+		if err != nil {
+			logger.Error("Failed to load latency history, starting empty", "error", err)
+		}
+		d.history = store
+	}
+	return d.history
+}
+
+// recordLatency 按节点稳定 ID 记录延迟结果并安排写盘，同时为结果补全 ID
+func (d *Daemon) recordLatency(results []LatencyResult) {
+	if len(results) == 0 {
+		return
+	}
+	idx, _ := config.LoadNodeIndex(paths.Get().NodeIndexFile)
+	store := d.latencyHistory()
+	now := time.Now()
+	for i := range results {
+		if results[i].ID == "" {
+			if entry, ok := idx.Lookup(results[i].Tag); ok {
+				results[i].ID = entry.ID
+			}
+		}
+		key := results[i].ID
+		if key == "" {
+			key = results[i].Tag
+		}
+		store.Record(key, results[i].Delay, now)
+	}
+	d.scheduleHistorySave()
+}
+
+// scheduleHistorySave 在 historySaveDelay 后写盘，已有待执行的写盘时不重复安排
+func (d *Daemon) scheduleHistorySave() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.historySave != nil {
+		return
+	}
+	d.historySave = time.AfterFunc(historySaveDelay, d.flushHistory)
+}
+
+// flushHistory 立即写入尚未落盘的延迟历史；构建配置（latency 排序以该文件为输入）与 daemon 退出前调用
+func (d *Daemon) flushHistory() {
+	d.mu.Lock()
+	if d.historySave != nil {
+		d.historySave.Stop()
+		d.historySave = nil
+	}
+	store := d.history
+	d.mu.Unlock()
+	if store == nil {
+		return
+	}
+	if err := store.Save(); err != nil {
+		logger.Error("Failed to save latency history", "error", err)
+	}
+}
+
+// handleNodeStats 返回节点的延迟统计（p50/p95、成功率、最后存活时间），按当前 tag 索引
+func (d *Daemon) handleNodeStats(ctx context.Context, req NodeStatsRequest) (NodeStatsResponse, error) {
+	var filter map[string]bool
+	if len(req.Nodes) > 0 {
+		filter = make(map[string]bool, len(req.Nodes))
+		for _, name := range req.Nodes {
+			filter[name] = true
+		}
+	}
+
+	idx, _ := config.LoadNodeIndex(paths.Get().NodeIndexFile)
+	if idx == nil {
+		idx = &config.NodeIndex{}
+	}
+	store := d.latencyHistory()
+	stats := make(map[string]NodeStats)
+	for _, entry := range idx.Nodes {
+		if filter != nil && !filter[entry.Tag] && !filter[entry.ID] {
+			continue
+		}
+		key := entry.ID
+		if key == "" {
+			key = entry.Tag
+		}
+		if st, ok := store.Stats(key); ok {
+			stats[entry.Tag] = NodeStats{Tag: entry.Tag, ID: entry.ID, Stats: st}
+		}
+	}
+	// 不在节点索引中的出站（如手写的 outbound）以 tag 记录
+	for name := range filter {
+		if _, indexed := idx.Lookup(name); indexed {
+			continue
+		}
+		if _, indexed := idx.Resolve(name); indexed {
+			continue
+		}
+		if st, ok := store.Stats(name); ok {
+			stats[name] = NodeStats{Tag: name, Stats: st}
+		}
+	}
+	return NodeStatsResponse{Stats: stats}, nil
+}
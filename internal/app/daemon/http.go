@@ -0,0 +1,374 @@
+package daemon
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+// ============================================================================
+// HTTP 控制接口：把 IPC 命令以 REST 端点的形式暴露在回环地址上，
+// 供 Raycast、Home Assistant、网页面板等无法直接使用 unix socket 的工具调用。
+// 所有请求都转换为 CommandMessage 交给 Handle / StreamFor，与 IPC 共用同一套处理函数
+// ============================================================================
+
+// httpRoute 一个 REST 端点对应的 IPC 命令
+type httpRoute struct {
+	pattern string // http.ServeMux 模式，如 "POST /mode"
+	command string
+	// params 将路径与查询参数并入 payload，为空时只使用请求体
+	params func(r *http.Request, payload map[string]any) error
+}
+
+// httpRoutes REST 端点；POST 请求体即 IPC 命令的 payload（JSON 对象）
+var httpRoutes = []httpRoute{
+	{pattern: "GET /hello", command: "hello"},
+	{pattern: "GET /status", command: "status"},
+	{pattern: "GET /health", command: "health"},
+	{pattern: "GET /log", command: "log"},
+	{pattern: "POST /run", command: "run"},
+	{pattern: "POST /stop", command: "stop"},
+	{pattern: "POST /mode", command: "mode"},
+	{pattern: "POST /route", command: "route"},
+	{pattern: "POST /reload", command: "reload"},
+	{pattern: "GET /nodes", command: "node.list"},
+	{pattern: "GET /nodes/stats", command: "node.stats", params: queryParams(nil, "nodes")},
+	{pattern: "POST /nodes/test", command: "node.test"},
+	{pattern: "GET /nodes/speedtest", command: "node.speedtest"},
+	{pattern: "POST /nodes/speedtest", command: "node.speedtest"},
+	{pattern: "GET /nodes/{tag...}", command: "node.info", params: pathParams("tag", queryParams([]string{"reveal"}))},
+	{pattern: "POST /nodes/{group}/select", command: "node.use", params: pathParams("group", nil)},
+	{pattern: "GET /config/generations", command: "config.generations"},
+	{pattern: "POST /config/rollback/{id}", command: "config.rollback", params: intPathParam("id")},
+}
+
+// pathParams 将路径参数 name 并入 payload，然后执行 next
+func pathParams(name string, next func(*http.Request, map[string]any) error) func(*http.Request, map[string]any) error {
+	return func(r *http.Request, payload map[string]any) error {
+		payload[name] = r.PathValue(name)
+		if next != nil {
+			return next(r, payload)
+		}
+		return nil
+	}
+}
+
+// intPathParam 将整数路径参数 name 并入 payload
+func intPathParam(name string) func(*http.Request, map[string]any) error {
+	return func(r *http.Request, payload map[string]any) error {
+		v, err := strconv.Atoi(r.PathValue(name))
+		if err != nil {
+			return ipc.Errorf(ipc.CodeInvalidRequest, "invalid %s: %s", name, r.PathValue(name))
+		}
+		payload[name] = v
+		return nil
+	}
+}
+
+// queryParams 将查询参数并入 payload：bools 中的参数按布尔值解析，lists 中的参数按逗号分隔为列表
+func queryParams(bools []string, lists ...string) func(*http.Request, map[string]any) error {
+	return func(r *http.Request, payload map[string]any) error {
+		query := r.URL.Query()
+		for _, name := range bools {
+			if !query.Has(name) {
+				continue
+			}
+			v, err := strconv.ParseBool(query.Get(name))
+			if err != nil {
+				return ipc.Errorf(ipc.CodeInvalidRequest, "invalid %s: %s", name, query.Get(name))
+			}
+			payload[name] = v
+		}
+		for _, name := range lists {
+			var items []any
+			for _, value := range query[name] {
+				for item := range strings.SplitSeq(value, ",") {
+					if item = strings.TrimSpace(item); item != "" {
+						items = append(items, item)
+					}
+				}
+			}
+			if len(items) > 0 {
+				payload[name] = items
+			}
+		}
+		return nil
+	}
+}
+
+// runHTTPServer 按 settings.json 的 http 设置启动 HTTP 控制接口，直到 ctx 取消
+func (d *Daemon) runHTTPServer(ctx context.Context) {
+	settings, err := config.LoadSettings(paths.Get().SettingsFile)
+	if err != nil || !settings.HTTP.Enabled {
+		return
+	}
+	listen := settings.HTTP.ListenValue()
+	if err := checkLoopback(listen); err != nil {
+		logger.Error("HTTP API disabled", "listen", listen, "error", err)
+		return
+	}
+	token, err := loadOrCreateToken(paths.Get().HTTPTokenFile)
+	if err != nil {
+		logger.Error("HTTP API disabled, failed to prepare token", "path", paths.Get().HTTPTokenFile, "error", err)
+		return
+	}
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		logger.Error("HTTP API disabled, failed to listen", "listen", listen, "error", err)
+		return
+	}
+
+	addr := listener.Addr().String()
+	d.mu.Lock()
+	d.httpAddr = addr
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		d.httpAddr = ""
+		d.mu.Unlock()
+	}()
+
+	// 不设置 WriteTimeout：/events 是长连接
+	server := &http.Server{
+		Handler:           d.httpHandler(token),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	})
+	defer stop()
+
+	logger.Info("HTTP API listening", "addr", addr, "token", paths.Get().HTTPTokenFile)
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("HTTP API stopped", "error", err)
+	}
+}
+
+// httpHandler 返回带令牌校验的 HTTP 处理器
+func (d *Daemon) httpHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	for _, route := range httpRoutes {
+		mux.HandleFunc(route.pattern, func(w http.ResponseWriter, r *http.Request) {
+			d.serveHTTPCommand(w, r, route)
+		})
+	}
+	mux.HandleFunc("GET /events", d.serveHTTPEvents)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeHTTPError(w, ipc.Errorf(ipc.CodeUnknownCommand, "no such endpoint: %s %s", r.Method, r.URL.Path), nil)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			// EventSource 无法设置请求头，允许通过查询参数传递令牌
+			got = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			logger.Warn("Denied HTTP API request", "method", r.Method, "path", r.URL.Path, "remote", r.RemoteAddr)
+			writeHTTPError(w, ipc.Errorf(ipc.CodePermissionDenied, "missing or invalid token"), nil)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// serveHTTPCommand 将请求转换为 IPC 命令并交给 Handle
+func (d *Daemon) serveHTTPCommand(w http.ResponseWriter, r *http.Request, route httpRoute) {
+	payload, err := readHTTPPayload(r)
+	if err == nil && route.params != nil {
+		err = route.params(r, payload)
+	}
+	if err != nil {
+		writeHTTPError(w, err, nil)
+		return
+	}
+	// 命令在 daemon 生命周期的 context 中执行：sing-box 由 run、reload 启动后继续使用该 context，
+	// 不能在响应结束时随请求取消。请求的 context 只用于等待结果
+	done := make(chan ipc.CommandResult, 1)
+	cmd := ipc.CommandMessage{Name: route.command, Payload: payload}
+	go func() { done <- d.Handle(d.lifetime(), cmd) }()
+	var result ipc.CommandResult
+	select {
+	case result = <-done:
+	case <-r.Context().Done():
+		if d.lifetime().Err() == nil {
+			// 客户端已断开，命令继续执行
+			return
+		}
+		// daemon 正在退出（如本请求就是 stop），关闭 HTTP 服务时会等待进行中的请求，仍然返回结果
+		result = <-done
+	}
+	if err := result.Err(); err != nil {
+		writeHTTPError(w, err, result.Data)
+		return
+	}
+	data := result.Data
+	if data == nil {
+		data = map[string]any{}
+	}
+	writeJSON(w, http.StatusOK, data)
+}
+
+// serveHTTPEvents 以 Server-Sent Events 推送事件，参数同 subscribe：
+// since（或 Last-Event-ID 请求头）与 types（逗号分隔）
+func (d *Daemon) serveHTTPEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHTTPError(w, ipc.Errorf(ipc.CodeInternal, "streaming unsupported"), nil)
+		return
+	}
+	payload := map[string]any{}
+	since := r.URL.Query().Get("since")
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		since = id
+	}
+	if since != "" {
+		v, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			writeHTTPError(w, ipc.Errorf(ipc.CodeInvalidRequest, "invalid since: %s", since), nil)
+			return
+		}
+		payload["since"] = v
+	}
+	if types := r.URL.Query()["types"]; len(types) > 0 {
+		payload["types"] = types
+	}
+	cmd := ipc.CommandMessage{Name: "subscribe", Payload: payload}
+	stream := d.StreamFor(cmd)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if ev, ok := v.(Event); ok {
+			fmt.Fprintf(w, "id: %d\nevent: %s\n", ev.Seq, ev.Type)
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	if err := stream(r.Context(), cmd, send); err != nil && r.Context().Err() == nil {
+		data, _ := json.Marshal(ipc.Fail(err))
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+}
+
+// readHTTPPayload 解析请求体中的 JSON 对象，空请求体返回空 payload
+func readHTTPPayload(r *http.Request) (map[string]any, error) {
+	payload := map[string]any{}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return nil, ipc.Errorf(ipc.CodeInvalidRequest, "read body: %w", err)
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return payload, nil
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, ipc.Errorf(ipc.CodeInvalidRequest, "body must be a JSON object: %w", err)
+	}
+	return payload, nil
+}
+
+// httpStatus 将 IPC 错误码映射为 HTTP 状态码
+func httpStatus(code string) int {
+	switch code {
+	case ipc.CodeUnknownCommand, ipc.CodeNotFound:
+		return http.StatusNotFound
+	case ipc.CodeInvalidRequest:
+		return http.StatusBadRequest
+	case ipc.CodeNotRunning, ipc.CodeAlreadyRunning:
+		return http.StatusConflict
+	case ipc.CodeRejected:
+		return http.StatusUnprocessableEntity
+	case ipc.CodeUnavailable:
+		return http.StatusBadGateway
+	case ipc.CodeTimeout:
+		return http.StatusGatewayTimeout
+	case ipc.CodePermissionDenied:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeHTTPError 输出 {"code", "error"}，处理函数返回了部分结果时附带 data
+func writeHTTPError(w http.ResponseWriter, err error, data map[string]any) {
+	code := ipc.ErrorCode(err)
+	if code == "" {
+		code = ipc.CodeInternal
+	}
+	body := map[string]any{"code": code, "error": err.Error()}
+	if len(data) > 0 {
+		body["data"] = data
+	}
+	writeJSON(w, httpStatus(code), body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// checkLoopback 确保 HTTP 接口只监听回环地址
+func checkLoopback(listen string) error {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("%s is not a loopback address", host)
+}
+
+// loadOrCreateToken 读取访问令牌，不存在时生成随机令牌并以 0600 权限写入
+func loadOrCreateToken(path string) (string, error) {
+	if data, err := os.ReadFile(path); err == nil {
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, os.Chmod(path, 0600)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
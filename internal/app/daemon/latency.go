@@ -0,0 +1,175 @@
+package daemon
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/clashapi"
+	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+)
+
+const (
+	defaultLatencyURL         = "http://www.gstatic.com/generate_204"
+	defaultLatencyTimeout     = 3000 // 毫秒
+	defaultLatencyConcurrency = 8
+)
+
+// nonNodeTypes 是 Clash API 中不代表具体节点的类型（策略组与内置出站）
+var nonNodeTypes = map[string]bool{
+	"Selector":    true,
+	"URLTest":     true,
+	"Fallback":    true,
+	"LoadBalance": true,
+	"Direct":      true,
+	"Reject":      true,
+	"Block":       true,
+	"DNS":         true,
+	"Pass":        true,
+	"Compatible":  true,
+}
+
+// LatencyResult 单个节点的延迟测试结果
+type LatencyResult struct {
+	Tag   string `json:"tag"`
+	ID    string `json:"id,omitempty"`
+	Delay int    `json:"delay"` // 毫秒，失败时为 0
+	Error string `json:"error,omitempty"`
+}
+
+// OK 表示测试成功
+func (r LatencyResult) OK() bool {
+	return r.Error == ""
+}
+
+// handleNodeTest 并发测试一个组（或全部）节点的延迟，可选切换到最快节点
+func (d *Daemon) handleNodeTest(ctx context.Context, req NodeTestRequest) (NodeTestResponse, error) {
+	if !d.isRunning() {
+		return NodeTestResponse{}, errNotRunning
+	}
+	nodes := slices.DeleteFunc(slices.Clone(req.Nodes), func(s string) bool { return s == "" })
+	group := req.Group
+	if group == "" && !req.All && len(nodes) == 0 {
+		return NodeTestResponse{}, ipc.Errorf(ipc.CodeInvalidRequest, "missing group")
+	}
+	if req.SelectBest && group == "" {
+		return NodeTestResponse{}, ipc.Errorf(ipc.CodeInvalidRequest, "select_best requires a group")
+	}
+	testURL := req.URL
+	if testURL == "" {
+		testURL = defaultLatencyURL
+	}
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = defaultLatencyTimeout
+	}
+	concurrency := req.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultLatencyConcurrency
+	}
+
+	apiAddr, err := d.resolveAPIAddr(req.API)
+	if err != nil {
+		return NodeTestResponse{}, err
+	}
+	c := clashapi.New(apiAddr)
+	proxies, err := c.GetProxies()
+	if err != nil {
+		return NodeTestResponse{}, ipc.WithCode(ipc.CodeUnavailable, err)
+	}
+
+	var tags []string
+	if len(nodes) > 0 {
+		for _, name := range nodes {
+			if _, ok := proxies[name]; !ok {
+				return NodeTestResponse{}, ipc.Errorf(ipc.CodeNotFound, "node not found: %s", name)
+			}
+			tags = append(tags, name)
+		}
+	} else if group != "" {
+		g, ok := proxies[group]
+		if !ok {
+			return NodeTestResponse{}, ipc.Errorf(ipc.CodeNotFound, "group not found: %s", group)
+		}
+		for _, member := range g.All {
+			if !nonNodeTypes[proxies[member].Type] {
+				tags = append(tags, member)
+			}
+		}
+	} else {
+		for name, p := range proxies {
+			if !nonNodeTypes[p.Type] {
+				tags = append(tags, name)
+			}
+		}
+	}
+
+	// 延迟测试本身受 timeout 限制，HTTP 客户端需留出余量
+	tester := clashapi.NewWithTimeout(apiAddr, time.Duration(timeout)*time.Millisecond+2*time.Second)
+	results := testLatency(tester, tags, testURL, timeout, concurrency)
+	d.recordLatency(results)
+
+	resp := NodeTestResponse{Results: results, URL: testURL, Timeout: timeout, Group: group}
+	if req.SelectBest {
+		if len(results) == 0 || !results[0].OK() {
+			return resp, ipc.Errorf(ipc.CodeUnavailable, "no reachable node in group %s", group)
+		}
+		best := results[0].Tag
+		if err := c.SelectProxy(group, best); err != nil {
+			return resp, ipc.WithCode(ipc.CodeUnavailable, err)
+		}
+		d.recordSelection(group, best)
+		resp.Selected = best
+	}
+	return resp, nil
+}
+
+// testLatency 以最多 concurrency 个并发测试 tags 的延迟，结果按延迟升序排列，失败的排在最后
+func testLatency(c *clashapi.Client, tags []string, testURL string, timeout, concurrency int) []LatencyResult {
+	results := make([]LatencyResult, len(tags))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	if concurrency > len(tags) {
+		concurrency = len(tags)
+	}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				res := LatencyResult{Tag: tags[i]}
+				delay, err := c.GetNodeDelay(tags[i], testURL, timeout)
+				if err != nil {
+					res.Error = err.Error()
+				} else {
+					res.Delay = delay
+				}
+				results[i] = res
+			}
+		}()
+	}
+	for i := range tags {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	sortLatencyResults(results)
+	return results
+}
+
+// sortLatencyResults 按延迟升序排序，失败的结果排在最后（按 tag 排序保证稳定输出）
+func sortLatencyResults(results []LatencyResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.OK() != b.OK() {
+			return a.OK()
+		}
+		if a.OK() && a.Delay != b.Delay {
+			return a.Delay < b.Delay
+		}
+		return a.Tag < b.Tag
+	})
+}
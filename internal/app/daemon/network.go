@@ -0,0 +1,130 @@
+package daemon
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/netmon"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+// EventNetwork 网络发生变化（默认路由、网卡、地址），data.action 为采取的处理
+const EventNetwork = "network"
+
+// runNetworkMonitor 在 daemon 生命周期内监听网络变化。
+// 监听始终运行，是否处理、如何处理在每次触发时按 settings.json 的 network 决定，修改设置即时生效
+func (d *Daemon) runNetworkMonitor(ctx context.Context) {
+	d.mu.Lock()
+	mon := d.monitor
+	d.mu.Unlock()
+	if mon == nil {
+		mon = netmon.New()
+	}
+	changes, err := mon.Watch(ctx)
+	if err != nil {
+		logger.Error("Failed to start network monitor", "error", err)
+		return
+	}
+
+	// 合并一段时间内的连续变化（如 Wi-Fi 切换时网卡、地址、路由先后变化），
+	// 最后一次变化之后 debounce 时间内没有新变化才处理
+	var timer *time.Timer
+	var fire <-chan time.Time
+	var pending []netmon.Change
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case c, ok := <-changes:
+			if !ok {
+				return
+			}
+			if isTunnelInterface(c.Interface) {
+				continue
+			}
+			if !slices.Contains(pending, c) {
+				pending = append(pending, c)
+			}
+			debounce := networkSettings().DebounceValue()
+			if timer == nil {
+				timer = time.NewTimer(debounce)
+			} else {
+				timer.Reset(debounce)
+			}
+			fire = timer.C
+		case <-fire:
+			fire = nil
+			d.handleNetworkChange(ctx, pending)
+			pending = nil
+		}
+	}
+}
+
+func networkSettings() model.NetworkSettings {
+	settings, _ := config.LoadSettings(paths.Get().SettingsFile)
+	return settings.Network
+}
+
+// isTunnelInterface 判断是否是 TUN 设备（sing-box 的 TUN 入站或其他 VPN）。
+// sing-box 启动、reload 时会创建 TUN 设备，这些变化不能再触发 reload
+func isTunnelInterface(name string) bool {
+	return strings.HasPrefix(name, "tun") || strings.HasPrefix(name, "utun")
+}
+
+// handleNetworkChange 按 settings.json 的 network.action 处理一批合并后的网络变化
+func (d *Daemon) handleNetworkChange(ctx context.Context, changes []netmon.Change) {
+	cfg := networkSettings()
+	if !cfg.EnabledValue() || !d.isRunning() {
+		return
+	}
+	state, err := d.currentState()
+	if err != nil || state == nil {
+		return
+	}
+	action := cfg.ActionValue(state.RunOptions.ProxyMode)
+
+	names := make([]string, 0, len(changes))
+	for _, c := range changes {
+		names = append(names, c.String())
+	}
+	summary := strings.Join(names, ", ")
+	logger.Info("Network changed", "changes", summary, "action", action)
+	d.emit(EventNetwork, "network changed: "+summary, map[string]any{
+		"changes": changes,
+		"action":  action,
+	})
+
+	switch action {
+	case model.NetworkActionDNS:
+		d.reapplySystemDNS()
+	case model.NetworkActionProbe:
+		d.reapplySystemDNS()
+		d.failoverWake.notify()
+		d.supervisor.wake.notify()
+	case model.NetworkActionReload:
+		// reload 的过程与结果由 reloadWith 推送事件，TUN 模式的 DNS 覆盖也在其中重新设置
+		if _, err := d.requestRestart(ctx, "network", 0); err != nil {
+			logger.Error("Reload after network change failed", "error", err)
+		}
+	}
+}
+
+// reapplySystemDNS 切换网络后系统可能为新的网络服务恢复 DHCP 下发的 DNS，TUN 模式下重新设置覆盖
+func (d *Daemon) reapplySystemDNS() {
+	d.mu.Lock()
+	mode := d.dnsMode
+	d.mu.Unlock()
+	if mode != model.ProxyModeTUN {
+		return
+	}
+	d.resetTUNDNS(mode)
+	d.syncSystemDNS(mode)
+}
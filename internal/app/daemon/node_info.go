@@ -0,0 +1,154 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+const redactedValue = "******"
+
+// secretKeys 是出站配置中需要脱敏的字段
+var secretKeys = map[string]bool{
+	"uuid":                   true,
+	"password":               true,
+	"private_key":            true,
+	"private_key_passphrase": true, // ssh
+	"pre_shared_key":         true,
+	"auth":                   true,
+	"auth_str":               true,
+	"token":                  true,
+	"psk":                    true,
+	"short_id":               true, // tls.reality
+}
+
+// stringSecretKeys 是值为字符串时才需要脱敏的字段：hysteria 的 obfs 是混淆密码，
+// hysteria2 的 obfs 是对象，其中的 password 按 secretKeys 脱敏
+var stringSecretKeys = map[string]bool{
+	"obfs": true,
+}
+
+// handleNodeInfo 返回生成节点的详细信息：来源、原始名称、协议参数及去重别名。
+// 读取运行时 raw.json，普通用户无需直接访问该文件；敏感字段默认脱敏。
+func (d *Daemon) handleNodeInfo(ctx context.Context, req NodeInfoRequest) (NodeInfoResponse, error) {
+	tag := req.Tag
+	if tag == "" {
+		return NodeInfoResponse{}, ipc.Errorf(ipc.CodeInvalidRequest, "missing tag")
+	}
+
+	outbounds, err := loadRawOutbounds(paths.Get().RawConfigFile)
+	if err != nil {
+		return NodeInfoResponse{}, err
+	}
+
+	idx, _ := config.LoadNodeIndex(paths.Get().NodeIndexFile)
+	entry, indexed := idx.Lookup(tag)
+	if _, ok := outbounds[tag]; !ok {
+		// 允许通过稳定 ID 或 "<source>/<name>" 查找
+		if resolved, ok := idx.Resolve(tag); ok {
+			tag = resolved
+			entry, indexed = idx.Lookup(tag)
+		}
+	}
+	outbound, ok := outbounds[tag]
+	if !ok {
+		return NodeInfoResponse{}, ipc.Errorf(ipc.CodeNotFound, "node not found: %s", tag)
+	}
+	if !req.Reveal {
+		outbound = redactSecrets(outbound).(map[string]any)
+	}
+
+	resp := NodeInfoResponse{
+		Tag:        tag,
+		Type:       outbound["type"],
+		Server:     outbound["server"],
+		ServerPort: outbound["server_port"],
+		Transport:  outbound["transport"],
+		TLS:        outbound["tls"],
+		Detour:     outbound["detour"],
+		Outbound:   outbound,
+		Revealed:   req.Reveal,
+	}
+	if indexed {
+		resp.ID = entry.ID
+		resp.Source = entry.Source
+		resp.Name = entry.Name
+		resp.Aliases = entry.Aliases
+	}
+	return resp, nil
+}
+
+// loadRawOutbounds 读取 raw.json 中的出站，按 tag 索引
+func loadRawOutbounds(path string) (map[string]map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ipc.Errorf(ipc.CodeNotRunning, "config not generated yet, start sing-helm first")
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var raw struct {
+		Outbounds []map[string]any `json:"outbounds"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	result := make(map[string]map[string]any, len(raw.Outbounds))
+	for _, out := range raw.Outbounds {
+		if tag, ok := out["tag"].(string); ok && tag != "" {
+			result[tag] = out
+		}
+	}
+	return result, nil
+}
+
+// redactSecrets 返回脱敏后的副本，递归处理嵌套对象（如 tls.reality、obfs）
+func redactSecrets(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			key := strings.ToLower(k)
+			if _, isString := item.(string); secretKeys[key] || (isString && stringSecretKeys[key]) {
+				out[k] = redactLeaf(item)
+				continue
+			}
+			out[k] = redactSecrets(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = redactSecrets(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func redactLeaf(v any) any {
+	switch val := v.(type) {
+	case string:
+		if val == "" {
+			return val
+		}
+		return redactedValue
+	case []any:
+		out := make([]any, len(val))
+		for i := range val {
+			out[i] = redactedValue
+		}
+		return out
+	case map[string]any:
+		return redactSecrets(val)
+	default:
+		return redactedValue
+	}
+}
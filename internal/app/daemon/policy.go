@@ -0,0 +1,195 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+
+	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+// ============================================================================
+// IPC 访问控制：socket 对所有用户开放（0666），按客户端的 uid/gid 区分
+// 只读命令与会改变 daemon 状态的命令。root、daemon 自身的用户以及通过 sudo
+// 启动 daemon 的用户始终拥有全部权限，其余用户由运行时目录下的 policy.json 决定
+// ============================================================================
+
+// access 命令所需的权限级别
+type access int
+
+const (
+	accessRead  access = iota // 只读：查询状态、节点与事件
+	accessWrite               // 修改：启停、切换模式/节点、reload 等
+)
+
+func (a access) String() string {
+	if a == accessRead {
+		return "read"
+	}
+	return "write"
+}
+
+// readOnlyCommands 只读命令，其余命令（包括未知命令）都需要 write 权限
+var readOnlyCommands = map[string]bool{
+	"hello":              true,
+	"status":             true,
+	"health":             true,
+	"log":                true,
+	"node.list":          true,
+	"node.info":          true,
+	"node.stats":         true,
+	"node.test":          true,
+	"node.speedtest":     true,
+	"events":             true,
+	"subscribe":          true,
+	"config.generations": true,
+}
+
+// requiredAccess 返回执行 cmd 所需的权限。部分只读命令的参数会改变其性质：
+// 指定 api 会让 daemon 连接任意地址，reveal 会返回节点密钥，
+// select_best 会切换节点，带节点的 speedtest 会占用 probe 并产生大量流量
+func requiredAccess(cmd ipc.CommandMessage) access {
+	if !readOnlyCommands[cmd.Name] {
+		return accessWrite
+	}
+	if api, _ := cmd.Payload["api"].(string); api != "" {
+		return accessWrite
+	}
+	switch cmd.Name {
+	case "node.info":
+		var req NodeInfoRequest
+		if cmd.Decode(&req) != nil || req.Reveal {
+			return accessWrite
+		}
+	case "node.test":
+		var req NodeTestRequest
+		if cmd.Decode(&req) != nil || req.SelectBest {
+			return accessWrite
+		}
+	case "node.speedtest":
+		var req NodeSpeedtestRequest
+		if cmd.Decode(&req) != nil || req.Node != "" || len(req.Nodes) > 0 {
+			return accessWrite
+		}
+	}
+	return accessRead
+}
+
+// Policy policy.json 的内容
+type Policy struct {
+	Read  *PolicyRule `json:"read,omitempty"`  // 为空时所有用户都可以执行只读命令
+	Write *PolicyRule `json:"write,omitempty"` // 额外允许执行修改命令的用户，同时拥有只读权限
+}
+
+// PolicyRule 允许的用户与用户组，用户组同时匹配主组与附加组
+type PolicyRule struct {
+	UIDs []uint32 `json:"uids,omitempty"`
+	GIDs []uint32 `json:"gids,omitempty"`
+}
+
+func (r *PolicyRule) allows(uid uint32, gids []uint32) bool {
+	if r == nil {
+		return false
+	}
+	if slices.Contains(r.UIDs, uid) {
+		return true
+	}
+	for _, gid := range gids {
+		if slices.Contains(r.GIDs, gid) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows 判断 uid（所属组 gids）是否拥有 need 权限；trusted 的用户不受限制
+func (p *Policy) allows(uid uint32, gids []uint32, need access, trusted []uint32) bool {
+	if slices.Contains(trusted, uid) {
+		return true
+	}
+	if p.Write.allows(uid, gids) {
+		return true
+	}
+	if need == accessWrite {
+		return false
+	}
+	return p.Read == nil || p.Read.allows(uid, gids)
+}
+
+// loadPolicy 读取 policy.json，文件不存在时使用默认策略（所有用户只读）
+func loadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Policy{}, nil
+		}
+		return nil, err
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// trustedUIDs root、daemon 自身的用户以及通过 sudo 启动 daemon 的用户
+func trustedUIDs() []uint32 {
+	uids := []uint32{0, uint32(os.Getuid())}
+	if v, err := strconv.ParseUint(os.Getenv("SUDO_UID"), 10, 32); err == nil {
+		uids = append(uids, uint32(v))
+	}
+	return uids
+}
+
+// peerGroups 返回客户端的主组与附加组；查询失败时只有主组
+func peerGroups(cred ipc.PeerCred) []uint32 {
+	gids := []uint32{cred.GID}
+	u, err := user.LookupId(strconv.FormatUint(uint64(cred.UID), 10))
+	if err != nil {
+		return gids
+	}
+	ids, err := u.GroupIds()
+	if err != nil {
+		return gids
+	}
+	for _, id := range ids {
+		if v, err := strconv.ParseUint(id, 10, 32); err == nil && uint32(v) != cred.GID {
+			gids = append(gids, uint32(v))
+		}
+	}
+	return gids
+}
+
+// Authorize 实现 ipc.Authorizer，每个通过 socket 收到的命令都会先经过这里。
+// 策略在每次请求时重新读取，修改 policy.json 即时生效；无法解析时只允许受信任的用户
+func (d *Daemon) Authorize(ctx context.Context, cmd ipc.CommandMessage) error {
+	need := requiredAccess(cmd)
+	cred, ok := ipc.PeerFromContext(ctx)
+	if !ok {
+		// 无法获取客户端身份（平台不支持）时只允许只读命令
+		if need == accessRead {
+			return nil
+		}
+		logger.Warn("Denied IPC command from unknown peer", "command", cmd.Name)
+		return ipc.Errorf(ipc.CodePermissionDenied, "permission denied: %s requires %s access", cmd.Name, need)
+	}
+
+	policy, err := loadPolicy(paths.Get().PolicyFile)
+	if err != nil {
+		logger.Error("Invalid IPC policy, only trusted users are allowed", "path", paths.Get().PolicyFile, "error", err)
+		policy = &Policy{Read: &PolicyRule{}}
+	}
+	if policy.allows(cred.UID, peerGroups(cred), need, trustedUIDs()) {
+		return nil
+	}
+	logger.Warn("Denied IPC command", "command", cmd.Name, "access", need.String(), "uid", cred.UID, "gid", cred.GID, "pid", cred.PID)
+	return ipc.Errorf(ipc.CodePermissionDenied, "permission denied: uid %d may not run %s (requires %s access, see %s)",
+		cred.UID, cmd.Name, need, paths.Get().PolicyFile)
+}
@@ -0,0 +1,102 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+)
+
+func TestRequiredAccess(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  ipc.CommandMessage
+		want access
+	}{
+		{"status", ipc.CommandMessage{Name: "status"}, accessRead},
+		{"subscribe", ipc.CommandMessage{Name: "subscribe"}, accessRead},
+		{"node list", ipc.CommandMessage{Name: "node.list"}, accessRead},
+		{"node list with api", ipc.CommandMessage{Name: "node.list", Payload: map[string]any{"api": "10.0.0.1:80"}}, accessWrite},
+		{"node info", ipc.CommandMessage{Name: "node.info", Payload: map[string]any{"tag": "a"}}, accessRead},
+		{"node info reveal", ipc.CommandMessage{Name: "node.info", Payload: map[string]any{"tag": "a", "reveal": true}}, accessWrite},
+		{"node test", ipc.CommandMessage{Name: "node.test", Payload: map[string]any{"group": "proxy"}}, accessRead},
+		{"node test select best", ipc.CommandMessage{Name: "node.test", Payload: map[string]any{"group": "proxy", "select_best": true}}, accessWrite},
+		{"stored speedtest", ipc.CommandMessage{Name: "node.speedtest"}, accessRead},
+		{"speedtest", ipc.CommandMessage{Name: "node.speedtest", Payload: map[string]any{"nodes": []any{"a"}}}, accessWrite},
+		{"stop", ipc.CommandMessage{Name: "stop"}, accessWrite},
+		{"mode", ipc.CommandMessage{Name: "mode", Payload: map[string]any{"mode": "tun"}}, accessWrite},
+		{"unknown", ipc.CommandMessage{Name: "bogus"}, accessWrite},
+	}
+	for _, tt := range tests {
+		if got := requiredAccess(tt.cmd); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestPolicyAllows(t *testing.T) {
+	trusted := []uint32{0, 501}
+	defaults := &Policy{}
+	if !defaults.allows(1000, []uint32{1000}, accessRead, trusted) {
+		t.Fatal("expected everyone to read by default")
+	}
+	if defaults.allows(1000, []uint32{1000}, accessWrite, trusted) {
+		t.Fatal("expected untrusted users not to write by default")
+	}
+	if !defaults.allows(501, []uint32{20}, accessWrite, trusted) {
+		t.Fatal("expected trusted users to write")
+	}
+
+	policy := &Policy{
+		Read:  &PolicyRule{GIDs: []uint32{20}},
+		Write: &PolicyRule{UIDs: []uint32{1001}, GIDs: []uint32{30}},
+	}
+	cases := []struct {
+		uid  uint32
+		gids []uint32
+		need access
+		want bool
+	}{
+		{1000, []uint32{20}, accessRead, true},
+		{1000, []uint32{20}, accessWrite, false},
+		{1000, []uint32{1000}, accessRead, false},
+		{1001, []uint32{1001}, accessWrite, true},
+		{1001, []uint32{1001}, accessRead, true},
+		{1002, []uint32{1002, 30}, accessWrite, true},
+		{0, nil, accessWrite, true},
+	}
+	for _, c := range cases {
+		if got := policy.allows(c.uid, c.gids, c.need, trusted); got != c.want {
+			t.Errorf("uid=%d gids=%v %s: expected %v, got %v", c.uid, c.gids, c.need, c.want, got)
+		}
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.json")
+
+	policy, err := loadPolicy(path)
+	if err != nil || policy.Read != nil || policy.Write != nil {
+		t.Fatalf("expected default policy for a missing file, got %+v err=%v", policy, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"read": {"gids": [20]}, "write": {"uids": [1000]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err = loadPolicy(path)
+	if err != nil {
+		t.Fatalf("load policy: %v", err)
+	}
+	if len(policy.Read.GIDs) != 1 || policy.Read.GIDs[0] != 20 || len(policy.Write.UIDs) != 1 || policy.Write.UIDs[0] != 1000 {
+		t.Fatalf("unexpected policy: %+v %+v", policy.Read, policy.Write)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"read": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadPolicy(path); err == nil {
+		t.Fatal("expected an error for a malformed policy")
+	}
+}
@@ -0,0 +1,335 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/clashapi"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
+	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+	"github.com/kyson-dev/sing-helm/internal/sys/version"
+)
+
+// ============================================================================
+// IPC 协议：每个命令的请求与响应结构。
+// 线上格式仍是 CommandMessage.Payload / CommandResult.Data 中的 JSON 字段，
+// 新增字段需保持可选，协议不兼容的变更需同时提升 ipc.ProtocolVersion
+// ============================================================================
+
+// errNotRunning sing-box 未运行时各命令返回的错误
+var errNotRunning = &ipc.Error{Code: ipc.CodeNotRunning, Err: errors.New("sing-box not running")}
+
+// HelloRequest hello 握手，客户端报告自身的协议版本
+type HelloRequest struct {
+	Protocol int `json:"protocol,omitempty"`
+}
+
+// HelloResponse daemon 的版本与支持的命令
+type HelloResponse struct {
+	Protocol int      `json:"protocol"`
+	Version  string   `json:"version"`
+	Commands []string `json:"commands"`
+}
+
+// Supports 判断 daemon 是否支持 name 命令
+func (r HelloResponse) Supports(name string) bool {
+	return slices.Contains(r.Commands, name)
+}
+
+// ReloadInfo reload 的附带结果，嵌入到会触发 reload 的命令的响应中
+type ReloadInfo struct {
+	Unrestored []string `json:"unrestored_selections,omitempty"` // reload 后未能恢复的节点选择
+	Reloaded   *bool    `json:"reloaded,omitempty"`              // 为 false 表示配置无变化，未 reload sing-box
+}
+
+// RunRequest run 命令，未指定的字段沿用上次的运行状态
+type RunRequest struct {
+	Mode      string `json:"mode,omitempty"`
+	Route     string `json:"route,omitempty"`
+	APIPort   int    `json:"api_port,omitempty"`
+	MixedPort int    `json:"mixed_port,omitempty"`
+}
+
+// RunResponse run 命令的结果
+type RunResponse struct {
+	ProxyMode string `json:"proxy_mode"`
+	RouteMode string `json:"route_mode"`
+	ReloadInfo
+}
+
+// StatusResponse status 命令的结果；sing-box 从未启动时只有 running、watch 与 supervisor
+type StatusResponse struct {
+	Running    bool           `json:"running"`
+	PID        int            `json:"pid,omitempty"`
+	ProxyMode  string         `json:"proxy_mode,omitempty"`
+	RouteMode  string         `json:"route_mode,omitempty"`
+	APIPort    int            `json:"api_port,omitempty"`
+	MixedPort  int            `json:"mixed_port,omitempty"`
+	ListenAddr string         `json:"listen_addr,omitempty"`
+	Generation int            `json:"generation,omitempty"`
+	Watch      WatchInfo      `json:"watch"`
+	HTTPAddr   string         `json:"http_addr,omitempty"` // HTTP 控制接口的监听地址，未启用时为空
+	Supervisor SupervisorInfo `json:"supervisor"`
+}
+
+// SupervisorInfo sing-box 内核监护的状态
+type SupervisorInfo struct {
+	Enabled         bool      `json:"enabled"`
+	Restarts        int       `json:"restarts"`              // 崩溃后成功重启的次数
+	Crashed         bool      `json:"crashed,omitempty"`     // 已崩溃，等待重启
+	NextRestart     time.Time `json:"next_restart,omitzero"` // 下一次重启的时间
+	LastCrash       time.Time `json:"last_crash,omitzero"`
+	LastCrashReason string    `json:"last_crash_reason,omitempty"`
+	LastGood        int       `json:"last_good_generation,omitempty"` // 最近一次运行正常的配置版本
+}
+
+// WatchInfo 配置监听的状态
+type WatchInfo struct {
+	Enabled    bool      `json:"enabled"`
+	Reloads    int       `json:"reloads"`
+	LastReload time.Time `json:"last_reload,omitzero"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
+// HealthResponse health 命令的结果
+type HealthResponse struct {
+	Running bool `json:"running"`
+	PID     int  `json:"pid,omitempty"`
+}
+
+// LogResponse log 命令的结果
+type LogResponse struct {
+	Path string `json:"path"`
+}
+
+// ModeRequest mode 命令
+type ModeRequest struct {
+	Mode string `json:"mode"`
+}
+
+// ModeResponse mode 命令的结果
+type ModeResponse struct {
+	ProxyMode string `json:"proxy_mode"`
+	ReloadInfo
+}
+
+// RouteRequest route 命令
+type RouteRequest struct {
+	Route string `json:"route"`
+}
+
+// RouteResponse route 命令的结果
+type RouteResponse struct {
+	RouteMode string `json:"route_mode"`
+	ReloadInfo
+}
+
+// ReloadRequest reload 命令，Timeout 为等待 reload 完成的时长（如 "90s"）
+type ReloadRequest struct {
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// ReloadResponse reload 命令的结果
+type ReloadResponse struct {
+	ReloadInfo
+}
+
+// StopResponse stop 命令的结果
+type StopResponse struct{}
+
+// NodeListRequest node.list 命令，API 可指定 Clash API 地址
+type NodeListRequest struct {
+	API string `json:"api,omitempty"`
+}
+
+// NodeListResponse node.list 命令的结果，附带节点索引以便按稳定 ID 追踪节点
+type NodeListResponse struct {
+	Proxies map[string]clashapi.ProxyData `json:"proxies"`
+	Nodes   []nodeProvider.NodeEntry      `json:"nodes,omitempty"`
+}
+
+// NodeUseRequest node.use 命令
+type NodeUseRequest struct {
+	API   string `json:"api,omitempty"`
+	Group string `json:"group"`
+	Node  string `json:"node"`
+}
+
+// NodeUseResponse node.use 命令的结果
+type NodeUseResponse struct {
+	Group string `json:"group"`
+	Node  string `json:"node"`
+}
+
+// NodeTestRequest node.test 命令：测试 Nodes、Group 的成员或全部节点（All）
+type NodeTestRequest struct {
+	API         string   `json:"api,omitempty"`
+	Group       string   `json:"group,omitempty"`
+	All         bool     `json:"all,omitempty"`
+	Nodes       []string `json:"nodes,omitempty"`
+	SelectBest  bool     `json:"select_best,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Timeout     int      `json:"timeout,omitempty"` // 毫秒
+	Concurrency int      `json:"concurrency,omitempty"`
+}
+
+// NodeTestResponse node.test 命令的结果，按延迟升序，失败的排在最后
+type NodeTestResponse struct {
+	Results  []LatencyResult `json:"results"`
+	URL      string          `json:"url"`
+	Timeout  int             `json:"timeout"`
+	Group    string          `json:"group,omitempty"`
+	Selected string          `json:"selected,omitempty"`
+}
+
+// NodeInfoRequest node.info 命令，Tag 也可以是稳定 ID 或 "<source>/<name>"
+type NodeInfoRequest struct {
+	Tag    string `json:"tag"`
+	Reveal bool   `json:"reveal,omitempty"`
+}
+
+// NodeInfoResponse node.info 命令的结果，协议参数保持 raw.json 中的原样
+type NodeInfoResponse struct {
+	Tag        string         `json:"tag"`
+	ID         string         `json:"id,omitempty"`
+	Source     string         `json:"source,omitempty"`
+	Name       string         `json:"name,omitempty"`
+	Aliases    []string       `json:"aliases,omitempty"`
+	Type       any            `json:"type"`
+	Server     any            `json:"server"`
+	ServerPort any            `json:"server_port"`
+	Transport  any            `json:"transport"`
+	TLS        any            `json:"tls"`
+	Detour     any            `json:"detour"`
+	Outbound   map[string]any `json:"outbound"`
+	Revealed   bool           `json:"revealed"`
+}
+
+// NodeStatsRequest node.stats 命令，Nodes 为空时返回全部节点
+type NodeStatsRequest struct {
+	Nodes []string `json:"nodes,omitempty"`
+}
+
+// NodeStatsResponse node.stats 命令的结果，按当前 tag 索引
+type NodeStatsResponse struct {
+	Stats map[string]NodeStats `json:"stats"`
+}
+
+// NodeSpeedtestRequest node.speedtest 命令，不带节点时返回已保存的结果
+type NodeSpeedtestRequest struct {
+	Node        string   `json:"node,omitempty"`
+	Nodes       []string `json:"nodes,omitempty"`
+	DownloadURL string   `json:"download_url,omitempty"`
+	UploadURL   *string  `json:"upload_url,omitempty"` // 为空字符串时跳过上传测试
+	UploadBytes int64    `json:"upload_bytes,omitempty"`
+	Timeout     int      `json:"timeout,omitempty"` // 毫秒
+}
+
+// NodeSpeedtestResponse node.speedtest 命令的结果
+type NodeSpeedtestResponse struct {
+	Results []SpeedResult `json:"results"`
+}
+
+// EventsRequest events 命令，只返回序号大于 Since 的事件
+type EventsRequest struct {
+	Since uint64 `json:"since,omitempty"`
+}
+
+// EventsResponse events 命令的结果
+type EventsResponse struct {
+	Events []Event `json:"events"`
+}
+
+// SubscribeRequest subscribe 命令。Since 为空时只推送新事件；Types 非空时只推送其中的类型
+type SubscribeRequest struct {
+	Since *uint64  `json:"since,omitempty"`
+	Types []string `json:"types,omitempty"`
+}
+
+// ConfigGenerationsResponse config.generations 命令的结果，新的在前
+type ConfigGenerationsResponse struct {
+	Generations []config.Generation `json:"generations"`
+	Current     int                 `json:"current,omitempty"` // 当前运行的版本
+}
+
+// ConfigRollbackRequest config.rollback 命令
+type ConfigRollbackRequest struct {
+	ID int `json:"id"`
+}
+
+// ConfigRollbackResponse config.rollback 命令的结果
+type ConfigRollbackResponse struct {
+	Generation int `json:"generation"`
+	ReloadInfo
+}
+
+// ============================================================================
+// 命令分发
+// ============================================================================
+
+// command 解析请求并执行一个 IPC 命令
+type command func(d *Daemon, ctx context.Context, cmd ipc.CommandMessage) ipc.CommandResult
+
+// handler 将带类型的处理函数包装为 command：请求解析失败返回 invalid_request，
+// 处理函数的错误按其错误码返回；出错时若同时返回了部分结果（如 node.test 的测试结果），一并放入 Data
+func handler[Req, Resp any](fn func(*Daemon, context.Context, Req) (Resp, error)) command {
+	return func(d *Daemon, ctx context.Context, cmd ipc.CommandMessage) ipc.CommandResult {
+		var req Req
+		if err := cmd.Decode(&req); err != nil {
+			return ipc.Fail(err)
+		}
+		resp, err := fn(d, ctx, req)
+		if err != nil {
+			result := ipc.Fail(err)
+			if !reflect.ValueOf(&resp).Elem().IsZero() {
+				result.Data = ipc.OK(resp).Data
+			}
+			return result
+		}
+		return ipc.OK(resp)
+	}
+}
+
+// commands 请求/响应式命令；流式命令见 StreamFor
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"hello":              handler((*Daemon).handleHello),
+		"run":                handler((*Daemon).handleRun),
+		"stop":               handler((*Daemon).handleStop),
+		"status":             handler((*Daemon).handleStatus),
+		"health":             handler((*Daemon).handleHealth),
+		"log":                handler((*Daemon).handleLog),
+		"mode":               handler((*Daemon).handleMode),
+		"route":              handler((*Daemon).handleRoute),
+		"reload":             handler((*Daemon).handleReload),
+		"node.list":          handler((*Daemon).handleNodeList),
+		"node.use":           handler((*Daemon).handleNodeUse),
+		"node.test":          handler((*Daemon).handleNodeTest),
+		"node.info":          handler((*Daemon).handleNodeInfo),
+		"node.stats":         handler((*Daemon).handleNodeStats),
+		"node.speedtest":     handler((*Daemon).handleNodeSpeedtest),
+		"events":             handler((*Daemon).handleEvents),
+		"config.generations": handler((*Daemon).handleConfigGenerations),
+		"config.rollback":    handler((*Daemon).handleConfigRollback),
+	}
+}
+
+// streamCommands 流式命令
+var streamCommands = []string{"subscribe"}
+
+// handleHello 返回协议版本、daemon 版本与支持的命令，供客户端判断兼容性
+func (d *Daemon) handleHello(ctx context.Context, req HelloRequest) (HelloResponse, error) {
+	names := make([]string, 0, len(commands)+len(streamCommands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	names = append(names, streamCommands...)
+	slices.Sort(names)
+	return HelloResponse{Protocol: ipc.ProtocolVersion, Version: version.Tag, Commands: names}, nil
+}
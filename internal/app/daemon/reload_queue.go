@@ -0,0 +1,183 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+)
+
+// defaultReloadWait 调用方等待 reload 完成的默认时长
+const defaultReloadWait = 60 * time.Second
+
+// reloadQueue 串行执行 reload：执行期间到达的请求排队，下一轮合并为一次 reload
+type reloadQueue struct {
+	mu      sync.Mutex
+	active  bool                      // 是否有 worker 在处理队列
+	changes []func(*model.RunOptions) // 待合并的 RunOptions 修改，按到达顺序应用
+	current []func(*model.RunOptions) // 正在执行的一轮 reload 应用的修改
+	reasons []string                  // 待合并请求的来源，记录在 reload 事件中
+	waiters []chan reloadOutcome      // 等待下一轮 reload 结果的调用方
+	force   bool                      // 有请求要求配置未变化时也重启 sing-box
+}
+
+// reloadOutcome 一轮 reload 的结果，同一轮合并的请求共享
+type reloadOutcome struct {
+	result reloadResult
+	err    error
+}
+
+// requestReload 将 change（可为 nil，表示仅按当前输入重新构建）合并进下一轮 reload，
+// 并等待包含该修改的 reload 完成，最多等待 wait（<= 0 时使用默认值）。
+// reason 说明请求来源（如 mode、watch）。等待超时或 ctx 取消都不会撤销请求，修改仍会在排队的 reload 中生效
+func (d *Daemon) requestReload(ctx context.Context, reason string, change func(*model.RunOptions), wait time.Duration) (reloadResult, error) {
+	return d.enqueueReload(ctx, reason, change, false, wait)
+}
+
+// requestRestart 与 requestReload 相同，但即使配置没有变化也重启 sing-box（如网络切换后重建 TUN）
+func (d *Daemon) requestRestart(ctx context.Context, reason string, wait time.Duration) (reloadResult, error) {
+	return d.enqueueReload(ctx, reason, nil, true, wait)
+}
+
+func (d *Daemon) enqueueReload(ctx context.Context, reason string, change func(*model.RunOptions), force bool, wait time.Duration) (reloadResult, error) {
+	done := make(chan reloadOutcome, 1)
+	q := &d.reloads
+	q.mu.Lock()
+	q.force = q.force || force
+	if change != nil {
+		q.changes = append(q.changes, change)
+	}
+	if !slices.Contains(q.reasons, reason) {
+		q.reasons = append(q.reasons, reason)
+	}
+	q.waiters = append(q.waiters, done)
+	if !q.active {
+		q.active = true
+		// worker 处理的是所有调用方合并后的请求，不能随第一个调用方的 context 取消；
+		// ctx 只用于下面的等待
+		go d.processReloads(d.lifetime())
+	}
+	q.mu.Unlock()
+
+	if wait <= 0 {
+		wait = defaultReloadWait
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-timer.C:
+		return reloadResult{}, fmt.Errorf("timed out after %s waiting for reload, the change stays queued", wait)
+	case <-ctx.Done():
+		return reloadResult{}, ctx.Err()
+	}
+}
+
+// queuedRunOptions 返回正在执行和排队的修改全部生效后的 RunOptions，即最终将运行的配置。
+// 修改只是设置字段，重复应用已提交的修改不影响结果
+func (d *Daemon) queuedRunOptions() (model.RunOptions, error) {
+	state, err := d.currentState()
+	if err != nil {
+		return model.RunOptions{}, err
+	}
+	if state == nil {
+		return model.RunOptions{}, errors.New("missing state")
+	}
+	q := &d.reloads
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, change := range slices.Concat(q.current, q.changes) {
+		change(&state.RunOptions)
+	}
+	return state.RunOptions, nil
+}
+
+// processReloads 处理队列直到为空；每轮取出全部待处理请求，合并为一次 reload
+func (d *Daemon) processReloads(ctx context.Context) {
+	q := &d.reloads
+	for {
+		q.mu.Lock()
+		if len(q.waiters) == 0 {
+			q.active = false
+			q.mu.Unlock()
+			return
+		}
+		changes, reasons, waiters, force := q.changes, q.reasons, q.waiters, q.force
+		q.changes, q.reasons, q.waiters, q.force = nil, nil, nil, false
+		q.current = changes
+		q.mu.Unlock()
+
+		result, err := d.reloadWith(ctx, reasons, changes, force)
+		q.mu.Lock()
+		q.current = nil
+		q.mu.Unlock()
+		for _, w := range waiters {
+			w <- reloadOutcome{result: result, err: err}
+		}
+	}
+}
+
+// reloadWith 在最新的运行状态上依次应用 changes 并重新构建、reload，过程与结果以事件推送。
+// force 为 true 时即使生成的配置没有变化也 reload
+func (d *Daemon) reloadWith(ctx context.Context, reasons []string, changes []func(*model.RunOptions), force bool) (reloadResult, error) {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+	if force {
+		// 清空构建记录，applyRunOptions 不会再以配置未变化为由跳过
+		d.setBuilt(nil)
+	}
+
+	state, err := d.currentState()
+	if err != nil {
+		return reloadResult{}, err
+	}
+	if state == nil {
+		return reloadResult{}, errors.New("missing state")
+	}
+	previous := state.RunOptions
+	for _, change := range changes {
+		change(&state.RunOptions)
+	}
+
+	d.emit(EventReloadStart, "reloading config", map[string]any{"reasons": reasons})
+	result, err := d.applyRunOptions(ctx, state)
+	if err != nil {
+		d.emit(EventReloadFailed, "reload failed, the previous config keeps running: "+err.Error(), map[string]any{
+			"reasons": reasons,
+			"error":   err.Error(),
+		})
+		return result, err
+	}
+	data := map[string]any{"reasons": reasons, "skipped": result.Skipped, "generation": state.Generation}
+	if len(result.Unrestored) > 0 {
+		data["unrestored_selections"] = result.Unrestored
+	}
+	message := "config reloaded"
+	if result.Skipped {
+		message = "config unchanged, sing-box not reloaded"
+	}
+	d.emit(EventReload, message, data)
+	d.emitModeChanges(previous, state.RunOptions)
+	return result, nil
+}
+
+// emitModeChanges 推送代理模式、路由模式的变化
+func (d *Daemon) emitModeChanges(previous, current model.RunOptions) {
+	if previous.ProxyMode != current.ProxyMode {
+		d.emit(EventMode, fmt.Sprintf("proxy mode changed to %s", current.ProxyMode), map[string]any{
+			"mode":     string(current.ProxyMode),
+			"previous": string(previous.ProxyMode),
+		})
+	}
+	if previous.RouteMode != current.RouteMode {
+		d.emit(EventRoute, fmt.Sprintf("route mode changed to %s", current.RouteMode), map[string]any{
+			"route":    string(current.RouteMode),
+			"previous": string(previous.RouteMode),
+		})
+	}
+}
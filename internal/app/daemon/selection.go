@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"fmt"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/clashapi"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+// recordSelection 记录 selector 组当前选中的成员，并立即持久化，
+// 使 reload / mode 切换 / 重启之后可以恢复用户的手动选择。
+func (d *Daemon) recordSelection(group, tag string) {
+	sel := NodeSelection{Tag: tag}
+	if idx, err := config.LoadNodeIndex(paths.Get().NodeIndexFile); err == nil {
+		if entry, ok := idx.Lookup(tag); ok {
+			sel.Key = entry.Key()
+		}
+	}
+
+	d.mu.Lock()
+	if d.state == nil {
+		d.state = &RuntimeState{}
+	}
+	if d.state.Selections == nil {
+		d.state.Selections = make(map[string]NodeSelection)
+	}
+	d.state.Selections[group] = sel
+	snapshot := d.state.clone()
+	d.mu.Unlock()
+
+	if err := SaveState(snapshot); err != nil {
+		logger.Error("Failed to save runtime state", "error", err)
+	}
+}
+
+// restoreSelections 在 sing-box (重新) 启动后重新应用已记录的节点选择。
+// 生成的 tag 可能因去重后缀变化，因此优先按稳定标识在新的节点索引中查找。
+// 返回无法恢复的选择描述，供 IPC 响应展示。
+func (d *Daemon) restoreSelections() []string {
+	state, _ := d.currentState()
+	if state == nil || len(state.Selections) == 0 {
+		return nil
+	}
+
+	idx, err := config.LoadNodeIndex(paths.Get().NodeIndexFile)
+	if err != nil {
+		logger.Debug("Node index unavailable, restoring selections by tag", "error", err)
+	}
+	apiAddr, err := d.resolveAPIAddr("")
+	if err != nil {
+		return []string{fmt.Sprintf("all groups: %v", err)}
+	}
+	c := clashapi.New(apiAddr)
+
+	var failed []string
+	updated := make(map[string]NodeSelection)
+	for group, sel := range state.Selections {
+		tag, ok := resolveSelection(sel, idx)
+		if !ok {
+			failed = append(failed, fmt.Sprintf("%s: node %q no longer exists", group, sel.Key))
+			continue
+		}
+		if err := c.SelectProxy(group, tag); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s: %v", group, tag, err))
+			continue
+		}
+		if tag != sel.Tag {
+			sel.Tag = tag
+			updated[group] = sel
+		}
+	}
+
+	if len(updated) > 0 {
+		d.mu.Lock()
+		if d.state != nil && d.state.Selections != nil {
+			for group, sel := range updated {
+				d.state.Selections[group] = sel
+			}
+		}
+		d.mu.Unlock()
+	}
+	for _, f := range failed {
+		logger.Error("Failed to restore node selection", "detail", f)
+	}
+	return failed
+}
+
+// resolveSelection 返回选择在当前配置中对应的 tag。
+// 非节点成员（auto、direct 等）没有稳定标识，直接沿用原 tag。
+func resolveSelection(sel NodeSelection, idx *config.NodeIndex) (string, bool) {
+	if sel.Key == "" {
+		return sel.Tag, sel.Tag != ""
+	}
+	if idx == nil {
+		return sel.Tag, sel.Tag != ""
+	}
+	return idx.Resolve(sel.Key)
+}
@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
+)
+
+func TestResolveSelection(t *testing.T) {
+	idx := &config.NodeIndex{Nodes: []nodeProvider.NodeEntry{
+		{Tag: "HK-01", Source: "sub-a", Name: "HK-01"},
+		{ID: "3f2a9c01d4e5b678", Tag: "HK-01 (sub-b)", Source: "sub-b", Name: "HK-01", Aliases: []string{"sub-c/HK-01"}},
+	}}
+
+	cases := []struct {
+		name   string
+		sel    NodeSelection
+		want   string
+		wantOK bool
+	}{
+		{"tag shifted", NodeSelection{Tag: "HK-01 #2", Key: "3f2a9c01d4e5b678"}, "HK-01 (sub-b)", true},
+		{"legacy key", NodeSelection{Tag: "HK-01 #2", Key: "sub-b/HK-01"}, "HK-01 (sub-b)", true},
+		{"deduplicated alias", NodeSelection{Tag: "HK-01 (sub-c)", Key: "sub-c/HK-01"}, "HK-01 (sub-b)", true},
+		{"node removed", NodeSelection{Tag: "JP-01", Key: "sub-a/JP-01"}, "", false},
+		{"non-node member", NodeSelection{Tag: "auto"}, "auto", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := resolveSelection(tc.sel, idx)
+			if ok != tc.wantOK || got != tc.want {
+				t.Fatalf("resolveSelection() = %q, %v; want %q, %v", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
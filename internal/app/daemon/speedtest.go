@@ -0,0 +1,274 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/clashapi"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
+	"github.com/kyson-dev/sing-helm/internal/sys/ipc"
+	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+const (
+	defaultSpeedDownloadURL = "https://speed.cloudflare.com/__down?bytes=25000000"
+	defaultSpeedUploadURL   = "https://speed.cloudflare.com/__up"
+	defaultSpeedUploadBytes = 5 << 20
+	defaultSpeedTimeout     = 15 * time.Second
+)
+
+// SpeedTestOptions 测速参数，下载/上传地址可指向本地 HTTP 服务以便离线验证
+type SpeedTestOptions struct {
+	DownloadURL string
+	UploadURL   string // 为空时跳过上传测试
+	UploadBytes int64
+	Timeout     time.Duration // 下载、上传各自的时间上限
+}
+
+// SpeedResult 单个节点的吞吐测试结果
+type SpeedResult struct {
+	Tag          string    `json:"tag"`
+	ID           string    `json:"id,omitempty"`
+	TTFBMs       int64     `json:"ttfb_ms"`
+	DownloadMbps float64   `json:"download_mbps"`
+	UploadMbps   float64   `json:"upload_mbps,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	TestedAt     time.Time `json:"tested_at"`
+}
+
+// handleNodeSpeedtest 通过 probe selector 让测速流量经过指定节点，测量 TTFB 和上下行吞吐。
+// 不带节点时返回已保存的结果。
+func (d *Daemon) handleNodeSpeedtest(ctx context.Context, req NodeSpeedtestRequest) (NodeSpeedtestResponse, error) {
+	var nodes []string
+	if req.Node != "" {
+		nodes = append(nodes, req.Node)
+	}
+	for _, node := range req.Nodes {
+		if node != "" {
+			nodes = append(nodes, node)
+		}
+	}
+
+	resultsPath := paths.Get().SpeedTestFile
+	if len(nodes) == 0 {
+		stored, err := loadSpeedResults(resultsPath)
+		if err != nil {
+			return NodeSpeedtestResponse{}, err
+		}
+		results := slices.Collect(maps.Values(stored))
+		slices.SortFunc(results, func(a, b SpeedResult) int { return strings.Compare(a.Tag, b.Tag) })
+		return NodeSpeedtestResponse{Results: results}, nil
+	}
+
+	if !d.isRunning() {
+		return NodeSpeedtestResponse{}, errNotRunning
+	}
+	state, err := d.currentState()
+	if err != nil {
+		return NodeSpeedtestResponse{}, err
+	}
+	if state == nil || state.RunOptions.ProbePort == 0 {
+		return NodeSpeedtestResponse{}, ipc.Errorf(ipc.CodeUnavailable, "speed test probe unavailable (no nodes configured?)")
+	}
+	apiAddr, err := d.resolveAPIAddr("")
+	if err != nil {
+		return NodeSpeedtestResponse{}, err
+	}
+
+	opts := SpeedTestOptions{
+		DownloadURL: defaultSpeedDownloadURL,
+		UploadURL:   defaultSpeedUploadURL,
+		UploadBytes: defaultSpeedUploadBytes,
+		Timeout:     defaultSpeedTimeout,
+	}
+	if req.DownloadURL != "" {
+		opts.DownloadURL = req.DownloadURL
+	}
+	if req.UploadURL != nil {
+		opts.UploadURL = *req.UploadURL
+	}
+	if req.UploadBytes > 0 {
+		opts.UploadBytes = req.UploadBytes
+	}
+	if req.Timeout > 0 {
+		opts.Timeout = time.Duration(req.Timeout) * time.Millisecond
+	}
+
+	// probe selector 是共享的，同一时间只允许一个测速
+	d.speedMu.Lock()
+	defer d.speedMu.Unlock()
+
+	proxyURL := &url.URL{
+		Scheme: "http",
+		User:   url.UserPassword(moduleUtils.ProbeUser, state.RunOptions.ProbeAuth),
+		Host:   fmt.Sprintf("127.0.0.1:%d", state.RunOptions.ProbePort),
+	}
+	c := clashapi.New(apiAddr)
+	idx, _ := config.LoadNodeIndex(paths.Get().NodeIndexFile)
+	results := make([]SpeedResult, 0, len(nodes))
+	for _, tag := range nodes {
+		var res SpeedResult
+		if err := c.SelectProxy(moduleUtils.TagProbe, tag); err != nil {
+			res = SpeedResult{Tag: tag, Error: err.Error(), TestedAt: time.Now()}
+		} else {
+			// 每个节点使用新的 Transport，避免复用经过上一个节点建立的连接
+			client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL), DisableKeepAlives: true}}
+			res = measureSpeed(ctx, client, opts)
+			res.Tag = tag
+		}
+		if entry, ok := idx.Lookup(tag); ok {
+			res.ID = entry.ID
+		}
+		results = append(results, res)
+	}
+
+	if err := storeSpeedResults(resultsPath, results); err != nil {
+		logger.Error("Failed to save speed test results", "error", err)
+	}
+	return NodeSpeedtestResponse{Results: results}, nil
+}
+
+// measureSpeed 使用 client 测量下载 TTFB/吞吐以及上传吞吐
+func measureSpeed(ctx context.Context, client *http.Client, opts SpeedTestOptions) SpeedResult {
+	res := SpeedResult{TestedAt: time.Now()}
+
+	ttfb, down, err := measureDownload(ctx, client, opts.DownloadURL, opts.Timeout)
+	res.TTFBMs = ttfb.Milliseconds()
+	res.DownloadMbps = down
+	if err != nil {
+		res.Error = fmt.Sprintf("download: %v", err)
+		return res
+	}
+
+	if opts.UploadURL != "" {
+		up, err := measureUpload(ctx, client, opts.UploadURL, opts.UploadBytes, opts.Timeout)
+		res.UploadMbps = up
+		if err != nil {
+			res.Error = fmt.Sprintf("upload: %v", err)
+		}
+	}
+	return res
+}
+
+// measureDownload 下载至结束或超时，吞吐按首字节之后的数据计算
+func measureDownload(ctx context.Context, client *http.Client, target string, timeout time.Duration) (time.Duration, float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var firstByte time.Time
+	trace := &httptrace.ClientTrace{GotFirstResponseByte: func() { firstByte = time.Now() }}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, target, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("bad status: %s", resp.Status)
+	}
+	if firstByte.IsZero() {
+		firstByte = time.Now()
+	}
+	ttfb := firstByte.Sub(start)
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	elapsed := time.Since(firstByte)
+	// 超时前已收到数据时按已下载量计算
+	if err != nil && !(errors.Is(err, context.DeadlineExceeded) && n > 0) {
+		return ttfb, 0, err
+	}
+	return ttfb, mbps(n, elapsed), nil
+}
+
+// measureUpload 上传 size 字节的数据，吞吐按整个请求耗时计算
+func measureUpload(ctx context.Context, client *http.Client, target string, size int64, timeout time.Duration) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, io.LimitReader(zeroReader{}, size))
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 400 {
+		return 0, fmt.Errorf("bad status: %s", resp.Status)
+	}
+	return mbps(size, time.Since(start)), nil
+}
+
+func mbps(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / elapsed.Seconds() / 1e6
+}
+
+// zeroReader 产生无限的零字节，用作上传数据
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// loadSpeedResults 读取保存的测速结果（按节点稳定 ID，缺失时按 tag 索引）
+func loadSpeedResults(path string) (map[string]SpeedResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]SpeedResult{}, nil
+		}
+		return nil, err
+	}
+	results := make(map[string]SpeedResult)
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to parse speed test results: %w", err)
+	}
+	return results, nil
+}
+
+// storeSpeedResults 合并新结果并写回
+func storeSpeedResults(path string, results []SpeedResult) error {
+	stored, err := loadSpeedResults(path)
+	if err != nil {
+		stored = make(map[string]SpeedResult)
+	}
+	for _, r := range results {
+		key := r.ID
+		if key == "" {
+			key = r.Tag
+		}
+		stored[key] = r
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
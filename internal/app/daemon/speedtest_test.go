@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMeasureSpeed_LocalEndpoint(t *testing.T) {
+	payload := strings.Repeat("x", 1<<20)
+	var uploaded int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down":
+			_, _ = io.WriteString(w, payload)
+		case "/up":
+			uploaded, _ = io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	res := measureSpeed(context.Background(), srv.Client(), SpeedTestOptions{
+		DownloadURL: srv.URL + "/down",
+		UploadURL:   srv.URL + "/up",
+		UploadBytes: 256 << 10,
+		Timeout:     5 * time.Second,
+	})
+	if res.Error != "" {
+		t.Fatalf("unexpected error: %s", res.Error)
+	}
+	if res.DownloadMbps <= 0 || res.UploadMbps <= 0 {
+		t.Fatalf("expected positive throughput, got %+v", res)
+	}
+	if uploaded != 256<<10 {
+		t.Fatalf("expected 256KiB uploaded, got %d", uploaded)
+	}
+
+	res = measureSpeed(context.Background(), srv.Client(), SpeedTestOptions{
+		DownloadURL: srv.URL + "/missing",
+		Timeout:     time.Second,
+	})
+	if res.Error == "" {
+		t.Fatalf("expected error for failing endpoint")
+	}
+}
+
+func TestStoreSpeedResults_MergesByNodeID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "speedtest.json")
+	if err := storeSpeedResults(path, []SpeedResult{{Tag: "HK 01", ID: "abc", DownloadMbps: 10}, {Tag: "JP 01", DownloadMbps: 5}}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	if err := storeSpeedResults(path, []SpeedResult{{Tag: "HK 01 #2", ID: "abc", DownloadMbps: 20}}); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	got, err := loadSpeedResults(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(got) != 2 || got["abc"].DownloadMbps != 20 || got["JP 01"].DownloadMbps != 5 {
+		t.Fatalf("unexpected stored results: %+v", got)
+	}
+}
@@ -4,13 +4,41 @@ import (
 	"encoding/json"
 	"os"
 
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
 	"github.com/kyson-dev/sing-helm/internal/sys/paths"
 )
 
 type RuntimeState struct {
-	RunOptions model.RunOptions `json:"run_options"`
-	PID        int              `json:"pid"`
+	RunOptions model.RunOptions         `json:"run_options"`
+	PID        int                      `json:"pid"`
+	Selections map[string]NodeSelection `json:"selections,omitempty"` // selector group -> selected member
+	Ports      map[string]int           `json:"ports,omitempty"`      // automatically allocated ports, reused across rebuilds
+	Generation int                      `json:"generation,omitempty"` // config generation sing-box is running
+}
+
+// NodeSelection records the member a selector group was switched to.
+type NodeSelection struct {
+	Tag string `json:"tag"`           // generated tag at the time of selection
+	Key string `json:"key,omitempty"` // stable node identity; empty for non-node members such as auto/direct
+}
+
+// clone returns a copy of s that shares no maps with the original.
+func (s *RuntimeState) clone() *RuntimeState {
+	copyState := *s
+	if s.Selections != nil {
+		copyState.Selections = make(map[string]NodeSelection, len(s.Selections))
+		for group, sel := range s.Selections {
+			copyState.Selections[group] = sel
+		}
+	}
+	if s.Ports != nil {
+		copyState.Ports = make(map[string]int, len(s.Ports))
+		for name, port := range s.Ports {
+			copyState.Ports[name] = port
+		}
+	}
+	return &copyState
 }
 
 // SaveState saves runtime state to the given path.
@@ -29,7 +57,8 @@ func SaveStateTo(path string, s *RuntimeState) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	// run_options 含测速入站的认证密码
+	return config.WritePrivateFile(path, data)
 }
 
 // LoadStateFrom loads runtime state from a specific path (DI-friendly).
@@ -0,0 +1,342 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/clashapi"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	"github.com/kyson-dev/sing-helm/internal/proxy/engine"
+	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+// 内核监护相关的事件类型
+const (
+	EventCrash   = "crash"   // sing-box 崩溃或失去响应，等待重启
+	EventRestart = "restart" // 崩溃后已重新启动 sing-box
+)
+
+const (
+	// restartBackoffBase 崩溃后第一次重启前的等待时间，之后每次失败翻倍
+	restartBackoffBase = time.Second
+	// errorProbeGap sing-box 输出 error 日志后提前探测的最小间隔，连接错误刷屏时不会频繁探测
+	errorProbeGap = 5 * time.Second
+)
+
+// errorReporter 由能上报 sing-box error 日志的 ServiceRunner 实现（engine 的实例）
+type errorReporter interface {
+	SetErrorHandler(engine.ErrorHandler)
+}
+
+// coreSupervisor 内核监护的状态：探测失败计数、崩溃记录与重启退避
+type coreSupervisor struct {
+	mu        sync.Mutex
+	failures  int       // 连续探测失败次数
+	down      bool      // 已判定崩溃，等待重启
+	attempts  int       // 本次崩溃以来失败的重启次数
+	next      time.Time // 下一次重启的时间
+	restarts  int       // 崩溃后成功重启的次数
+	crashedAt time.Time
+	reason    string    // 最近一次崩溃的原因
+	lastError string    // 上次探测正常以来 sing-box 的最后一条 error 日志
+	probedAt  time.Time // 上次因 error 日志提前探测的时间
+	good      int       // 最近一次探测正常时运行的配置版本
+	wake      wakeup    // 新的崩溃或 error 日志需要提前处理
+}
+
+// reportError 记录 sing-box 的 error 日志，距上次提前探测足够久时返回 true
+func (s *coreSupervisor) reportError(message string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError = message
+	if now.Sub(s.probedAt) < errorProbeGap {
+		return false
+	}
+	s.probedAt = now
+	return true
+}
+
+// observe 记录一次探测结果，连续失败达到 failures 次时返回 true（视为崩溃）
+func (s *coreSupervisor) observe(err error, generation, failures int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.failures = 0
+		s.lastError = ""
+		if generation > 0 {
+			s.good = generation
+		}
+		return false
+	}
+	s.failures++
+	return s.failures >= failures
+}
+
+// crash 记录一次崩溃并安排第一次重启，已处于崩溃状态时返回 false
+func (s *coreSupervisor) crash(reason string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		return false
+	}
+	if s.lastError != "" {
+		reason += " (last error: " + s.lastError + ")"
+	}
+	s.down = true
+	s.failures = 0
+	s.attempts = 0
+	s.next = now.Add(restartBackoffBase)
+	s.crashedAt = now
+	s.reason = reason
+	return true
+}
+
+// pending 返回是否处于崩溃状态，以及距下一次重启还需等待多久
+func (s *coreSupervisor) pending(now time.Time) (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.down {
+		return 0, false
+	}
+	return s.next.Sub(now), true
+}
+
+// fallback 返回本次重启使用的配置版本：连续失败 after 次后改用最近一次正常的版本，0 表示使用 raw.json
+func (s *coreSupervisor) fallback(after int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attempts < after {
+		return 0
+	}
+	return s.good
+}
+
+// restartFailed 记录一次失败的重启，按指数退避安排下一次，返回等待时间
+func (s *coreSupervisor) restartFailed(now time.Time, maxBackoff time.Duration) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	delay := maxBackoff
+	if s.attempts < 32 {
+		delay = min(restartBackoffBase<<s.attempts, maxBackoff)
+	}
+	s.next = now.Add(delay)
+	return delay
+}
+
+// restarted 重启成功，返回这是第几次尝试
+func (s *coreSupervisor) restarted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt := s.attempts + 1
+	s.down = false
+	s.attempts = 0
+	s.failures = 0
+	s.lastError = ""
+	s.restarts++
+	return attempt
+}
+
+// recovered sing-box 已由其他途径（如 run 命令）重新启动，清除崩溃状态
+func (s *coreSupervisor) recovered() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = false
+	s.attempts = 0
+	s.failures = 0
+}
+
+func (s *coreSupervisor) info() SupervisorInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := SupervisorInfo{
+		Restarts:        s.restarts,
+		Crashed:         s.down,
+		LastCrash:       s.crashedAt,
+		LastCrashReason: s.reason,
+		LastGood:        s.good,
+	}
+	if s.down {
+		info.NextRestart = s.next
+	}
+	return info
+}
+
+// supervisorInfo 返回内核监护的状态，供 status 使用
+func (d *Daemon) supervisorInfo() SupervisorInfo {
+	settings, _ := config.LoadSettings(paths.Get().SettingsFile)
+	info := d.supervisor.info()
+	info.Enabled = settings.Supervisor.EnabledValue()
+	return info
+}
+
+// runSupervisor 在 daemon 生命周期内监护 sing-box：周期性探测 Clash API，连续失败或 reload 后
+// 无法启动时视为崩溃，按退避间隔重启。每轮重新读取 settings.json 使修改即时生效
+func (d *Daemon) runSupervisor(ctx context.Context) {
+	s := &d.supervisor
+	var cfg model.SupervisorSettings
+	for {
+		wait := cfg.IntervalValue()
+		if delay, down := s.pending(time.Now()); down {
+			wait = delay
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		case <-s.wake.C():
+		}
+
+		settings, err := config.LoadSettings(paths.Get().SettingsFile)
+		if err != nil {
+			logger.Error("Failed to load settings", "error", err)
+		}
+		cfg = settings.Supervisor
+		if !cfg.EnabledValue() {
+			continue
+		}
+		if delay, down := s.pending(time.Now()); down {
+			if delay <= 0 {
+				d.restartCore(ctx, cfg)
+			}
+			continue
+		}
+		d.probeCore(cfg)
+	}
+}
+
+// probeCore 探测一次 Clash API；reload 进行中时 sing-box 本来就在重启，跳过本轮
+func (d *Daemon) probeCore(cfg model.SupervisorSettings) {
+	if !d.reloadMu.TryLock() {
+		return
+	}
+	defer d.reloadMu.Unlock()
+	if !d.isRunning() {
+		return
+	}
+	state, err := d.currentState()
+	if err != nil || state == nil {
+		return
+	}
+	apiAddr, err := d.resolveAPIAddr("")
+	if err != nil {
+		return
+	}
+	_, err = clashapi.NewWithTimeout(apiAddr, cfg.TimeoutValue()).GetConfigs()
+	if err != nil {
+		logger.Debug("Supervisor: probe failed", "error", err)
+	}
+	if d.supervisor.observe(err, state.Generation, cfg.FailuresValue()) {
+		d.coreCrashed(fmt.Sprintf("clash api not responding after %d probes: %v", cfg.FailuresValue(), err))
+	}
+}
+
+// coreError 接收 sing-box 的 error 日志：记录为崩溃原因的线索，并让监护循环提前探测
+func (d *Daemon) coreError(message string) {
+	if d.supervisor.reportError(message, time.Now()) {
+		d.supervisor.wake.notify()
+	}
+}
+
+// coreCrashed 记录 sing-box 崩溃并唤醒监护循环安排重启
+func (d *Daemon) coreCrashed(reason string) {
+	s := &d.supervisor
+	if !s.crash(reason, time.Now()) {
+		return
+	}
+	d.setRunning(false)
+	reason = s.info().LastCrashReason
+	logger.Error("Sing-box crashed", "reason", reason)
+	d.emit(EventCrash, "sing-box crashed: "+reason, map[string]any{"reason": reason})
+	s.wake.notify()
+}
+
+// restartCore 重新启动崩溃的 sing-box；连续失败 fallback_after 次后改用最近一次运行正常的配置版本
+func (d *Daemon) restartCore(ctx context.Context, cfg model.SupervisorSettings) {
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+	s := &d.supervisor
+
+	d.mu.Lock()
+	if d.running || d.state == nil {
+		// 等待期间已通过 run 命令重新启动
+		d.mu.Unlock()
+		s.recovered()
+		return
+	}
+	d.running = true
+	stale := d.service
+	d.service = nil
+	state := d.state.clone()
+	d.mu.Unlock()
+
+	// 崩溃的实例可能仍占用端口，先关闭
+	if stale != nil {
+		stale.Stop()
+	}
+	d.resetTUNDNS(state.RunOptions.ProxyMode)
+
+	gens := config.DefaultGenerations()
+	path := paths.Get().RawConfigFile
+	var gen config.Generation
+	fallback := s.fallback(cfg.FallbackAfterValue())
+	if fallback != 0 {
+		var err error
+		if gen, err = gens.Get(fallback); err != nil {
+			logger.Error("Last known-good config generation unavailable", "generation", fallback, "error", err)
+			fallback = 0
+		} else {
+			path = gens.ConfigPath(fallback)
+		}
+	}
+
+	logger.Info("Restarting sing-box", "config", path)
+	svc := d.newService()
+	if err := svc.StartFromFile(ctx, path); err != nil {
+		d.setRunning(false)
+		delay := s.restartFailed(time.Now(), cfg.MaxBackoffValue())
+		logger.Error("Failed to restart sing-box", "error", err, "retry_in", delay)
+		d.emit(EventError, "failed to restart sing-box: "+err.Error(), map[string]any{
+			"command":  "restart",
+			"retry_in": delay.String(),
+		})
+		return
+	}
+	d.mu.Lock()
+	d.service = svc
+	d.mu.Unlock()
+
+	if fallback != 0 {
+		if err := gens.Restore(fallback, paths.Get().RawConfigFile); err != nil {
+			logger.Error("Failed to restore raw.json from config generation", "generation", fallback, "error", err)
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			d.setBuilt(&config.Rendered{Data: data, InputHash: gen.InputHash})
+		} else {
+			d.setBuilt(nil)
+		}
+		state.RunOptions = gen.RunOptions
+		state.Generation = fallback
+		d.commitState(state)
+	}
+
+	attempt := s.restarted()
+	d.syncSystemDNS(state.RunOptions.ProxyMode)
+	unrestored := d.restoreSelections()
+
+	message := "sing-box restarted"
+	if fallback != 0 {
+		message = fmt.Sprintf("sing-box restarted on the last known-good generation %d", fallback)
+	}
+	logger.Info("Sing-box restarted", "attempt", attempt, "generation", state.Generation)
+	data := map[string]any{"attempt": attempt, "generation": state.Generation, "fallback": fallback != 0}
+	if len(unrestored) > 0 {
+		data["unrestored_selections"] = unrestored
+	}
+	d.emit(EventRestart, message, data)
+}
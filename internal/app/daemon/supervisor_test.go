@@ -0,0 +1,84 @@
+package daemon
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSupervisorCrashAndBackoff(t *testing.T) {
+	var s coreSupervisor
+	now := time.Now()
+	probeErr := errors.New("connection refused")
+
+	if s.observe(nil, 4, 3) {
+		t.Fatalf("healthy probe must not report a crash")
+	}
+	if s.observe(probeErr, 4, 3) || s.observe(probeErr, 4, 3) {
+		t.Fatalf("crash reported before 3 failures")
+	}
+	if !s.observe(probeErr, 4, 3) {
+		t.Fatalf("expected crash after 3 failures")
+	}
+
+	if !s.reportError("router: missing outbound", now) {
+		t.Fatalf("first error line should trigger an early probe")
+	}
+	if s.reportError("another error", now.Add(time.Second)) {
+		t.Fatalf("error lines within the probe gap must not trigger another probe")
+	}
+	if !s.crash("clash api not responding", now) {
+		t.Fatalf("expected first crash to be recorded")
+	}
+	if s.crash("again", now) {
+		t.Fatalf("crash while down must be ignored")
+	}
+	info := s.info()
+	if !info.Crashed || !strings.Contains(info.LastCrashReason, "last error: another error") {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+	if delay, down := s.pending(now); !down || delay != restartBackoffBase {
+		t.Fatalf("expected first restart after %s, got %s (down=%v)", restartBackoffBase, delay, down)
+	}
+
+	var delays []time.Duration
+	for range 6 {
+		delays = append(delays, s.restartFailed(now, 10*time.Second))
+	}
+	want := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Fatalf("backoff %d: expected %s, got %s", i, want[i], delays[i])
+		}
+	}
+
+	if attempt := s.restarted(); attempt != 7 {
+		t.Fatalf("expected restart on attempt 7, got %d", attempt)
+	}
+	info = s.info()
+	if info.Crashed || info.Restarts != 1 || !info.NextRestart.IsZero() {
+		t.Fatalf("unexpected info after restart: %+v", info)
+	}
+}
+
+func TestSupervisorFallsBackToLastGoodGeneration(t *testing.T) {
+	var s coreSupervisor
+	now := time.Now()
+	s.observe(nil, 5, 3)
+	s.observe(nil, 6, 3)
+	s.crash("reload failed", now)
+
+	for attempt := range 3 {
+		if gen := s.fallback(3); gen != 0 {
+			t.Fatalf("attempt %d: expected raw.json before fallback, got generation %d", attempt, gen)
+		}
+		s.restartFailed(now, time.Minute)
+	}
+	if gen := s.fallback(3); gen != 6 {
+		t.Fatalf("expected fallback to generation 6, got %d", gen)
+	}
+	if s.info().LastGood != 6 {
+		t.Fatalf("expected last good generation 6 in status")
+	}
+}
@@ -0,0 +1,205 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config"
+	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+// watchStatus 配置监听触发的 reload 统计，在 status 中展示
+type watchStatus struct {
+	Reloads    int
+	LastReload time.Time
+	LastError  string
+}
+
+// configWatcher 监听 profile.json 与订阅目录，合并一段时间内的连续写入后回调 trigger
+type configWatcher struct {
+	fs       *fsnotify.Watcher
+	files    map[string]bool // 单独监听的文件（其所在目录的其他文件忽略）
+	dirs     map[string]bool // 目录下所有 .json 文件都触发
+	debounce func() time.Duration
+	trigger  func(files []string)
+}
+
+// newConfigWatcher 监听 files 所在目录与 dirs，不存在的目录会被创建。
+// 编辑器通常以重命名方式保存文件，因此监听目录而不是文件本身
+func newConfigWatcher(files, dirs []string, debounce func() time.Duration, trigger func([]string)) (*configWatcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	w := &configWatcher{
+		fs:       fs,
+		files:    make(map[string]bool),
+		dirs:     make(map[string]bool),
+		debounce: debounce,
+		trigger:  trigger,
+	}
+	watched := make(map[string]bool)
+	add := func(dir string) error {
+		if watched[dir] {
+			return nil
+		}
+		watched[dir] = true
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		return fs.Add(dir)
+	}
+	for _, file := range files {
+		w.files[filepath.Clean(file)] = true
+		if err := add(filepath.Dir(filepath.Clean(file))); err != nil {
+			fs.Close()
+			return nil, err
+		}
+	}
+	for _, dir := range dirs {
+		w.dirs[filepath.Clean(dir)] = true
+		if err := add(filepath.Clean(dir)); err != nil {
+			fs.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// relevant 判断变化的文件是否是构建输入
+func (w *configWatcher) relevant(name string) bool {
+	name = filepath.Clean(name)
+	if w.files[name] {
+		return true
+	}
+	return w.dirs[filepath.Dir(name)] && strings.HasSuffix(name, ".json")
+}
+
+// run 处理文件事件直到 ctx 取消；最后一次相关事件之后 debounce 时间内没有新事件才回调
+func (w *configWatcher) run(ctx context.Context) {
+	defer w.fs.Close()
+	var timer *time.Timer
+	var fire <-chan time.Time
+	pending := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if ev.Op == fsnotify.Chmod || !w.relevant(ev.Name) {
+				continue
+			}
+			pending[filepath.Clean(ev.Name)] = true
+			if timer == nil {
+				timer = time.NewTimer(w.debounce())
+			} else {
+				timer.Reset(w.debounce())
+			}
+			fire = timer.C
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			logger.Error("Config watcher error", "error", err)
+		case <-fire:
+			fire = nil
+			files := make([]string, 0, len(pending))
+			for name := range pending {
+				files = append(files, name)
+			}
+			sort.Strings(files)
+			pending = make(map[string]bool)
+			w.trigger(files)
+		}
+	}
+}
+
+// runConfigWatcher 在 daemon 生命周期内监听构建输入文件。
+// 监听始终运行，是否自动 reload 在每次触发时按 settings.json 的 watch.enabled 决定，修改设置即时生效
+func (d *Daemon) runConfigWatcher(ctx context.Context) {
+	p := paths.Get()
+	debounce := func() time.Duration {
+		settings, _ := config.LoadSettings(p.SettingsFile)
+		return settings.Watch.DebounceValue()
+	}
+	w, err := newConfigWatcher([]string{p.ConfigFile}, []string{p.SubConfigDir, p.SubCacheDir}, debounce, func(files []string) {
+		d.reloadForWatch(ctx, files)
+	})
+	if err != nil {
+		logger.Error("Failed to start config watcher", "error", err)
+		return
+	}
+	w.run(ctx)
+}
+
+// reloadForWatch 文件变化后重新构建；reload 会校验新配置，且仅在生成的配置变化时 reload。
+// reload 的过程与结果由 reloadWith 推送事件，这里只推送订阅缓存的更新
+func (d *Daemon) reloadForWatch(ctx context.Context, files []string) {
+	if refreshed := subscriptionFiles(files); len(refreshed) > 0 {
+		d.emit(EventSubscription, "subscription cache updated", map[string]any{"files": refreshed})
+	}
+	settings, err := config.LoadSettings(paths.Get().SettingsFile)
+	if err != nil {
+		logger.Error("Failed to load settings", "error", err)
+	}
+	if !settings.Watch.Enabled || !d.isRunning() {
+		return
+	}
+	logger.Info("Config files changed, rebuilding", "files", files)
+	result, err := d.requestReload(ctx, "watch", nil, 0)
+	if err != nil {
+		logger.Error("Automatic reload failed", "error", err)
+		d.mu.Lock()
+		d.watch.LastError = err.Error()
+		d.mu.Unlock()
+		return
+	}
+	if result.Skipped {
+		logger.Info("Generated config unchanged, not reloading")
+		return
+	}
+
+	d.mu.Lock()
+	d.watch.Reloads++
+	d.watch.LastReload = time.Now()
+	d.watch.LastError = ""
+	d.mu.Unlock()
+}
+
+// subscriptionFiles 返回 files 中位于订阅缓存目录的文件
+func subscriptionFiles(files []string) []string {
+	cacheDir := filepath.Clean(paths.Get().SubCacheDir)
+	var out []string
+	for _, file := range files {
+		if filepath.Dir(file) == cacheDir {
+			out = append(out, file)
+		}
+	}
+	return out
+}
+
+// watchInfo 返回配置监听的状态，供 status 使用
+func (d *Daemon) watchInfo() WatchInfo {
+	settings, _ := config.LoadSettings(paths.Get().SettingsFile)
+	d.mu.Lock()
+	status := d.watch
+	d.mu.Unlock()
+	return WatchInfo{
+		Enabled:    settings.Watch.Enabled,
+		Reloads:    status.Reloads,
+		LastReload: status.LastReload,
+		LastError:  status.LastError,
+	}
+}
@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigWatcher_DebouncesBursts(t *testing.T) {
+	home := t.TempDir()
+	profile := filepath.Join(home, "profile.json")
+	subs := filepath.Join(home, "subscriptions")
+
+	triggered := make(chan []string, 4)
+	w, err := newConfigWatcher([]string{profile}, []string{subs}, func() time.Duration {
+		return 100 * time.Millisecond
+	}, func(files []string) {
+		triggered <- files
+	})
+	if err != nil {
+		t.Fatalf("newConfigWatcher: %v", err)
+	}
+	if _, err := os.Stat(subs); err != nil {
+		t.Fatalf("expected watched dir to be created: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.run(ctx)
+
+	// 非构建输入的文件不触发
+	if err := os.WriteFile(filepath.Join(home, "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(profile, []byte(`{}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(subs, "a.json"), []byte(`{}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	select {
+	case files := <-triggered:
+		want := []string{profile, filepath.Join(subs, "a.json")}
+		if !reflect.DeepEqual(files, want) {
+			t.Fatalf("expected %v, got %v", want, files)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the watcher to trigger")
+	}
+	select {
+	case files := <-triggered:
+		t.Fatalf("expected one trigger per burst, got another for %v", files)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
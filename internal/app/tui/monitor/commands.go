@@ -2,6 +2,7 @@ package monitor
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"sort"
@@ -64,46 +65,73 @@ func cmdReadTraffic(conn *websocket.Conn) tea.Cmd {
 	}
 }
 
+// cmdSubscribeEvents 订阅 daemon 事件流，事件经通道逐个交给 cmdReadEvent
+func cmdSubscribeEvents() tea.Cmd {
+	return func() tea.Msg {
+		ch := make(chan tea.Msg, 16)
+		go func() {
+			defer close(ch)
+			sender := ipc.NewUnixSender(paths.Get().SocketFile)
+			err := sender.Subscribe(context.Background(), ipc.CommandMessage{Name: "subscribe"}, func(raw json.RawMessage) error {
+				var ev daemonEvent
+				if err := json.Unmarshal(raw, &ev); err != nil {
+					return err
+				}
+				ch <- daemonEventMsg{Event: ev}
+				return nil
+			})
+			ch <- eventStreamClosedMsg{err: err}
+		}()
+		return eventStreamMsg{events: ch}
+	}
+}
+
+// cmdReadEvent 读取下一个事件（阻塞）
+func cmdReadEvent(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return eventStreamClosedMsg{}
+		}
+		return msg
+	}
+}
+
+// cmdResubscribeAfter 延迟重新订阅
+func cmdResubscribeAfter(delay time.Duration) tea.Cmd {
+	return tea.Tick(delay, func(t time.Time) tea.Msg {
+		return resubscribeTickMsg{}
+	})
+}
+
 // -----------------------------------------------------------------------------
 // 2. 数据获取命令
 // -----------------------------------------------------------------------------
 
-// cmdFetchStatus 获取状态信息
+// cmdFetchStatus 获取连接与流量统计；模式等 daemon 状态由事件流驱动，不再轮询
 func cmdFetchStatus(c *clashapi.Client) tea.Cmd {
 	return func() tea.Msg {
-		// 从 sing-box API 获取连接信息
 		conns, err := c.GetConnections()
-		// 从 daemon 获取配置模式 + API 地址
-		proxyMode := "unknown"
-		routeMode := "unknown"
-		apiBase := ""
-		if status, fetchErr := fetchDaemonStatus(); fetchErr == nil {
-			proxyMode = status.proxyMode
-			routeMode = status.routeMode
-			apiBase = status.apiBase
-		}
-
 		if err != nil {
-			return statusMsg{
-				ProxyMode: proxyMode,
-				RouteMode: routeMode,
-				APIBase:   apiBase,
-				Err:       err,
-			}
+			return statusMsg{Err: err}
 		}
-
 		return statusMsg{
-			ProxyMode:   proxyMode,
-			RouteMode:   routeMode,
 			Connections: len(conns.Connections),
 			Memory:      conns.Memory,
 			TotalUp:     conns.UploadTotal,
 			TotalDown:   conns.DownloadTotal,
-			APIBase:     apiBase,
 		}
 	}
 }
 
+// cmdFetchDaemonStatus 获取 daemon 状态（模式 + API 地址）
+func cmdFetchDaemonStatus() tea.Cmd {
+	return func() tea.Msg {
+		status, err := fetchDaemonStatus()
+		return daemonStatusMsg{Status: status, Err: err}
+	}
+}
+
 // cmdFetchProxies 获取代理列表
 func cmdFetchProxies(c *clashapi.Client) tea.Cmd {
 	return func() tea.Msg {
@@ -115,9 +143,22 @@ func cmdFetchProxies(c *clashapi.Client) tea.Cmd {
 	}
 }
 
-// cmdTestLatency 测试节点延迟
+// cmdTestLatency 测试节点延迟，优先经 daemon 测试以记入延迟历史，daemon 不可用时直连 API
 func cmdTestLatency(c *clashapi.Client, name string) tea.Cmd {
 	return func() tea.Msg {
+		resp, err := sendDaemonCommand("node.test", map[string]any{"nodes": []string{name}, "timeout": 2000})
+		if err == nil {
+			var results []struct {
+				Delay int    `json:"delay"`
+				Error string `json:"error"`
+			}
+			if data, mErr := json.Marshal(resp.Data["results"]); mErr == nil && json.Unmarshal(data, &results) == nil && len(results) == 1 {
+				if results[0].Error != "" {
+					return latencyMsg{Name: name, Delay: -1}
+				}
+				return latencyMsg{Name: name, Delay: results[0].Delay}
+			}
+		}
 		delay, err := c.GetNodeDelay(name, "http://www.gstatic.com/generate_204", 2000)
 		if err != nil {
 			return latencyMsg{Name: name, Delay: -1}
@@ -126,6 +167,53 @@ func cmdTestLatency(c *clashapi.Client, name string) tea.Cmd {
 	}
 }
 
+// cmdSpeedTest 通过 daemon 测试节点吞吐（经 probe 入站）
+func cmdSpeedTest(name string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		resp, err := sendDaemonCommandContext(ctx, "node.speedtest", map[string]any{"node": name})
+		if err != nil {
+			return speedMsg{Name: name, Err: err}
+		}
+		results := decodeSpeedResults(resp.Data["results"])
+		if len(results) == 0 {
+			return speedMsg{Name: name, Err: fmt.Errorf("no speed test result")}
+		}
+		return speedMsg{Name: name, Result: results[0]}
+	}
+}
+
+// cmdFetchSpeedResults 获取已保存的测速结果
+func cmdFetchSpeedResults() tea.Cmd {
+	return func() tea.Msg {
+		resp, err := sendDaemonCommand("node.speedtest", nil)
+		if err != nil {
+			return speedResultsMsg{Err: err}
+		}
+		return speedResultsMsg{Results: decodeSpeedResults(resp.Data["results"])}
+	}
+}
+
+// cmdFetchStats 获取节点延迟统计
+func cmdFetchStats() tea.Cmd {
+	return func() tea.Msg {
+		resp, err := sendDaemonCommand("node.stats", nil)
+		if err != nil {
+			return statsMsg{Err: err}
+		}
+		data, err := json.Marshal(resp.Data["stats"])
+		if err != nil {
+			return statsMsg{Err: err}
+		}
+		var stats map[string]nodeStats
+		if err := json.Unmarshal(data, &stats); err != nil {
+			return statsMsg{Err: err}
+		}
+		return statsMsg{Stats: stats}
+	}
+}
+
 // cmdStatusTick 状态定时刷新
 func cmdStatusTick(delay time.Duration) tea.Cmd {
 	return tea.Tick(delay, func(t time.Time) tea.Msg {
@@ -183,10 +271,10 @@ func cmdSwitchRoute(current string) tea.Cmd {
 	}
 }
 
-// cmdSwitchNode 切换节点
-func cmdSwitchNode(c *clashapi.Client, group, node string) tea.Cmd {
+// cmdSwitchNode 切换节点（经由 daemon，以便记录选择并在 reload 后恢复）
+func cmdSwitchNode(group, node string) tea.Cmd {
 	return func() tea.Msg {
-		err := c.SelectProxy(group, node)
+		_, err := sendDaemonCommand("node.use", map[string]any{"group": group, "node": node})
 		if err != nil {
 			return nodeChangedMsg{Group: group, Node: node, Err: err}
 		}
@@ -202,6 +290,10 @@ func cmdSwitchNode(c *clashapi.Client, group, node string) tea.Cmd {
 func extractGroups(proxies map[string]clashapi.ProxyData) []string {
 	var groups []string
 	for name, data := range proxies {
+		// probe 是测速专用的内部组，不展示
+		if name == "probe" {
+			continue
+		}
 		if data.Type == "Selector" || data.Type == "URLTest" {
 			groups = append(groups, name)
 		}
@@ -250,20 +342,38 @@ func fetchDaemonStatus() (*daemonStatus, error) {
 	return s, nil
 }
 
+// decodeSpeedResults 解析 node.speedtest 返回的结果（列表或按节点索引的 map）
+func decodeSpeedResults(raw any) []speedResult {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var list []speedResult
+	if err := json.Unmarshal(data, &list); err == nil {
+		return list
+	}
+	var byKey map[string]speedResult
+	if err := json.Unmarshal(data, &byKey); err != nil {
+		return nil
+	}
+	for _, r := range byKey {
+		list = append(list, r)
+	}
+	return list
+}
+
 func sendDaemonCommand(name string, payload map[string]any) (ipc.CommandResult, error) {
+	return sendDaemonCommandContext(context.Background(), name, payload)
+}
+
+func sendDaemonCommandContext(ctx context.Context, name string, payload map[string]any) (ipc.CommandResult, error) {
 	sender := ipc.NewUnixSender(paths.Get().SocketFile)
-	resp, err := sender.Send(context.Background(), ipc.CommandMessage{Name: name, Payload: payload})
+	resp, err := sender.Send(ctx, ipc.CommandMessage{Name: name, Payload: payload})
 	if err != nil {
 		return ipc.CommandResult{}, fmt.Errorf("ipc send failed: %w", err)
 	}
-	if resp.Status == "" {
-		resp.Status = "ok"
-	}
-	if resp.Status != "ok" {
-		if resp.Error != "" {
-			return resp, fmt.Errorf("daemon error: %s", resp.Error)
-		}
-		return resp, fmt.Errorf("daemon responded with status %s", resp.Status)
+	if err := resp.Err(); err != nil {
+		return resp, fmt.Errorf("daemon error: %w", err)
 	}
 	return resp, nil
 }
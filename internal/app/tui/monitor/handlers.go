@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"errors"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -80,12 +81,63 @@ func (m *Model) handleTraffic(msg trafficMsg) (Model, tea.Cmd) {
 	return *m, cmdReadTraffic(m.wsConn)
 }
 
-// handleStatus 处理状态信息
-func (m *Model) handleStatus(msg statusMsg) (Model, tea.Cmd) {
-	m.statusInFlight = false
-	if msg.APIBase != "" && msg.APIBase != m.apiBase {
-		m.apiBase = msg.APIBase
-		m.apiClient = clashapi.New(msg.APIBase)
+// handleEventStream 事件订阅建立后拉取一次 daemon 状态，之后的变化由事件推送
+func (m *Model) handleEventStream(msg eventStreamMsg) (Model, tea.Cmd) {
+	m.events = msg.events
+	return *m, tea.Batch(cmdReadEvent(m.events), cmdFetchDaemonStatus())
+}
+
+// handleEventStreamClosed 事件订阅断开后延迟重新订阅
+func (m *Model) handleEventStreamClosed(msg eventStreamClosedMsg) (Model, tea.Cmd) {
+	m.events = nil
+	if msg.err != nil {
+		logger.Debug("Event stream closed", "error", msg.err)
+	}
+	return *m, cmdResubscribeAfter(2 * time.Second)
+}
+
+// handleDaemonEvent 按事件类型更新状态，并继续读取下一个事件
+func (m *Model) handleDaemonEvent(msg daemonEventMsg) (Model, tea.Cmd) {
+	ev := msg.Event
+	m.lastEvent = &ev
+	cmds := []tea.Cmd{cmdReadEvent(m.events)}
+
+	switch ev.Type {
+	case "mode":
+		if mode, ok := ev.Data["mode"].(string); ok && mode != "" {
+			m.proxyMode = mode
+		}
+	case "route":
+		if route, ok := ev.Data["route"].(string); ok && route != "" {
+			m.routeMode = route
+		}
+	case "started", "reload":
+		// sing-box 重启后 API 地址与节点列表都可能变化
+		if skipped, _ := ev.Data["skipped"].(bool); !skipped {
+			cmds = append(cmds, cmdFetchDaemonStatus(), cmdFetchProxies(m.apiClient))
+		}
+	case "node", "failover", "failback":
+		cmds = append(cmds, cmdFetchProxies(m.apiClient))
+	case "reload_failed", "error":
+		m.lastError = errors.New(ev.Message)
+	}
+	return *m, tea.Batch(cmds...)
+}
+
+// handleDaemonStatus 处理 daemon 状态；API 地址变化时重连
+func (m *Model) handleDaemonStatus(msg daemonStatusMsg) (Model, tea.Cmd) {
+	if msg.Err != nil || msg.Status == nil {
+		return *m, nil
+	}
+	if msg.Status.proxyMode != "" {
+		m.proxyMode = msg.Status.proxyMode
+	}
+	if msg.Status.routeMode != "" {
+		m.routeMode = msg.Status.routeMode
+	}
+	if msg.Status.apiBase != "" && msg.Status.apiBase != m.apiBase {
+		m.apiBase = msg.Status.apiBase
+		m.apiClient = clashapi.New(msg.Status.apiBase)
 		if m.wsConn != nil {
 			m.wsConn.Close()
 			m.wsConn = nil
@@ -94,12 +146,16 @@ func (m *Model) handleStatus(msg statusMsg) (Model, tea.Cmd) {
 		m.reconnectWait = false
 		return *m, cmdConnect(m.apiBase)
 	}
+	return *m, nil
+}
+
+// handleStatus 处理连接与流量统计
+func (m *Model) handleStatus(msg statusMsg) (Model, tea.Cmd) {
+	m.statusInFlight = false
 	if msg.Err != nil {
 		return *m, cmdStatusTick(m.statusInterval)
 	}
 
-	m.proxyMode = msg.ProxyMode
-	m.routeMode = msg.RouteMode
 	m.connections = msg.Connections
 	m.memory = msg.Memory
 	m.traffic.TotalUp = msg.TotalUp
@@ -136,7 +192,7 @@ func (m *Model) handleProxies(msg proxiesMsg) (Model, tea.Cmd) {
 		m.cursor.Node = 0
 		m.expandedList = m.proxies[m.groups[0]].All
 
-		var cmds []tea.Cmd
+		cmds := []tea.Cmd{cmdFetchSpeedResults(), cmdFetchStats()}
 		for _, nodeName := range m.expandedList {
 			m.testing[nodeName] = true
 			cmds = append(cmds, cmdTestLatency(m.apiClient, nodeName))
@@ -151,6 +207,43 @@ func (m *Model) handleProxies(msg proxiesMsg) (Model, tea.Cmd) {
 func (m *Model) handleLatency(msg latencyMsg) (Model, tea.Cmd) {
 	delete(m.testing, msg.Name)
 	m.latencies[msg.Name] = msg.Delay
+	// 一批测试全部结束后刷新统计
+	if len(m.testing) == 0 {
+		return *m, cmdFetchStats()
+	}
+	return *m, nil
+}
+
+// handleStats 处理节点延迟统计
+func (m *Model) handleStats(msg statsMsg) (Model, tea.Cmd) {
+	if msg.Err != nil || msg.Stats == nil {
+		return *m, nil
+	}
+	m.stats = msg.Stats
+	return *m, nil
+}
+
+// handleSpeed 处理吞吐测试结果
+func (m *Model) handleSpeed(msg speedMsg) (Model, tea.Cmd) {
+	delete(m.speedTest, msg.Name)
+	if msg.Err != nil {
+		m.speeds[msg.Name] = speedResult{Tag: msg.Name, Error: msg.Err.Error()}
+		return *m, nil
+	}
+	m.speeds[msg.Name] = msg.Result
+	return *m, nil
+}
+
+// handleSpeedResults 处理已保存的测速结果
+func (m *Model) handleSpeedResults(msg speedResultsMsg) (Model, tea.Cmd) {
+	if msg.Err != nil {
+		return *m, nil
+	}
+	for _, r := range msg.Results {
+		if _, exists := m.speeds[r.Tag]; !exists {
+			m.speeds[r.Tag] = r
+		}
+	}
 	return *m, nil
 }
 
@@ -218,6 +311,9 @@ func (m *Model) handleKeyPress(msg tea.KeyMsg) (Model, tea.Cmd) {
 	case "t":
 		return m.handleKeyTest()
 
+	case "s":
+		return m.handleKeySpeedTest()
+
 	case "m":
 		return m.handleKeyMode()
 
@@ -304,7 +400,7 @@ func (m *Model) handleKeyEnter() (Model, tea.Cmd) {
 	group := m.groups[m.cursor.Group]
 	node := m.expandedList[m.cursor.Node]
 
-	return *m, cmdSwitchNode(m.apiClient, group, node)
+	return *m, cmdSwitchNode(group, node)
 }
 
 // handleKeyTest 处理测速键
@@ -322,6 +418,23 @@ func (m *Model) handleKeyTest() (Model, tea.Cmd) {
 	return *m, tea.Batch(cmds...)
 }
 
+// handleKeySpeedTest 对光标所在节点进行吞吐测试
+func (m *Model) handleKeySpeedTest() (Model, tea.Cmd) {
+	if !m.expanded || len(m.expandedList) == 0 {
+		return *m, nil
+	}
+	node := m.expandedList[m.cursor.Node]
+	// 策略组没有独立的吞吐，只测具体节点
+	if data, ok := m.proxies[node]; ok && (data.Type == "Selector" || data.Type == "URLTest") {
+		return *m, nil
+	}
+	if m.speedTest[node] {
+		return *m, nil
+	}
+	m.speedTest[node] = true
+	return *m, cmdSpeedTest(node)
+}
+
 // handleKeyMode 处理 mode 切换键
 // 使用互斥锁确保同一时间只有一个 mode/route 请求
 func (m *Model) handleKeyMode() (Model, tea.Cmd) {
@@ -1,6 +1,9 @@
 package monitor
 
 import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/gorilla/websocket"
 	"github.com/kyson-dev/sing-helm/internal/proxy/clashapi"
 )
@@ -37,21 +40,51 @@ type trafficMsg struct {
 	Down int64
 }
 
-// statusMsg 状态信息（从 daemon 获取）
+// statusMsg 连接与流量统计（从 sing-box API 获取）
 type statusMsg struct {
-	ProxyMode   string
-	RouteMode   string
 	Connections int
 	Memory      uint64
 	TotalUp     int64
 	TotalDown   int64
-	APIBase     string
 	Err         error
 }
 
 // statusTickMsg 触发状态轮询
 type statusTickMsg struct{}
 
+// daemonStatusMsg daemon 状态（模式 + API 地址）
+type daemonStatusMsg struct {
+	Status *daemonStatus
+	Err    error
+}
+
+// daemonEvent daemon 推送的事件（对应 daemon.Event）
+type daemonEvent struct {
+	Seq     uint64         `json:"seq"`
+	Time    time.Time      `json:"time"`
+	Type    string         `json:"type"`
+	Message string         `json:"message"`
+	Data    map[string]any `json:"data"`
+}
+
+// eventStreamMsg 事件订阅已建立
+type eventStreamMsg struct {
+	events <-chan tea.Msg
+}
+
+// daemonEventMsg 收到一个 daemon 事件
+type daemonEventMsg struct {
+	Event daemonEvent
+}
+
+// eventStreamClosedMsg 事件订阅断开（daemon 退出或重启，触发重新订阅）
+type eventStreamClosedMsg struct {
+	err error
+}
+
+// resubscribeTickMsg 重新订阅计时器触发
+type resubscribeTickMsg struct{}
+
 // proxiesMsg 代理节点列表
 type proxiesMsg struct {
 	Proxies map[string]clashapi.ProxyData
@@ -64,6 +97,43 @@ type latencyMsg struct {
 	Delay int // -1 表示失败/超时
 }
 
+// nodeStats 节点延迟统计（对应 daemon node.stats）
+type nodeStats struct {
+	Samples     int       `json:"samples"`
+	P50         int       `json:"p50"`
+	P95         int       `json:"p95"`
+	SuccessRate float64   `json:"success_rate"`
+	LastAlive   time.Time `json:"last_alive"`
+}
+
+// statsMsg 节点延迟统计
+type statsMsg struct {
+	Stats map[string]nodeStats
+	Err   error
+}
+
+// speedResult 节点吞吐测试结果（对应 daemon node.speedtest）
+type speedResult struct {
+	Tag          string  `json:"tag"`
+	TTFBMs       int64   `json:"ttfb_ms"`
+	DownloadMbps float64 `json:"download_mbps"`
+	UploadMbps   float64 `json:"upload_mbps"`
+	Error        string  `json:"error"`
+}
+
+// speedMsg 单个节点吞吐测试完成
+type speedMsg struct {
+	Name   string
+	Result speedResult
+	Err    error
+}
+
+// speedResultsMsg 已保存的测速结果
+type speedResultsMsg struct {
+	Results []speedResult
+	Err     error
+}
+
 // -----------------------------------------------------------------------------
 // 3. 请求结果消息
 // -----------------------------------------------------------------------------
@@ -3,6 +3,7 @@ package monitor
 import (
 	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/gorilla/websocket"
 	"github.com/kyson-dev/sing-helm/internal/proxy/clashapi"
 )
@@ -24,7 +25,11 @@ type Model struct {
 	lastError error                  // 最近的错误
 	updating  bool                   // mode/route 更新中（防止重复请求）
 
-	// 状态轮询控制
+	// daemon 事件流
+	events    <-chan tea.Msg // 事件订阅通道，nil 表示未订阅
+	lastEvent *daemonEvent   // 最近的事件，显示在界面底部
+
+	// 状态轮询控制（仅 sing-box 的连接与流量统计）
 	statusInFlight bool
 	statusInterval time.Duration
 	reconnectWait  bool
@@ -47,6 +52,9 @@ type Model struct {
 	proxies   map[string]clashapi.ProxyData // 代理详情
 	latencies map[string]int                // 节点延迟 (-1=失败, 0=未测试)
 	testing   map[string]bool               // 正在测速的节点
+	speeds    map[string]speedResult        // 节点吞吐测试结果
+	speedTest map[string]bool               // 正在进行吞吐测试的节点
+	stats     map[string]nodeStats          // 节点延迟统计（来自 daemon 历史）
 
 	// =========================================================================
 	// 第三层：UI 交互状态
@@ -85,6 +93,9 @@ func NewModel(apiHost string) Model {
 		proxies:   make(map[string]clashapi.ProxyData),
 		latencies: make(map[string]int),
 		testing:   make(map[string]bool),
+		speeds:    make(map[string]speedResult),
+		speedTest: make(map[string]bool),
+		stats:     make(map[string]nodeStats),
 	}
 }
 
@@ -143,6 +154,23 @@ func (m *Model) IsTesting(name string) bool {
 	return m.testing[name]
 }
 
+// Speed 获取节点吞吐测试结果
+func (m *Model) Speed(name string) (speedResult, bool) {
+	r, ok := m.speeds[name]
+	return r, ok
+}
+
+// Stats 获取节点延迟统计
+func (m *Model) Stats(name string) (nodeStats, bool) {
+	st, ok := m.stats[name]
+	return st, ok
+}
+
+// IsSpeedTesting 节点是否正在进行吞吐测试
+func (m *Model) IsSpeedTesting(name string) bool {
+	return m.speedTest[name]
+}
+
 // Cursor 获取光标状态
 func (m *Model) Cursor() CursorState {
 	return m.cursor
@@ -168,6 +196,11 @@ func (m *Model) Memory() uint64 {
 	return m.memory
 }
 
+// LastEvent 获取最近的 daemon 事件
+func (m *Model) LastEvent() *daemonEvent {
+	return m.lastEvent
+}
+
 // LastError 获取最近的错误
 func (m *Model) LastError() error {
 	return m.lastError
@@ -165,3 +165,67 @@ func TestSmartReconnect(t *testing.T) {
 	assert.Equal(t, "Connecting", m.ConnState().String())
 	assert.True(t, m.IsUpdating())
 }
+
+// TestUpdate_Stats 验证延迟统计的更新与渲染
+func TestUpdate_Stats(t *testing.T) {
+	m := NewModel("localhost:9090")
+
+	updated, _ := m.Update(statsMsg{Stats: map[string]nodeStats{
+		"HK-01": {Samples: 20, P50: 80, P95: 210, SuccessRate: 0.9},
+		"JP-01": {Samples: 1, P50: 50, P95: 50, SuccessRate: 1},
+	}})
+	m = updated.(Model)
+
+	assert.Contains(t, renderStats(m, "HK-01"), "p95  210  90%", "Should render p95 and success rate")
+	assert.Empty(t, renderStats(m, "JP-01"), "A single sample is not meaningful")
+	assert.Empty(t, renderStats(m, "US-01"), "Unknown nodes render nothing")
+
+	// 失败的统计请求不应清空已有数据
+	updated, _ = m.Update(statsMsg{Err: assert.AnError})
+	m = updated.(Model)
+	_, ok := m.Stats("HK-01")
+	assert.True(t, ok)
+}
+
+// TestUpdate_DaemonEvents 验证 daemon 事件驱动的状态更新
+func TestUpdate_DaemonEvents(t *testing.T) {
+	m := NewModel("localhost:9090")
+	m.connState.OnConnected()
+	events := make(chan tea.Msg, 1)
+	m.events = events
+
+	updated, cmd := m.Update(daemonEventMsg{Event: daemonEvent{
+		Type:    "mode",
+		Message: "proxy mode changed to tun",
+		Data:    map[string]any{"mode": "tun", "previous": "system"},
+	}})
+	m = updated.(Model)
+	assert.Equal(t, "tun", m.ProxyMode(), "Mode event should update the mode")
+	assert.NotNil(t, cmd, "Should keep reading the event stream")
+
+	updated, _ = m.Update(daemonEventMsg{Event: daemonEvent{
+		Type: "route",
+		Data: map[string]any{"route": "global"},
+	}})
+	m = updated.(Model)
+	assert.Equal(t, "global", m.RouteMode(), "Route event should update the route")
+
+	updated, _ = m.Update(daemonEventMsg{Event: daemonEvent{
+		Type:    "reload_failed",
+		Message: "reload failed, the previous config keeps running",
+	}})
+	m = updated.(Model)
+	assert.Error(t, m.LastError())
+	assert.Contains(t, m.View(), "reload failed", "Should show the last event")
+
+	// 下一个事件从订阅通道读取
+	events <- daemonEventMsg{Event: daemonEvent{Type: "node"}}
+	next := cmdReadEvent(m.events)()
+	assert.IsType(t, daemonEventMsg{}, next)
+
+	// 订阅断开后延迟重新订阅
+	updated, cmd = m.Update(eventStreamClosedMsg{})
+	m = updated.(Model)
+	assert.Nil(t, m.events)
+	assert.NotNil(t, cmd)
+}
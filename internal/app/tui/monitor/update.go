@@ -12,6 +12,7 @@ import (
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		cmdConnect(m.apiBase),
+		cmdSubscribeEvents(),
 	)
 }
 
@@ -34,6 +35,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		newM, cmd := m.handleReconnectTick()
 		return newM, cmd
 
+	case eventStreamMsg:
+		newM, cmd := m.handleEventStream(msg)
+		return newM, cmd
+
+	case eventStreamClosedMsg:
+		newM, cmd := m.handleEventStreamClosed(msg)
+		return newM, cmd
+
+	case resubscribeTickMsg:
+		return m, cmdSubscribeEvents()
+
 	// =========================================================================
 	// 数据更新消息
 	// =========================================================================
@@ -45,6 +57,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		newM, cmd := m.handleStatus(msg)
 		return newM, cmd
 
+	case daemonStatusMsg:
+		newM, cmd := m.handleDaemonStatus(msg)
+		return newM, cmd
+
+	case daemonEventMsg:
+		newM, cmd := m.handleDaemonEvent(msg)
+		return newM, cmd
+
 	case statusTickMsg:
 		newM, cmd := m.handleStatusTick()
 		return newM, cmd
@@ -57,6 +77,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		newM, cmd := m.handleLatency(msg)
 		return newM, cmd
 
+	case statsMsg:
+		newM, cmd := m.handleStats(msg)
+		return newM, cmd
+
+	case speedMsg:
+		newM, cmd := m.handleSpeed(msg)
+		return newM, cmd
+
+	case speedResultsMsg:
+		newM, cmd := m.handleSpeedResults(msg)
+		return newM, cmd
+
 	// =========================================================================
 	// 请求结果消息
 	// =========================================================================
@@ -89,15 +89,12 @@ func (m Model) View() string {
 	help := renderHelpBar()
 
 	// 最终拼接
-	content := lipgloss.JoinVertical(lipgloss.Left,
-		header,
-		"",
-		cards,
-		"",
-		proxies,
-		"",
-		help,
-	)
+	parts := []string{header, "", cards, "", proxies, ""}
+	if event := renderLastEvent(m); event != "" {
+		parts = append(parts, event, "")
+	}
+	parts = append(parts, help)
+	content := lipgloss.JoinVertical(lipgloss.Left, parts...)
 
 	return mainBoxStyle.Render(content)
 }
@@ -376,11 +373,13 @@ func renderProxyPanel(m Model) string {
 					nodeNameStr = colorWhite.Render(paddedName)
 				}
 
-				nodeLine := fmt.Sprintf("%s%s %s %s%s",
+				nodeLine := fmt.Sprintf("%s%s %s %s%s%s%s",
 					colorDim.Render(prefix),
 					icon,
 					nodeNameStr,
 					latencyStr,
+					renderStats(m, nodeName),
+					renderSpeed(m, nodeName),
 					currentMark,
 				)
 				lines = append(lines, nodeLine)
@@ -427,6 +426,56 @@ func renderLatency(m Model, name string) string {
 	}
 }
 
+// renderStats 渲染节点历史统计：p95 与成功率
+func renderStats(m Model, name string) string {
+	st, ok := m.Stats(name)
+	if !ok || st.Samples < 2 {
+		return ""
+	}
+	text := fmt.Sprintf("[p95 %4d %3.0f%%]", st.P95, st.SuccessRate*100)
+	switch {
+	case st.SuccessRate >= 0.95:
+		return colorDim.Render(text) + " "
+	case st.SuccessRate >= 0.7:
+		return colorYellow.Render(text) + " "
+	default:
+		return colorRed.Render(text) + " "
+	}
+}
+
+// renderSpeed 渲染吞吐测试结果
+func renderSpeed(m Model, name string) string {
+	if m.IsSpeedTesting(name) {
+		return colorDim.Render("[speed...] ")
+	}
+	r, ok := m.Speed(name)
+	if !ok {
+		return ""
+	}
+	if r.Error != "" {
+		return colorRed.Render("[speed FAIL] ")
+	}
+	speed := fmt.Sprintf("[↓%.1f ↑%.1f Mbps]", r.DownloadMbps, r.UploadMbps)
+	return colorCyan.Render(speed) + " "
+}
+
+// renderLastEvent 渲染最近的 daemon 事件
+func renderLastEvent(m Model) string {
+	ev := m.LastEvent()
+	if ev == nil {
+		return ""
+	}
+	text := fmt.Sprintf("  %s %s", ev.Time.Local().Format("15:04:05"), ev.Message)
+	switch ev.Type {
+	case "reload_failed", "error", "failover":
+		return colorRed.Render(text)
+	case "reload_start":
+		return colorYellow.Render(text)
+	default:
+		return colorDim.Render(text)
+	}
+}
+
 // renderHelpBar 帮助栏
 func renderHelpBar() string {
 	keys := []struct {
@@ -437,6 +486,7 @@ func renderHelpBar() string {
 		{"←→", "collapse/expand"},
 		{"Enter", "select"},
 		{"t", "test"},
+		{"s", "speed"},
 		{"m", "mode"},
 		{"r", "route"},
 		{"q", "quit"},
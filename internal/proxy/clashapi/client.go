@@ -44,6 +44,13 @@ func New(host string) *Client {
 	}
 }
 
+// NewWithTimeout 创建指定 HTTP 超时的客户端（用于延迟测试等耗时请求）
+func NewWithTimeout(host string, timeout time.Duration) *Client {
+	c := New(host)
+	c.httpClient.Timeout = timeout
+	return c
+}
+
 // GetProxies 获取所有代理节点信息
 // 返回一个 map，Key 是组名(如 "Proxy"), Value 是详细信息
 func (c *Client) GetProxies() (map[string]ProxyData, error) {
@@ -5,7 +5,11 @@ import (
 
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/module"
+	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
+	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
+	"github.com/kyson-dev/sing-helm/internal/proxy/history"
 	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
 	"github.com/sagernet/sing-box/option"
 )
 
@@ -23,11 +27,70 @@ func NewBuilder(opts *model.RunOptions) *Builder {
 		defaultOpts := model.DefaultRunOptions()
 		opts = &defaultOpts
 	}
+	ctx := module.NewBuildContext(opts)
+	if path := paths.Get().SettingsFile; path != "" {
+		settings, err := LoadSettings(path)
+		if err != nil {
+			logger.Error("Failed to load settings, using defaults", "error", err)
+		}
+		ctx.Settings = settings
+	}
+	ctx.Ports.Ranges = portRanges(ctx.Settings.Ports)
+	if ctx.Settings.Groups.Order == nodeProvider.OrderLatency {
+		ctx.Latency = loadLatencyMedians(paths.Get().LatencyFile)
+	}
 	return &Builder{
 		opts:    opts,
 		modules: []module.ConfigModule{},
-		ctx:     module.NewBuildContext(opts),
+		ctx:     ctx,
+	}
+}
+
+// loadLatencyMedians 读取延迟历史中每个节点成功探测的中位数
+func loadLatencyMedians(path string) map[string]int {
+	if path == "" {
+		return nil
+	}
+	store, err := history.Open(path)
+	if err != nil {
+		logger.Error("Failed to load latency history", "error", err)
+	}
+	medians := make(map[string]int)
+	for id, st := range store.All() {
+		if st.P50 > 0 {
+			medians[id] = st.P50
+		}
+	}
+	return medians
+}
+
+// portRanges 解析 settings 中的端口范围，无效的范围记录错误后忽略
+func portRanges(settings model.PortSettings) map[string]moduleUtils.PortRange {
+	ranges := make(map[string]moduleUtils.PortRange)
+	for name, value := range map[string]string{
+		moduleUtils.PortAPI:   settings.API,
+		moduleUtils.PortMixed: settings.Mixed,
+		moduleUtils.PortProbe: settings.Probe,
+	} {
+		r, err := moduleUtils.ParsePortRange(value)
+		if err != nil {
+			logger.Error("Ignoring port range from settings", "port", name, "error", err)
+			continue
+		}
+		if !r.IsZero() {
+			ranges[name] = r
+		}
+	}
+	return ranges
+}
+
+// WithPorts 使用调用方提供的端口分配器（沿用 settings 中的端口范围）
+func (b *Builder) WithPorts(ports *moduleUtils.PortAllocator) *Builder {
+	if ports != nil {
+		ports.Ranges = b.ctx.Ports.Ranges
+		b.ctx.Ports = ports
 	}
+	return b
 }
 
 // With 添加一个模块（链式调用）
@@ -36,6 +99,11 @@ func (b *Builder) With(m module.ConfigModule) *Builder {
 	return b
 }
 
+// Context 返回构建上下文，Build 之后可读取各模块回填的信息
+func (b *Builder) Context() *module.BuildContext {
+	return b.ctx
+}
+
 // Build 构建完整的 sing-box 配置
 func (b *Builder) Build() (*option.Options, error) {
 	// 1. 复制用户配置作为基础
@@ -15,20 +15,14 @@ import (
 
 // BuildConfig loads the profile, applies runtime modules, and saves raw config.
 func BuildConfig(rawPath string, runops *model.RunOptions) error {
-	builder := NewBuilder(runops)
-	for _, m := range DefaultModules(runops) {
-		builder.With(m)
-	}
-
-	opts, err := builder.Build()
+	rendered, err := Render(runops, nil)
 	if err != nil {
-		return fmt.Errorf("failed to build config: %w", err)
+		return err
 	}
-
-	if err := SaveToFile(rawPath, opts); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	if err := rendered.Save(rawPath); err != nil {
+		return err
 	}
-
+	logger.Info("Config saved", "path", rawPath)
 	return nil
 }
 
@@ -41,6 +35,36 @@ func BuildOptions(runops *model.RunOptions) (*option.Options, error) {
 	return builder.Build()
 }
 
+// InspectNodes 仅运行模板与出站模块，返回生成的节点及去重合并记录（不写入磁盘）
+func InspectNodes() ([]nodeProvider.NodeEntry, []nodeProvider.NodeMerge, error) {
+	builder := NewBuilder(nil)
+	builder.With(&module.TemplateModule{})
+	builder.With(module.NewOutboundModule(&nodeProvider.SubscriptionNodeProvider{}))
+	if _, err := builder.Build(); err != nil {
+		return nil, nil, fmt.Errorf("failed to build nodes: %w", err)
+	}
+	return builder.Context().Nodes, builder.Context().Merges, nil
+}
+
+// FindNode 按 tag、稳定 ID 或 "<source>/<name>" 查找节点，包括已禁用的节点
+func FindNode(key string) (nodeProvider.NodeEntry, bool, error) {
+	builder := NewBuilder(nil)
+	builder.With(&module.TemplateModule{})
+	builder.With(module.NewOutboundModule(&nodeProvider.SubscriptionNodeProvider{}))
+	if _, err := builder.Build(); err != nil {
+		return nodeProvider.NodeEntry{}, false, fmt.Errorf("failed to build nodes: %w", err)
+	}
+	ctx := builder.Context()
+	for _, list := range [][]nodeProvider.NodeEntry{ctx.Nodes, ctx.DisabledNodes} {
+		for _, e := range list {
+			if e.Tag == key || e.Matches(key) {
+				return e, true, nil
+			}
+		}
+	}
+	return nodeProvider.NodeEntry{}, false, nil
+}
+
 // DefaultModules 根据 RunOptions 返回默认模块组合
 func DefaultModules(opts *model.RunOptions) []module.ConfigModule {
 	if opts == nil {
@@ -95,23 +119,32 @@ func DefaultModules(opts *model.RunOptions) []module.ConfigModule {
 	return modules
 }
 
-// SaveToFile 构建配置并保存到文件
-func SaveToFile(path string, opts *option.Options) error {
+// Marshal 将配置序列化为格式化的 JSON，与 raw.json 的内容一致
+func Marshal(opts *option.Options) ([]byte, error) {
 	// 使用 sing-box 的 JSON 序列化
 	data, err := singboxjson.Marshal(opts)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
 	}
 
 	// Re-marshal for pretty print
 	var pretty map[string]any
 	if err := json.Unmarshal(data, &pretty); err != nil {
-		return fmt.Errorf("failed to unmarshal for pretty print: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal for pretty print: %w", err)
 	}
 
 	data, err = json.MarshalIndent(pretty, "", "  ")
 	if err != nil {
-		return fmt.Errorf("failed to marshal indent: %w", err)
+		return nil, fmt.Errorf("failed to marshal indent: %w", err)
+	}
+	return data, nil
+}
+
+// SaveToFile 构建配置并保存到文件
+func SaveToFile(path string, opts *option.Options) error {
+	data, err := Marshal(opts)
+	if err != nil {
+		return err
 	}
 
 	if err := os.WriteFile(path, data, 0644); err != nil {
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+// DefaultGenerationLimit is the number of applied configs kept for rollback.
+const DefaultGenerationLimit = 10
+
+// ErrGenerationNotFound is returned when a generation does not exist or was pruned.
+var ErrGenerationNotFound = errors.New("generation not found")
+
+// Generation describes a config the daemon has applied and what produced it.
+type Generation struct {
+	ID         int              `json:"id"`
+	CreatedAt  time.Time        `json:"created_at"`
+	RunOptions model.RunOptions `json:"run_options"`
+	InputHash  string           `json:"input_hash"`
+	Inputs     Inputs           `json:"inputs"`
+	Nodes      int              `json:"nodes"`
+}
+
+// Generations stores applied configs as <Dir>/<id>/{raw.json,nodes.json,meta.json}
+// and keeps only the newest Limit of them. IDs increase monotonically.
+type Generations struct {
+	Dir   string
+	Limit int
+}
+
+// DefaultGenerations returns the store under the runtime directory.
+func DefaultGenerations() Generations {
+	return Generations{Dir: paths.Get().GenerationsDir, Limit: DefaultGenerationLimit}
+}
+
+// ConfigPath returns the raw.json of generation id.
+func (g Generations) ConfigPath(id int) string {
+	return filepath.Join(g.dir(id), "raw.json")
+}
+
+func (g Generations) dir(id int) string {
+	return filepath.Join(g.Dir, strconv.Itoa(id))
+}
+
+// Add records r, built from runops, as the newest generation and prunes the oldest ones.
+func (g Generations) Add(r *Rendered, runops model.RunOptions) (Generation, error) {
+	list, err := g.List()
+	if err != nil {
+		return Generation{}, err
+	}
+	gen := Generation{
+		ID:         1,
+		CreatedAt:  time.Now(),
+		RunOptions: runops,
+		InputHash:  r.InputHash,
+		Inputs:     r.Inputs,
+		Nodes:      len(r.Nodes),
+	}
+	if len(list) > 0 {
+		gen.ID = list[0].ID + 1
+	}
+
+	// meta.json 最后写入：中断的写入没有 meta.json，不会被当作有效版本
+	dir := g.dir(gen.ID)
+	if err := os.RemoveAll(dir); err != nil {
+		return Generation{}, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Generation{}, fmt.Errorf("failed to create generation dir: %w", err)
+	}
+	if err := WritePrivateFile(filepath.Join(dir, "raw.json"), r.Data); err != nil {
+		return Generation{}, fmt.Errorf("failed to save generation: %w", err)
+	}
+	if err := SaveNodeIndex(filepath.Join(dir, "nodes.json"), r.Nodes); err != nil {
+		return Generation{}, err
+	}
+	meta, err := json.MarshalIndent(gen, "", "  ")
+	if err != nil {
+		return Generation{}, err
+	}
+	if err := WritePrivateFile(filepath.Join(dir, "meta.json"), meta); err != nil {
+		return Generation{}, fmt.Errorf("failed to save generation: %w", err)
+	}
+
+	limit := g.Limit
+	if limit <= 0 {
+		limit = DefaultGenerationLimit
+	}
+	if len(list) >= limit {
+		for _, old := range list[limit-1:] {
+			_ = os.RemoveAll(g.dir(old.ID))
+		}
+	}
+	return gen, nil
+}
+
+// List returns the stored generations, newest first.
+func (g Generations) List() ([]Generation, error) {
+	entries, err := os.ReadDir(g.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []Generation
+	for _, entry := range entries {
+		id, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		gen, err := g.Get(id)
+		if err != nil {
+			continue
+		}
+		list = append(list, gen)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	return list, nil
+}
+
+// Get returns the metadata of generation id.
+func (g Generations) Get(id int) (Generation, error) {
+	data, err := os.ReadFile(filepath.Join(g.dir(id), "meta.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Generation{}, fmt.Errorf("%w: %d", ErrGenerationNotFound, id)
+		}
+		return Generation{}, err
+	}
+	var gen Generation
+	if err := json.Unmarshal(data, &gen); err != nil {
+		return Generation{}, fmt.Errorf("invalid generation %d: %w", id, err)
+	}
+	return gen, nil
+}
+
+// Restore copies generation id back to rawPath and the node index, as Rendered.Save does.
+func (g Generations) Restore(id int, rawPath string) error {
+	data, err := os.ReadFile(g.ConfigPath(id))
+	if err != nil {
+		return fmt.Errorf("failed to read generation %d: %w", id, err)
+	}
+	if err := WritePrivateFile(rawPath, data); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if indexPath := paths.Get().NodeIndexFile; indexPath != "" {
+		index, err := os.ReadFile(filepath.Join(g.dir(id), "nodes.json"))
+		if err != nil {
+			return fmt.Errorf("failed to read generation %d: %w", id, err)
+		}
+		if err := os.WriteFile(indexPath, index, 0644); err != nil {
+			return fmt.Errorf("failed to write node index: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,10 +1,186 @@
 package model
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+)
+
+// Dedupe strategies decide when two nodes are considered the same server.
+const (
+	DedupeStrict   = "strict"   // identical outbound apart from tag/detour
+	DedupeEndpoint = "endpoint" // same type, server, port and credential
+	DedupeName     = "name"     // same display name
+)
+
+// DedupeStrategies lists the supported dedupe strategies.
+var DedupeStrategies = []string{DedupeStrict, DedupeEndpoint, DedupeName}
+
+// ParseDedupeStrategy validates a dedupe strategy, treating an empty value as strict.
+func ParseDedupeStrategy(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", DedupeStrict:
+		return DedupeStrict, nil
+	case DedupeEndpoint:
+		return DedupeEndpoint, nil
+	case DedupeName:
+		return DedupeName, nil
+	default:
+		return "", fmt.Errorf("invalid dedupe strategy: %s (expected %s)", s, strings.Join(DedupeStrategies, ", "))
+	}
+}
+
+// credentialKeys are the outbound fields that identify an account on a server.
+var credentialKeys = []string{"uuid", "password", "username", "private_key", "auth_str", "token"}
+
 // Node is a normalized outbound entry representing a proxy node in a universal format.
 type Node struct {
-	Name       string         `json:"name"`
-	Type       string         `json:"type"`
-	Source     string         `json:"source,omitempty"`
-	SkipDedupe bool           `json:"-"`
-	Outbound   map[string]any `json:"outbound"`
+	ID             string         `json:"id,omitempty"`
+	Name           string         `json:"name"`
+	Type           string         `json:"type"`
+	Source         string         `json:"source,omitempty"`
+	SkipDedupe     bool           `json:"-"`
+	DedupeStrategy string         `json:"-"` // one of DedupeStrict, DedupeEndpoint, DedupeName; empty means strict
+	Outbound       map[string]any `json:"outbound"`
+
+	// Parsed optionally carries the already decoded outbound, so providers that start from
+	// sing-box options spare the processor decoding Outbound again. It must match Outbound.
+	Parsed *option.Outbound `json:"-"`
+}
+
+// Fingerprint returns the identity of the node's connection settings: a SHA-256 digest
+// of the outbound without its tag and detour. Two nodes with the same fingerprint reach
+// the same server the same way, whatever they are called.
+func (n Node) Fingerprint() string {
+	sum := sha256.Sum256(n.identity())
+	return hex.EncodeToString(sum[:])
+}
+
+// identity returns the canonical form the fingerprint is derived from: the JSON encoding
+// of the outbound with "type" set and tag/detour removed. It is produced without copying
+// the map or going through reflection, but stays byte-identical to json.Marshal so that
+// IDs derived from it never change.
+func (n Node) identity() []byte {
+	if n.Outbound == nil {
+		return []byte(n.Name + "|" + n.Type)
+	}
+
+	keys := make([]string, 0, len(n.Outbound)+1)
+	keys = append(keys, "type")
+	for k := range n.Outbound {
+		switch k {
+		case "tag", "detour", "type":
+			continue
+		default:
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	raw, err := appendJSONObject(make([]byte, 0, 256), keys, func(k string) any {
+		if k == "type" {
+			return n.Type
+		}
+		return n.Outbound[k]
+	})
+	if err == nil {
+		return raw
+	}
+
+	// Fallback to a coarse key only if encoding unexpectedly fails.
+	if server, hasServer := n.Outbound["server"].(string); hasServer {
+		if port, hasPort := n.Outbound["server_port"]; hasPort {
+			return []byte(fmt.Sprintf("%s:%v|%s", server, port, n.Type))
+		}
+	}
+	return []byte(n.Name + "|" + n.Type)
 }
+
+// DedupeKey returns the key under which the node is deduplicated with the given strategy.
+// Keys of different strategies never collide with each other.
+func (n Node) DedupeKey(strategy string) string {
+	return n.DedupeKeyFor(strategy, "")
+}
+
+// DedupeKeyFor is DedupeKey with a precomputed fingerprint, so callers deriving keys for
+// several strategies encode the outbound only once. An empty fingerprint is computed on demand.
+func (n Node) DedupeKeyFor(strategy, fingerprint string) string {
+	switch strategy {
+	case DedupeEndpoint:
+		if n.Outbound != nil {
+			if server, ok := n.Outbound["server"].(string); ok && server != "" {
+				var b strings.Builder
+				b.WriteString("endpoint|")
+				b.WriteString(n.Type)
+				b.WriteByte('|')
+				b.WriteString(strings.ToLower(server))
+				b.WriteByte('|')
+				writeKeyValue(&b, n.Outbound["server_port"])
+				for _, k := range credentialKeys {
+					if v, ok := n.Outbound[k]; ok && v != "" {
+						b.WriteByte('|')
+						b.WriteString(k)
+						b.WriteByte('=')
+						writeKeyValue(&b, v)
+					}
+				}
+				return b.String()
+			}
+		}
+	case DedupeName:
+		if name := strings.Join(strings.Fields(strings.ToLower(n.Name)), " "); name != "" {
+			return "name|" + name
+		}
+	}
+	if fingerprint == "" {
+		fingerprint = n.Fingerprint()
+	}
+	return "strict|" + fingerprint
+}
+
+// writeKeyValue writes v as fmt's %v would, without fmt for the common types.
+func writeKeyValue(b *strings.Builder, v any) {
+	switch x := v.(type) {
+	case string:
+		b.WriteString(x)
+	case float64:
+		b.WriteString(strconv.FormatFloat(x, 'g', -1, 64))
+	case int:
+		b.WriteString(strconv.Itoa(x))
+	default:
+		fmt.Fprint(b, v)
+	}
+}
+
+// StableID returns the node's ID, deriving it from the fingerprint when unset.
+// Unlike generated tags, the ID does not depend on processing order or on the
+// node's display name, so it survives subscription refreshes and renames.
+func (n Node) StableID() string {
+	if n.ID != "" {
+		return n.ID
+	}
+	return IDFromFingerprint(n.Fingerprint())
+}
+
+// IDFromFingerprint shortens a fingerprint to a node ID.
+func IDFromFingerprint(fingerprint string) string {
+	if len(fingerprint) < idLength {
+		return fingerprint
+	}
+	return fingerprint[:idLength]
+}
+
+// QualifiedID derives an ID from the fingerprint and a qualifier, for identical nodes that
+// are deliberately kept apart and therefore cannot share the fingerprint-derived ID.
+func (n Node) QualifiedID(qualifier string) string {
+	identity := append(n.identity(), '|')
+	sum := sha256.Sum256(append(identity, qualifier...))
+	return hex.EncodeToString(sum[:idLength/2])
+}
+
+// idLength is the number of hex digits in a node ID (the first 8 bytes of the fingerprint).
+const idLength = 16
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+// legacyIdentity is how fingerprints were computed before they were streamed:
+// json.Marshal of a copy of the outbound. IDs derived from it are persisted in
+// settings and history, so the current implementation must reproduce it exactly.
+func legacyIdentity(t *testing.T, n Node) string {
+	t.Helper()
+	identity := make(map[string]any, len(n.Outbound)+1)
+	for k, v := range n.Outbound {
+		if k != "tag" && k != "detour" {
+			identity[k] = v
+		}
+	}
+	identity["type"] = n.Type
+	raw, err := json.Marshal(identity)
+	if err != nil {
+		t.Fatalf("marshal identity: %v", err)
+	}
+	return string(raw)
+}
+
+func legacyID(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+func TestNodeIDs_CompatibleWithJSONMarshal(t *testing.T) {
+	nodes := []Node{
+		{Name: "plain", Type: "vless", Outbound: map[string]any{
+			"server": "a.example.com", "server_port": float64(443), "uuid": "u", "tag": "x", "detour": "d",
+		}},
+		{Name: "escapes", Type: "trojan", Outbound: map[string]any{
+			"password":  "<&>\"\\\b\f\n\r\t\x01\x1f\x7f",
+			"sni":       "日本  🇯🇵",
+			"broken":    "a\xffb\xc3",
+			"type":      "ignored",
+			"transport": map[string]any{"type": "ws", "headers": map[string]any{"Host": "h"}, "max": nil},
+		}},
+		{Name: "numbers", Type: "hysteria2", Outbound: map[string]any{
+			"a": float64(1e21), "b": 1e-7, "c": -0.000001, "d": 123456789.125, "e": float64(0),
+			"f": 3, "g": int64(-9), "h": true, "i": false, "j": []any{"x", float64(1), nil, []any{}},
+			"k": []string{"h2", "http/1.1"}, "l": map[string]any{}, "m": float32(0.1), "n": uint16(8080),
+		}},
+		{Name: "fallback", Type: "ss", Outbound: map[string]any{"obj": struct {
+			A string `json:"a"`
+		}{A: "<"}}},
+		{Name: "no-outbound", Type: "direct"},
+	}
+
+	for _, n := range nodes {
+		var want string
+		if n.Outbound == nil {
+			want = n.Name + "|" + n.Type
+		} else {
+			want = legacyIdentity(t, n)
+		}
+		if got := string(n.identity()); got != want {
+			t.Errorf("%s: identity = %s, want %s", n.Name, got, want)
+		}
+		if got := n.StableID(); got != legacyID(want) {
+			t.Errorf("%s: StableID = %s, want %s", n.Name, got, legacyID(want))
+		}
+		if got, want := n.QualifiedID("src/name"), legacyID(want+"|src/name"); got != want {
+			t.Errorf("%s: QualifiedID = %s, want %s", n.Name, got, want)
+		}
+	}
+}
+
+func TestDedupeKeyFor_UsesGivenFingerprint(t *testing.T) {
+	n := Node{Name: "a", Type: "vless", Outbound: map[string]any{"server": "s"}}
+	fp := n.Fingerprint()
+	if got := n.DedupeKeyFor(DedupeStrict, fp); got != n.DedupeKey(DedupeStrict) {
+		t.Fatalf("strict key mismatch: %s", got)
+	}
+	other := Node{Name: "b", Type: "vless", Outbound: map[string]any{"server": "s", "tag": "b"}}
+	if n.DedupeKey(DedupeStrict) != other.DedupeKey(DedupeStrict) {
+		t.Fatal("nodes differing only by tag should share the strict key")
+	}
+}
@@ -30,6 +30,8 @@ type RunOptions struct {
 	APIPort    int       `json:"api_port"`              // Clash API 端口，0 表示自动获取
 	MixedPort  int       `json:"mixed_port,omitempty"`  // Mixed 入站端口，0 表示自动获取
 	ListenAddr string    `json:"listen_addr,omitempty"` // 监听地址
+	ProbePort  int       `json:"probe_port,omitempty"`  // 测速入站端口，0 表示自动获取
+	ProbeAuth  string    `json:"probe_auth,omitempty"`  // 测速入站的认证密码，空表示构建时随机生成
 }
 
 // DefaultRunOptions 返回默认运行参数
@@ -0,0 +1,271 @@
+package model
+
+import "time"
+
+// Settings 用户级的 daemon 行为设置（settings.json），与 sing-box 配置 profile.json 分开
+type Settings struct {
+	Failover   FailoverSettings   `json:"failover"`
+	Nodes      NodeSettings       `json:"nodes"`
+	Groups     GroupSettings      `json:"groups"`
+	Ports      PortSettings       `json:"ports"`
+	Watch      WatchSettings      `json:"watch"`
+	HTTP       HTTPSettings       `json:"http"`
+	Supervisor SupervisorSettings `json:"supervisor"`
+	Network    NetworkSettings    `json:"network"`
+}
+
+// NetworkSettings 网络变化（默认路由、网卡、地址）后的处理，默认启用
+type NetworkSettings struct {
+	Enabled  *bool  `json:"enabled,omitempty"`  // 默认 true
+	Action   string `json:"action,omitempty"`   // auto、dns、probe 或 reload
+	Debounce string `json:"debounce,omitempty"` // 最后一次变化后等待多久再处理，如 "3s"
+}
+
+// 网络变化后的处理方式
+const (
+	NetworkActionAuto   = "auto"   // TUN 模式下同 reload，其他模式同 probe
+	NetworkActionDNS    = "dns"    // 只重新设置 TUN 模式的系统 DNS 覆盖
+	NetworkActionProbe  = "probe"  // 重新设置 DNS 覆盖，并立即探测选中的节点与 sing-box
+	NetworkActionReload = "reload" // 重启 sing-box（配置没有变化也重启），重建 TUN 与 DNS 覆盖
+)
+
+// EnabledValue 返回是否启用，默认 true
+func (n NetworkSettings) EnabledValue() bool {
+	if n.Enabled == nil {
+		return true
+	}
+	return *n.Enabled
+}
+
+// ActionValue 返回 mode 下实际采用的处理方式，未设置或无效时按 auto 处理
+func (n NetworkSettings) ActionValue(mode ProxyMode) string {
+	switch n.Action {
+	case NetworkActionDNS, NetworkActionProbe, NetworkActionReload:
+		return n.Action
+	}
+	if mode == ProxyModeTUN {
+		return NetworkActionReload
+	}
+	return NetworkActionProbe
+}
+
+// DebounceValue 返回去抖间隔，未设置或无效时为 3s
+func (n NetworkSettings) DebounceValue() time.Duration {
+	return parseDurationOr(n.Debounce, 3*time.Second)
+}
+
+// SupervisorSettings sing-box 内核崩溃或失去响应时的自动重启，默认启用
+type SupervisorSettings struct {
+	Enabled       *bool  `json:"enabled,omitempty"`        // 默认 true
+	Interval      string `json:"interval,omitempty"`       // Clash API 探测间隔，如 "10s"
+	Timeout       string `json:"timeout,omitempty"`        // 单次探测超时，如 "3s"
+	Failures      int    `json:"failures,omitempty"`       // 连续探测失败多少次后视为崩溃
+	MaxBackoff    string `json:"max_backoff,omitempty"`    // 重启间隔的上限，间隔从 1s 起逐次翻倍
+	FallbackAfter int    `json:"fallback_after,omitempty"` // 连续重启失败多少次后改用最近一次运行正常的配置版本
+}
+
+// EnabledValue 返回是否启用，默认 true
+func (s SupervisorSettings) EnabledValue() bool {
+	if s.Enabled == nil {
+		return true
+	}
+	return *s.Enabled
+}
+
+// IntervalValue 返回探测间隔，未设置或无效时为 10s
+func (s SupervisorSettings) IntervalValue() time.Duration {
+	return parseDurationOr(s.Interval, 10*time.Second)
+}
+
+// TimeoutValue 返回单次探测超时，未设置或无效时为 3s
+func (s SupervisorSettings) TimeoutValue() time.Duration {
+	return parseDurationOr(s.Timeout, 3*time.Second)
+}
+
+// FailuresValue 返回视为崩溃的连续探测失败次数，默认 3
+func (s SupervisorSettings) FailuresValue() int {
+	if s.Failures <= 0 {
+		return 3
+	}
+	return s.Failures
+}
+
+// MaxBackoffValue 返回重启间隔的上限，未设置或无效时为 1m
+func (s SupervisorSettings) MaxBackoffValue() time.Duration {
+	return parseDurationOr(s.MaxBackoff, time.Minute)
+}
+
+// FallbackAfterValue 返回改用上一个正常版本之前的重启失败次数，默认 3
+func (s SupervisorSettings) FallbackAfterValue() int {
+	if s.FallbackAfter <= 0 {
+		return 3
+	}
+	return s.FallbackAfter
+}
+
+// HTTPSettings 本地 HTTP 控制接口，与 IPC 命令一一对应，只允许监听回环地址；修改后重启 daemon 生效
+type HTTPSettings struct {
+	Enabled bool   `json:"enabled"`
+	Listen  string `json:"listen,omitempty"` // 监听地址，默认 127.0.0.1:9095
+}
+
+// ListenValue 返回监听地址，未设置时为 127.0.0.1:9095
+func (h HTTPSettings) ListenValue() string {
+	if h.Listen == "" {
+		return "127.0.0.1:9095"
+	}
+	return h.Listen
+}
+
+// WatchSettings profile.json 与订阅文件变化时自动重新构建并 reload
+type WatchSettings struct {
+	Enabled  bool   `json:"enabled"`
+	Debounce string `json:"debounce,omitempty"` // 最后一次写入后等待多久再构建，如 "2s"
+}
+
+// DebounceValue 返回去抖间隔，未设置或无效时为 2s
+func (w WatchSettings) DebounceValue() time.Duration {
+	return parseDurationOr(w.Debounce, 2*time.Second)
+}
+
+// PortSettings 自动分配端口时使用的范围，如 "9090-9099"，空值表示由内核分配。
+// RunOptions 中明确指定的端口不受影响
+type PortSettings struct {
+	API   string `json:"api,omitempty"`   // Clash API
+	Mixed string `json:"mixed,omitempty"` // mixed 入站
+	Probe string `json:"probe,omitempty"` // 测速入站
+}
+
+// GroupSettings 生成的策略组（proxy、auto 及成员为空的用户组）的设置
+type GroupSettings struct {
+	// Order 组内节点排序策略：name、source、region、latency，空值保持处理顺序
+	Order string `json:"order,omitempty"`
+}
+
+// NodeSettings 按稳定节点 ID 记录的节点偏好，在生成出站时应用
+type NodeSettings struct {
+	Disabled  []string `json:"disabled,omitempty"`  // 不生成出站的节点
+	Favorites []string `json:"favorites,omitempty"` // 在 proxy selector 中排在最前的节点
+}
+
+// IsDisabled 节点是否被禁用
+func (n NodeSettings) IsDisabled(id string) bool {
+	return containsString(n.Disabled, id)
+}
+
+// IsFavorite 节点是否被收藏
+func (n NodeSettings) IsFavorite(id string) bool {
+	return containsString(n.Favorites, id)
+}
+
+// SetDisabled 设置节点禁用状态，返回是否有变化
+func (n *NodeSettings) SetDisabled(id string, disabled bool) bool {
+	return setString(&n.Disabled, id, disabled)
+}
+
+// SetFavorite 设置节点收藏状态，返回是否有变化
+func (n *NodeSettings) SetFavorite(id string, favorite bool) bool {
+	return setString(&n.Favorites, id, favorite)
+}
+
+// FailoverSettings 手动选择的节点失效时的自动故障转移
+type FailoverSettings struct {
+	Enabled    bool   `json:"enabled"`
+	Interval   string `json:"interval,omitempty"`    // 探测间隔，如 "30s"
+	Failures   int    `json:"failures,omitempty"`    // 连续失败多少次后切换
+	Fallback   string `json:"fallback,omitempty"`    // fastest（组内最快的健康节点）或 auto
+	SwitchBack *bool  `json:"switch_back,omitempty"` // 原节点恢复后是否切回，默认 true
+	URL        string `json:"url,omitempty"`         // 探测地址
+	Timeout    string `json:"timeout,omitempty"`     // 单次探测超时，如 "3s"
+}
+
+// Failover fallback 策略
+const (
+	FallbackFastest = "fastest"
+	FallbackAuto    = "auto"
+)
+
+// DefaultSettings 返回默认设置
+func DefaultSettings() Settings {
+	return Settings{}
+}
+
+// IntervalValue 返回探测间隔，未设置或无效时为 30s
+func (f FailoverSettings) IntervalValue() time.Duration {
+	return parseDurationOr(f.Interval, 30*time.Second)
+}
+
+// TimeoutValue 返回单次探测超时，未设置或无效时为 3s
+func (f FailoverSettings) TimeoutValue() time.Duration {
+	return parseDurationOr(f.Timeout, 3*time.Second)
+}
+
+// FailuresValue 返回触发切换的连续失败次数，默认 3
+func (f FailoverSettings) FailuresValue() int {
+	if f.Failures <= 0 {
+		return 3
+	}
+	return f.Failures
+}
+
+// FallbackValue 返回 fallback 策略，默认 fastest
+func (f FailoverSettings) FallbackValue() string {
+	if f.Fallback == FallbackAuto {
+		return FallbackAuto
+	}
+	return FallbackFastest
+}
+
+// SwitchBackValue 返回原节点恢复后是否切回，默认 true
+func (f FailoverSettings) SwitchBackValue() bool {
+	if f.SwitchBack == nil {
+		return true
+	}
+	return *f.SwitchBack
+}
+
+// URLValue 返回探测地址
+func (f FailoverSettings) URLValue() string {
+	if f.URL == "" {
+		return "http://www.gstatic.com/generate_204"
+	}
+	return f.URL
+}
+
+func parseDurationOr(s string, def time.Duration) time.Duration {
+	if s == "" {
+		return def
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func setString(list *[]string, s string, present bool) bool {
+	if containsString(*list, s) == present {
+		return false
+	}
+	if present {
+		*list = append(*list, s)
+		return true
+	}
+	out := (*list)[:0]
+	for _, v := range *list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	*list = out
+	return true
+}
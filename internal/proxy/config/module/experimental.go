@@ -43,13 +43,9 @@ func (m *ExperimentalModule) Apply(opts *option.Options, ctx *BuildContext) erro
 	}
 
 	// 确定 API 端口
-	apiPort := m.APIPort
-	if apiPort == 0 {
-		var err error
-		apiPort, err = moduleUtils.GetFreePort()
-		if err != nil {
-			return err
-		}
+	apiPort, err := ctx.AllocatePort(moduleUtils.PortAPI, listenAddr, m.APIPort)
+	if err != nil {
+		return err
 	}
 
 	// 更新 context 中的端口信息
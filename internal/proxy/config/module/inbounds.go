@@ -46,13 +46,9 @@ func (m *MixedModule) Apply(opts *option.Options, ctx *BuildContext) error {
 	if resolvedListenAddr == "" {
 		resolvedListenAddr = "127.0.0.1"
 	}
-	resolvedPort := m.Port
-	if resolvedPort == 0 {
-		var err error
-		resolvedPort, err = moduleUtils.GetFreePort()
-		if err != nil {
-			return err
-		}
+	resolvedPort, err := ctx.AllocatePort(moduleUtils.PortMixed, resolvedListenAddr, m.Port)
+	if err != nil {
+		return err
 	}
 
 	// 更新 context 中的端口信息
@@ -20,6 +20,7 @@ func TestMixedApply_RemovesTunAndCreatesMixed(t *testing.T) {
 
 	run := &model.RunOptions{ProxyMode: model.ProxyModeTUN}
 	ctx := NewBuildContext(run)
+	ctx.Ports.SetProbe(func(string, int) error { return nil }) // 与本机端口占用无关
 	mod := &MixedModule{SetSystemProxy: true, ListenAddr: "127.0.0.1", Port: 7890}
 	if err := mod.Apply(opts, ctx); err != nil {
 		t.Fatalf("apply mixed: %v", err)
@@ -0,0 +1,169 @@
+package node
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Ordering policies for the members of generated groups.
+const (
+	OrderDefault = ""        // processing order: user nodes first, then sources by priority
+	OrderName    = "name"    // natural sort by tag ("HK 2" before "HK 10")
+	OrderSource  = "source"  // by source priority, natural sort within a source
+	OrderRegion  = "region"  // by detected region, natural sort within a region
+	OrderLatency = "latency" // by last known median latency, untested nodes last
+)
+
+// OrderPolicies lists the accepted ordering policies.
+var OrderPolicies = []string{OrderName, OrderSource, OrderRegion, OrderLatency}
+
+// ValidOrderPolicy reports whether policy is known. The empty policy keeps processing order.
+func ValidOrderPolicy(policy string) bool {
+	if policy == OrderDefault {
+		return true
+	}
+	for _, p := range OrderPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderTags sorts tags according to policy. entries supply source and ID for each tag;
+// latency maps node IDs to their median latency in milliseconds. The sort is stable and
+// unknown policies leave the order untouched.
+func OrderTags(tags []string, entries []NodeEntry, policy string, latency map[string]int) []string {
+	ordered := append([]string(nil), tags...)
+	if policy == OrderDefault || !ValidOrderPolicy(policy) {
+		return ordered
+	}
+
+	byTag := make(map[string]NodeEntry, len(entries))
+	sourceRank := make(map[string]int)
+	for _, e := range entries {
+		byTag[e.Tag] = e
+		if _, ok := sourceRank[e.Source]; !ok {
+			sourceRank[e.Source] = len(sourceRank)
+		}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		switch policy {
+		case OrderSource:
+			ra, rb := sourceRank[byTag[a].Source], sourceRank[byTag[b].Source]
+			if ra != rb {
+				return ra < rb
+			}
+		case OrderRegion:
+			ga, gb := DetectRegion(a), DetectRegion(b)
+			if ga != gb {
+				// Nodes without a recognizable region go last.
+				if ga == "" || gb == "" {
+					return gb == ""
+				}
+				return ga < gb
+			}
+		case OrderLatency:
+			la, oka := latency[byTag[a].ID]
+			lb, okb := latency[byTag[b].ID]
+			if oka != okb {
+				return oka
+			}
+			if oka && la != lb {
+				return la < lb
+			}
+		}
+		return NaturalLess(a, b)
+	})
+	return ordered
+}
+
+// NaturalLess compares strings case-insensitively, treating digit runs as numbers.
+func NaturalLess(a, b string) bool {
+	ra, rb := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if unicode.IsDigit(ra[i]) && unicode.IsDigit(rb[j]) {
+			si := i
+			for i < len(ra) && unicode.IsDigit(ra[i]) {
+				i++
+			}
+			sj := j
+			for j < len(rb) && unicode.IsDigit(rb[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ra[si:i]), "0")
+			nb := strings.TrimLeft(string(rb[sj:j]), "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			continue
+		}
+		if ra[i] != rb[j] {
+			return ra[i] < rb[j]
+		}
+		i++
+		j++
+	}
+	if len(ra)-i != len(rb)-j {
+		return len(ra)-i < len(rb)-j
+	}
+	return a < b
+}
+
+// regionKeywords maps lowercase name fragments to ISO region codes. Short codes are
+// matched as whole words only so "US" does not match inside "Russia".
+var regionKeywords = []struct {
+	code  string
+	words []string
+	names []string
+}{
+	{"HK", []string{"hk"}, []string{"hong kong", "hongkong", "香港", "🇭🇰"}},
+	{"TW", []string{"tw"}, []string{"taiwan", "台湾", "台灣", "🇹🇼"}},
+	{"JP", []string{"jp"}, []string{"japan", "tokyo", "osaka", "日本", "东京", "大阪", "🇯🇵"}},
+	{"KR", []string{"kr"}, []string{"korea", "seoul", "韩国", "首尔", "🇰🇷"}},
+	{"SG", []string{"sg"}, []string{"singapore", "新加坡", "狮城", "🇸🇬"}},
+	{"US", []string{"us", "usa"}, []string{"united states", "america", "los angeles", "san jose", "美国", "🇺🇸"}},
+	{"GB", []string{"uk", "gb"}, []string{"united kingdom", "london", "英国", "🇬🇧"}},
+	{"DE", []string{"de"}, []string{"germany", "frankfurt", "德国", "🇩🇪"}},
+	{"FR", []string{"fr"}, []string{"france", "paris", "法国", "🇫🇷"}},
+	{"NL", []string{"nl"}, []string{"netherlands", "amsterdam", "荷兰", "🇳🇱"}},
+	{"CA", []string{"ca"}, []string{"canada", "加拿大", "🇨🇦"}},
+	{"AU", []string{"au"}, []string{"australia", "sydney", "澳大利亚", "澳洲", "🇦🇺"}},
+	{"IN", nil, []string{"india", "印度", "🇮🇳"}},
+	{"RU", []string{"ru"}, []string{"russia", "moscow", "俄罗斯", "🇷🇺"}},
+	{"TR", []string{"tr"}, []string{"turkey", "türkiye", "土耳其", "🇹🇷"}},
+}
+
+// DetectRegion guesses the region code of a node from its name, or "" when unknown.
+func DetectRegion(name string) string {
+	lower := strings.ToLower(name)
+	for _, r := range regionKeywords {
+		for _, n := range r.names {
+			if strings.Contains(lower, n) {
+				return r.code
+			}
+		}
+	}
+	// Digits stay attached so "5GB" is not read as Great Britain, while "HK01" still matches.
+	words := strings.FieldsFunc(lower, func(c rune) bool {
+		return c > unicode.MaxASCII || !(unicode.IsLetter(c) || unicode.IsDigit(c))
+	})
+	for _, w := range words {
+		w = strings.TrimRightFunc(w, unicode.IsDigit)
+		for _, r := range regionKeywords {
+			for _, code := range r.words {
+				if w == code {
+					return r.code
+				}
+			}
+		}
+	}
+	return ""
+}
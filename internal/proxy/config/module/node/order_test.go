@@ -0,0 +1,66 @@
+package node
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestNaturalLess(t *testing.T) {
+	tags := []string{"HK 10", "hk 2", "HK 1", "JP 01", "HK", "JP 1a"}
+	sort.SliceStable(tags, func(i, j int) bool { return NaturalLess(tags[i], tags[j]) })
+	want := []string{"HK", "HK 1", "hk 2", "HK 10", "JP 01", "JP 1a"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Fatalf("natural sort = %v, want %v", tags, want)
+	}
+}
+
+func TestDetectRegion(t *testing.T) {
+	cases := map[string]string{
+		"🇭🇰 Hong Kong 01":   "HK",
+		"香港 IPLC":           "HK",
+		"JP-Tokyo-02":       "JP",
+		"US 03 | 1x":        "US",
+		"SG01":              "SG",
+		"Russia Moscow":     "RU",
+		"Premium node":      "",
+		"Bonus traffic 5GB": "",
+	}
+	for name, want := range cases {
+		if got := DetectRegion(name); got != want {
+			t.Errorf("DetectRegion(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestOrderTags(t *testing.T) {
+	entries := []NodeEntry{
+		{ID: "a", Tag: "US 2", Source: "sub-b"},
+		{ID: "b", Tag: "HK 10", Source: "sub-a"},
+		{ID: "c", Tag: "Relay", Source: "sub-a"},
+		{ID: "d", Tag: "HK 2", Source: "sub-b"},
+	}
+	tags := []string{"US 2", "HK 10", "Relay", "HK 2"}
+	latency := map[string]int{"a": 40, "b": 300, "d": 120}
+
+	cases := []struct {
+		policy string
+		want   []string
+	}{
+		{OrderDefault, []string{"US 2", "HK 10", "Relay", "HK 2"}},
+		{OrderName, []string{"HK 2", "HK 10", "Relay", "US 2"}},
+		{OrderSource, []string{"HK 2", "US 2", "HK 10", "Relay"}},
+		{OrderRegion, []string{"HK 2", "HK 10", "US 2", "Relay"}},
+		{OrderLatency, []string{"US 2", "HK 2", "HK 10", "Relay"}},
+		{"bogus", []string{"US 2", "HK 10", "Relay", "HK 2"}},
+	}
+	for _, tc := range cases {
+		got := OrderTags(tags, entries, tc.policy, latency)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("OrderTags(%q) = %v, want %v", tc.policy, got, tc.want)
+		}
+	}
+	if !reflect.DeepEqual(tags, []string{"US 2", "HK 10", "Relay", "HK 2"}) {
+		t.Fatalf("OrderTags modified its input: %v", tags)
+	}
+}
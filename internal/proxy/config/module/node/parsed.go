@@ -0,0 +1,75 @@
+package node
+
+import (
+	"sync"
+
+	"github.com/sagernet/sing-box/option"
+)
+
+// parsedKey identifies a decoded outbound: the fingerprint covers every field except
+// tag and detour, which are part of the key themselves.
+type parsedKey struct {
+	nodeType    string
+	fingerprint string
+	tag         string
+	detour      string
+}
+
+// parsedCache keeps decoded outbounds between builds, so rebuilding a config (mode
+// switches, reloads) does not decode tens of thousands of unchanged nodes again.
+// Entries live in two generations: every new processor starts a generation, and
+// entries not used during the last two are dropped. Cached outbounds are shared and
+// must be treated as read-only.
+type parsedCache struct {
+	mu       sync.Mutex
+	current  map[parsedKey]option.Outbound
+	previous map[parsedKey]option.Outbound
+}
+
+var parsedOutbounds = &parsedCache{}
+
+func (c *parsedCache) get(key parsedKey) (option.Outbound, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if out, ok := c.current[key]; ok {
+		return out, true
+	}
+	out, ok := c.previous[key]
+	if ok {
+		c.putLocked(key, out)
+	}
+	return out, ok
+}
+
+func (c *parsedCache) put(key parsedKey, out option.Outbound) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.putLocked(key, out)
+}
+
+func (c *parsedCache) putLocked(key parsedKey, out option.Outbound) {
+	if c.current == nil {
+		c.current = make(map[parsedKey]option.Outbound)
+	}
+	c.current[key] = out
+}
+
+// rotate starts a new generation. It is a no-op while the current one is empty, so
+// back-to-back processors (e.g. an inspection right after a build) keep the cache warm.
+func (c *parsedCache) rotate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.current) == 0 {
+		return
+	}
+	c.previous = c.current
+	c.current = make(map[parsedKey]option.Outbound, len(c.previous))
+}
+
+// parsedDetour returns the detour set in a decoded outbound's dialer options.
+func parsedDetour(out *option.Outbound) string {
+	if wrapper, ok := out.Options.(option.DialerOptionsWrapper); ok {
+		return wrapper.TakeDialerOptions().Detour
+	}
+	return ""
+}
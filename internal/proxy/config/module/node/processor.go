@@ -1,8 +1,6 @@
 package node
 
 import (
-	"encoding/json"
-	"fmt"
 	"strings"
 
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
@@ -10,30 +8,77 @@ import (
 	"github.com/sagernet/sing-box/option"
 )
 
+// NodeEntry describes one generated outbound and the original node(s) it was built from.
+type NodeEntry struct {
+	ID      string   `json:"id"`
+	Tag     string   `json:"tag"`
+	Type    string   `json:"type"`
+	Source  string   `json:"source"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"` // "<source>/<name>" of duplicates merged into this tag
+}
+
+// Key returns the identity used to follow a node across rebuilds, independent of
+// the generated tag (which may gain " (source)" or " #N" suffixes over time).
+// It is the stable node ID when known, otherwise "<source>/<name>".
+func (e NodeEntry) Key() string {
+	if e.ID != "" {
+		return e.ID
+	}
+	return e.Source + "/" + e.Name
+}
+
+// Matches reports whether key identifies this entry or one of its aliases.
+func (e NodeEntry) Matches(key string) bool {
+	if key == e.ID || key == e.Source+"/"+e.Name {
+		return true
+	}
+	for _, alias := range e.Aliases {
+		if alias == key {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeMerge records a node that was deduplicated into an existing canonical tag.
+type NodeMerge struct {
+	Tag      string `json:"tag"`      // canonical tag the node was merged into
+	Source   string `json:"source"`   // source of the merged node
+	Name     string `json:"name"`     // original name of the merged node
+	Strategy string `json:"strategy"` // dedupe strategy that matched
+}
+
 // OutboundProcessor processes raw outbounds, manages tags, and prevents duplication globally.
 type OutboundProcessor struct {
 	usedTags       map[string]bool
 	originalToTag  map[string]map[string]string // source -> original name -> unique tag
 	processedNodes []option.Outbound
 	actualTags     []string // purely the tags of actual nodes (vless, trojan, etc.)
+	entries        []NodeEntry
+	tagToEntry     map[string]int  // unique tag -> index in entries
+	usedIDs        map[string]bool // stable IDs already assigned to entries
 
 	// sourceGroups maps source names (or 'user') to their nodes' tags. Useful for grouping.
 	sourceGroups map[string][]string
 
-	// globalFingerprints prevents identical nodes (same IP:Port+Type) across all sources.
-	globalFingerprints map[string]bool
-	fingerprintToTag   map[string]string
-	globalNameToTag    map[string]string // original name -> unique tag (only when globally unambiguous)
-	ambiguousNames     map[string]bool   // original names that map to multiple unique tags
+	// dedupeKeys prevents duplicate nodes across all sources. Every kept node registers its
+	// key for each strategy, so an incoming node is matched using its own source's strategy.
+	dedupeKeys      map[string]string // dedupe key -> canonical tag
+	merges          []NodeMerge
+	globalNameToTag map[string]string // original name -> unique tag (only when globally unambiguous)
+	ambiguousNames  map[string]bool   // original names that map to multiple unique tags
 }
 
 func NewOutboundProcessor() *OutboundProcessor {
+	parsedOutbounds.rotate()
 	return &OutboundProcessor{
 		usedTags:           make(map[string]bool),
 		originalToTag:      make(map[string]map[string]string),
+		tagToEntry:         make(map[string]int),
+		usedIDs:            make(map[string]bool),
 		sourceGroups:       make(map[string][]string),
-		globalFingerprints: make(map[string]bool),
-		fingerprintToTag:   make(map[string]string),
+		dedupeKeys:         make(map[string]string),
 		globalNameToTag:    make(map[string]string),
 		ambiguousNames:     make(map[string]bool),
 	}
@@ -48,31 +93,56 @@ func (p *OutboundProcessor) AddNodes(nodes []model.Node) {
 		}
 
 		// 1. Global deduplication
-		var fp string
+		fp := n.Fingerprint()
+		id := n.ID
+		if id == "" {
+			id = model.IDFromFingerprint(fp)
+		}
+		strategy := n.DedupeStrategy
+		if strategy == "" {
+			strategy = model.DedupeStrict
+		}
 		if !n.SkipDedupe {
-			fp = p.fingerprint(n)
-			if p.globalFingerprints[fp] {
+			if canonicalTag, ok := p.dedupeKeys[n.DedupeKeyFor(strategy, fp)]; ok {
 				// Keep duplicate-name mapping to canonical tag so detour references remain valid.
-				if canonicalTag, ok := p.fingerprintToTag[fp]; ok {
-					p.recordMapping(source, n.Name, canonicalTag)
-				}
+				p.recordMapping(source, n.Name, canonicalTag)
+				p.recordAlias(canonicalTag, source, n.Name)
+				p.merges = append(p.merges, NodeMerge{Tag: canonicalTag, Source: source, Name: n.Name, Strategy: strategy})
 				continue
 			}
-			p.globalFingerprints[fp] = true
 		}
 
+		// Identical nodes kept apart (dedupe disabled) still need distinct IDs.
+		if p.usedIDs[id] {
+			id = n.QualifiedID(source + "/" + n.Name)
+		}
+		p.usedIDs[id] = true
+
 		// Ensure uniqueness of tag
 		uniqueTag := MakeUniqueOutboundTag(n.Name, source, p.usedTags)
 		p.recordMapping(source, n.Name, uniqueTag)
 		if !n.SkipDedupe {
-			p.fingerprintToTag[fp] = uniqueTag
+			for _, st := range model.DedupeStrategies {
+				key := n.DedupeKeyFor(st, fp)
+				if _, exists := p.dedupeKeys[key]; !exists {
+					p.dedupeKeys[key] = uniqueTag
+				}
+			}
 		}
 
 		// Create the option.Outbound structure
-		outbound := p.mapToOutbound(n.Type, uniqueTag, n.Outbound)
+		outbound := p.mapToOutbound(n, fp, uniqueTag)
 
 		p.processedNodes = append(p.processedNodes, outbound)
 		p.actualTags = append(p.actualTags, uniqueTag)
+		p.tagToEntry[uniqueTag] = len(p.entries)
+		p.entries = append(p.entries, NodeEntry{
+			ID:     id,
+			Tag:    uniqueTag,
+			Type:   n.Type,
+			Source: source,
+			Name:   n.Name,
+		})
 		p.sourceGroups[source] = append(p.sourceGroups[source], uniqueTag)
 	}
 }
@@ -87,6 +157,16 @@ func (p *OutboundProcessor) GetActualTags() []string {
 	return p.actualTags
 }
 
+// GetEntries returns the origin of every generated node, in processing order
+func (p *OutboundProcessor) GetEntries() []NodeEntry {
+	return p.entries
+}
+
+// GetMerges returns the nodes that were deduplicated into an existing tag, in processing order
+func (p *OutboundProcessor) GetMerges() []NodeMerge {
+	return p.merges
+}
+
 // GetGroups returns tags grouped by their source origin
 func (p *OutboundProcessor) GetGroups() map[string][]string {
 	return p.sourceGroups
@@ -94,36 +174,6 @@ func (p *OutboundProcessor) GetGroups() map[string][]string {
 
 // --- Internal helpers ---
 
-func (p *OutboundProcessor) fingerprint(n model.Node) string {
-	if n.Outbound == nil {
-		return n.Name + "|" + n.Type
-	}
-
-	identity := make(map[string]any, len(n.Outbound)+1)
-	identity["type"] = n.Type
-	for k, v := range n.Outbound {
-		switch k {
-		case "tag", "detour":
-			continue
-		default:
-			identity[k] = v
-		}
-	}
-
-	raw, err := json.Marshal(identity)
-	if err == nil {
-		return string(raw)
-	}
-
-	// Fallback to a coarse key only if marshal unexpectedly fails.
-	if server, hasServer := n.Outbound["server"].(string); hasServer {
-		if port, hasPort := n.Outbound["server_port"]; hasPort {
-			return fmt.Sprintf("%s:%v|%s", server, port, n.Type)
-		}
-	}
-	return n.Name + "|" + n.Type
-}
-
 func (p *OutboundProcessor) recordMapping(source, original, unique string) {
 	if p.originalToTag[source] == nil {
 		p.originalToTag[source] = make(map[string]string)
@@ -142,26 +192,54 @@ func (p *OutboundProcessor) recordMapping(source, original, unique string) {
 	p.globalNameToTag[original] = unique
 }
 
-func (p *OutboundProcessor) mapToOutbound(outType, tag string, raw map[string]any) option.Outbound {
-	var outbound option.Outbound
-
-	// Ensure tag matches our uniqueness guarantee
-	rawCopy := make(map[string]any, len(raw))
-	for k, v := range raw {
-		rawCopy[k] = v
+func (p *OutboundProcessor) recordAlias(tag, source, original string) {
+	idx, ok := p.tagToEntry[tag]
+	if !ok {
+		return
 	}
-	rawCopy["tag"] = tag
-	rawCopy["type"] = outType
+	entry := &p.entries[idx]
+	key := source + "/" + original
+	if entry.Matches(key) {
+		return
+	}
+	entry.Aliases = append(entry.Aliases, key)
+}
 
+func (p *OutboundProcessor) mapToOutbound(n model.Node, fingerprint, tag string) option.Outbound {
 	// Handle internal detour logic if it references other nodes
 	// e.g. wireguard nodes detour via another proxy
-	if detour, ok := rawCopy["detour"].(string); ok && detour != "" {
+	detour, _ := n.Outbound["detour"].(string)
+	if detour != "" {
 		if mapped, found := p.resolveDetour(detour); found {
-			rawCopy["detour"] = mapped
+			detour = mapped
 		}
 	}
 
-	moduleUtils.ApplyMapToOutbound(&outbound, rawCopy)
+	// Nodes handed over already decoded only need a new decode if their tag or detour moved.
+	if n.Parsed != nil && n.Parsed.Tag == tag && parsedDetour(n.Parsed) == detour {
+		return *n.Parsed
+	}
+
+	key := parsedKey{nodeType: n.Type, fingerprint: fingerprint, tag: tag, detour: detour}
+	if outbound, ok := parsedOutbounds.get(key); ok {
+		return outbound
+	}
+
+	// Ensure tag matches our uniqueness guarantee
+	rawCopy := make(map[string]any, len(n.Outbound)+2)
+	for k, v := range n.Outbound {
+		rawCopy[k] = v
+	}
+	rawCopy["tag"] = tag
+	rawCopy["type"] = n.Type
+	if detour != "" {
+		rawCopy["detour"] = detour
+	}
+
+	var outbound option.Outbound
+	if err := moduleUtils.ApplyMapToOutbound(&outbound, rawCopy); err == nil {
+		parsedOutbounds.put(key, outbound)
+	}
 	return outbound
 }
 
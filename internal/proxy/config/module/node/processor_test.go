@@ -123,3 +123,163 @@ func TestFingerprint_DifferentCredentialsAreNotDeduped(t *testing.T) {
 		t.Fatalf("expected 2 outbounds when credentials differ, got %d", len(outbounds))
 	}
 }
+
+func TestAddNodes_StableIDSurvivesTagChanges(t *testing.T) {
+	hk := model.Node{
+		Name:   "HK 01",
+		Source: "sub2",
+		Type:   "trojan",
+		Outbound: map[string]any{
+			"server":      "hk.example.com",
+			"server_port": 443,
+			"password":    "p",
+		},
+	}
+	other := model.Node{
+		Name:   "HK 01",
+		Source: "sub1",
+		Type:   "trojan",
+		Outbound: map[string]any{
+			"server":      "other.example.com",
+			"server_port": 443,
+			"password":    "p",
+		},
+	}
+
+	alone := NewOutboundProcessor()
+	alone.AddNodes([]model.Node{hk})
+	shifted := NewOutboundProcessor()
+	shifted.AddNodes([]model.Node{other, hk})
+
+	before := alone.GetEntries()[0]
+	after := shifted.GetEntries()[1]
+	if before.Tag == after.Tag {
+		t.Fatalf("expected generated tag to change, both are %q", before.Tag)
+	}
+	if before.ID == "" || before.ID != after.ID {
+		t.Fatalf("expected stable ID across tag changes, got %q and %q", before.ID, after.ID)
+	}
+	if after.ID == shifted.GetEntries()[0].ID {
+		t.Fatalf("expected different nodes to have different IDs")
+	}
+}
+
+func TestAddNodes_SkipDedupeKeepsIDsUnique(t *testing.T) {
+	n := model.Node{
+		Name:       "same",
+		Type:       "vless",
+		SkipDedupe: true,
+		Outbound: map[string]any{
+			"server":      "1.1.1.1",
+			"server_port": 443,
+			"uuid":        "u-1",
+		},
+	}
+	a, b := n, n
+	a.Source, b.Source = "alpha", "beta"
+
+	p := NewOutboundProcessor()
+	p.AddNodes([]model.Node{a, b})
+	entries := p.GetEntries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries without dedupe, got %d", len(entries))
+	}
+	if entries[0].ID == entries[1].ID {
+		t.Fatalf("expected distinct IDs, both are %q", entries[0].ID)
+	}
+}
+
+func TestAddNodes_DedupeStrategies(t *testing.T) {
+	base := map[string]any{
+		"server":      "Edge.Example.com",
+		"server_port": 443,
+		"uuid":        "u-1",
+		"tls":         map[string]any{"enabled": true, "server_name": "a.example.com"},
+	}
+	variant := map[string]any{
+		"server":      "edge.example.com",
+		"server_port": 443,
+		"uuid":        "u-1",
+		"tls":         map[string]any{"enabled": true, "server_name": "b.example.com", "alpn": []any{"h2"}},
+	}
+	elsewhere := map[string]any{
+		"server":      "other.example.com",
+		"server_port": 443,
+		"uuid":        "u-2",
+	}
+
+	cases := []struct {
+		strategy string
+		second   model.Node
+		merged   bool
+	}{
+		{model.DedupeStrict, model.Node{Name: "HK", Outbound: variant}, false},
+		{model.DedupeEndpoint, model.Node{Name: "HK", Outbound: variant}, true},
+		{model.DedupeEndpoint, model.Node{Name: "HK", Outbound: elsewhere}, false},
+		{model.DedupeName, model.Node{Name: " hk  01 ", Outbound: elsewhere}, true},
+		{model.DedupeName, model.Node{Name: "JP 01", Outbound: elsewhere}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.strategy+"/"+tc.second.Name, func(t *testing.T) {
+			second := tc.second
+			second.Source = "s2"
+			second.Type = "vless"
+			second.DedupeStrategy = tc.strategy
+
+			p := NewOutboundProcessor()
+			p.AddNodes([]model.Node{
+				{Name: "HK 01", Source: "s1", Type: "vless", Outbound: base},
+				second,
+			})
+
+			merges := p.GetMerges()
+			if tc.merged {
+				if len(p.GetProcessedOutbounds()) != 1 || len(merges) != 1 {
+					t.Fatalf("expected node to be merged, got %d outbounds and %d merges", len(p.GetProcessedOutbounds()), len(merges))
+				}
+				if merges[0].Tag != "HK 01" || merges[0].Source != "s2" || merges[0].Strategy != tc.strategy {
+					t.Fatalf("unexpected merge record: %+v", merges[0])
+				}
+				return
+			}
+			if len(p.GetProcessedOutbounds()) != 2 || len(merges) != 0 {
+				t.Fatalf("expected nodes to be kept apart, got %d outbounds and %d merges", len(p.GetProcessedOutbounds()), len(merges))
+			}
+		})
+	}
+}
+
+func TestAddNodes_ReusesParsedOutboundsAcrossBuilds(t *testing.T) {
+	nodes := func(detour string) []model.Node {
+		return []model.Node{
+			{Name: "hop", Source: "s1", Type: "vless", Outbound: map[string]any{
+				"server": "1.1.1.1", "server_port": 443, "uuid": "u-1",
+			}},
+			{Name: "chained", Source: "s1", Type: "vless", Outbound: map[string]any{
+				"server": "2.2.2.2", "server_port": 443, "uuid": "u-2", "detour": detour,
+			}},
+		}
+	}
+
+	first := NewOutboundProcessor()
+	first.AddNodes(nodes("hop"))
+	second := NewOutboundProcessor()
+	second.AddNodes(nodes("hop"))
+
+	a, b := first.GetProcessedOutbounds(), second.GetProcessedOutbounds()
+	if len(a) != 2 || len(b) != 2 {
+		t.Fatalf("expected 2 outbounds, got %d and %d", len(a), len(b))
+	}
+	for i := range a {
+		if a[i].Options != b[i].Options {
+			t.Fatalf("outbound %s was decoded again instead of reused", a[i].Tag)
+		}
+	}
+
+	// A different detour must not be served from the cache.
+	third := NewOutboundProcessor()
+	third.AddNodes(nodes("direct"))
+	if got := parsedDetour(&third.GetProcessedOutbounds()[1]); got != "direct" {
+		t.Fatalf("expected detour direct, got %q", got)
+	}
+}
@@ -1,6 +1,8 @@
 package node
 
 import (
+	"fmt"
+
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/subscription"
 	"github.com/kyson-dev/sing-helm/internal/sys/logger"
@@ -27,22 +29,42 @@ func (p *SubscriptionNodeProvider) GetNodes() ([]model.Node, error) {
 		return nil, nil // Return empty list instead of failing the whole build
 	}
 
-	var nodes []model.Node
+	// Per-source overrides must be valid; a typo should fail the build, not vanish.
+	overrides := make(map[string]map[string]any)
+	for _, s := range sources {
+		if len(s.Override) == 0 {
+			continue
+		}
+		if err := subscription.ValidateOverride(s.Override); err != nil {
+			return nil, fmt.Errorf("invalid override for subscription %q: %w", s.Name, err)
+		}
+		overrides[s.Name] = s.Override
+	}
+
+	nodes := make([]model.Node, 0, len(subNodes))
 	for _, n := range subNodes {
 		if n.Outbound == nil || n.Source == "" {
 			continue
 		}
 
-		outboundCopy := make(map[string]any, len(n.Outbound))
-		for k, v := range n.Outbound {
-			outboundCopy[k] = v
+		// Cached maps are shared between builds and never modified downstream, so they
+		// are only copied when an override has to be merged in.
+		outbound := n.Outbound
+		if override, ok := overrides[n.Source]; ok {
+			outbound = subscription.MergeOverride(n.Outbound, override)
+			if err := subscription.ValidateOutbound(n.Type, outbound); err != nil {
+				return nil, fmt.Errorf("invalid override for subscription %q (%s node %q): %w", n.Source, n.Type, n.Name, err)
+			}
 		}
 
 		nodes = append(nodes, model.Node{
-			Name:     n.Name,
-			Type:     n.Type,
-			Source:   n.Source, // Provide the sub source name
-			Outbound: outboundCopy,
+			ID:             n.ID,
+			Name:           n.Name,
+			Type:           n.Type,
+			Source:         n.Source, // Provide the sub source name
+			SkipDedupe:     n.SkipDedupe,
+			DedupeStrategy: n.DedupeStrategy,
+			Outbound:       outbound,
 		})
 	}
 
@@ -0,0 +1,83 @@
+package node
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/subscription"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+func setupSubscription(t *testing.T, override map[string]any) {
+	t.Helper()
+	paths.ResetForTest()
+	dir := t.TempDir()
+	paths.ForTestSetRuntimeDir(filepath.Join(dir, "run"))
+	t.Cleanup(paths.ForTestResetRuntimeDir)
+	if err := paths.ForTestInit(dir); err != nil {
+		t.Fatalf("paths init: %v", err)
+	}
+
+	p := paths.Get()
+	source := subscription.Source{Name: "sub", URL: "https://example.com/sub", Override: override}
+	source.NormalizeDefaults("sub")
+	if err := subscription.SaveSource(p.SubConfigDir, source); err != nil {
+		t.Fatalf("save source: %v", err)
+	}
+	if err := os.MkdirAll(p.SubCacheDir, 0755); err != nil {
+		t.Fatalf("create cache dir: %v", err)
+	}
+	cache := subscription.Cache{Source: source, Nodes: []model.Node{{
+		ID:   "0123456789abcdef",
+		Name: "HK",
+		Type: "vless",
+		Outbound: map[string]any{
+			"server":      "1.1.1.1",
+			"server_port": 443,
+			"uuid":        "11111111-1111-1111-1111-111111111111",
+			"tls":         map[string]any{"enabled": true, "server_name": "example.com"},
+		},
+	}}}
+	if err := subscription.SaveCache(filepath.Join(p.SubCacheDir, "sub.json"), cache); err != nil {
+		t.Fatalf("save cache: %v", err)
+	}
+}
+
+func TestSubscriptionNodeProvider_AppliesOverride(t *testing.T) {
+	setupSubscription(t, map[string]any{
+		"multiplex":     map[string]any{"enabled": true, "protocol": "h2mux"},
+		"tcp_fast_open": true,
+		"tls":           map[string]any{"utls": map[string]any{"enabled": true, "fingerprint": "chrome"}},
+	})
+
+	nodes, err := (&SubscriptionNodeProvider{}).GetNodes()
+	if err != nil {
+		t.Fatalf("GetNodes: %v", err)
+	}
+	if len(nodes) != 1 {
+		t.Fatalf("expected 1 node, got %d", len(nodes))
+	}
+	out := nodes[0].Outbound
+	if out["tcp_fast_open"] != true {
+		t.Fatalf("expected tcp_fast_open override, got %v", out["tcp_fast_open"])
+	}
+	tls, _ := out["tls"].(map[string]any)
+	if tls["server_name"] != "example.com" || tls["utls"] == nil {
+		t.Fatalf("expected tls to be deep-merged, got %v", tls)
+	}
+	if nodes[0].ID != "0123456789abcdef" {
+		t.Fatalf("override must not change the stable ID, got %s", nodes[0].ID)
+	}
+}
+
+func TestSubscriptionNodeProvider_RejectsInvalidOverride(t *testing.T) {
+	setupSubscription(t, map[string]any{"multiplx": map[string]any{"enabled": true}})
+
+	_, err := (&SubscriptionNodeProvider{}).GetNodes()
+	if err == nil || !strings.Contains(err.Error(), "multiplx") {
+		t.Fatalf("expected unknown field error, got %v", err)
+	}
+}
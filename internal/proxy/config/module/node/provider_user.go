@@ -33,11 +33,13 @@ func (p *UserNodeProvider) GetNodes() ([]model.Node, error) {
 			return nil, err
 		}
 
+		parsed := out
 		nodes = append(nodes, model.Node{
 			Name:     out.Tag,
 			Type:     out.Type,
 			Source:   "user",
 			Outbound: outboundMap,
+			Parsed:   &parsed,
 		})
 	}
 	return nodes, nil
@@ -1,9 +1,15 @@
 package module
 
 import (
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
 	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
 	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
+	"github.com/kyson-dev/sing-helm/internal/sys/logger"
+	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
 )
 
 // OutboundModule 出站模块
@@ -28,19 +34,59 @@ func (m *OutboundModule) Apply(opts *option.Options, ctx *BuildContext) error {
 	providers = append(providers, m.providers...)
 
 	// 1. 从所有 Provider 获取节点
+	detours := make(map[string]bool)
 	for _, provider := range providers {
 		nodes, err := provider.GetNodes()
 		if err != nil {
 			return err
 		}
+		for _, n := range nodes {
+			if detour, ok := n.Outbound["detour"].(string); ok && detour != "" {
+				detours[detour] = true
+			}
+		}
 		processor.AddNodes(nodes)
 	}
 
-	// 2. 获取去重且正确命名后的 proxy 出站节点
+	// 2. 获取去重且正确命名后的 proxy 出站节点，剔除用户禁用的节点
+	var nodeSettings model.NodeSettings
+	if ctx != nil {
+		nodeSettings = ctx.Settings.Nodes
+	}
+	entries, disabled := splitDisabledNodes(processor.GetEntries(), nodeSettings, detours)
+	disabledTags := make(map[string]bool, len(disabled))
+	for _, e := range disabled {
+		disabledTags[e.Tag] = true
+	}
+
 	filteredOutbounds := make([]option.Outbound, 0)
-	filteredOutbounds = append(filteredOutbounds, processor.GetProcessedOutbounds()...)
+	for _, out := range processor.GetProcessedOutbounds() {
+		if !disabledTags[out.Tag] {
+			filteredOutbounds = append(filteredOutbounds, out)
+		}
+	}
 
-	actualNodes := processor.GetActualTags()
+	actualNodes := make([]string, 0, len(processor.GetActualTags()))
+	for _, tag := range processor.GetActualTags() {
+		if !disabledTags[tag] {
+			actualNodes = append(actualNodes, tag)
+		}
+	}
+	if ctx != nil {
+		ctx.Nodes = entries
+		ctx.Merges = processor.GetMerges()
+		ctx.DisabledNodes = disabled
+	}
+
+	// 生成组内的节点按 settings 中的排序策略排列
+	orderedNodes := actualNodes
+	if ctx != nil {
+		policy := ctx.Settings.Groups.Order
+		if !nodeProvider.ValidOrderPolicy(policy) {
+			logger.Error("Unknown group order policy, keeping processing order", "order", policy)
+		}
+		orderedNodes = nodeProvider.OrderTags(actualNodes, entries, policy, ctx.Latency)
+	}
 
 	// 3. 构建内置出站
 	// 5. 添加 direct 出站
@@ -64,28 +110,29 @@ func (m *OutboundModule) Apply(opts *option.Options, ctx *BuildContext) error {
 	// 根据是否有实际节点决定如何配置 auto 和 proxy 策略组
 	if len(actualNodes) > 0 {
 
-		// 7. 添加 proxy selector
-		proxyNodes := append([]string{moduleUtils.TagAuto}, actualNodes...)
-		proxyOutbound := option.Outbound{}
-		proxyOutboundMap := map[string]any{
-			"type":      "selector",
-			"tag":       moduleUtils.TagProxy,
-			"outbounds": proxyNodes,
-			"default":   moduleUtils.TagAuto,
+		// 7. 添加 proxy selector（收藏节点排在最前）
+		proxyNodes := append([]string{moduleUtils.TagAuto}, favoritesFirst(orderedNodes, entries, nodeSettings)...)
+		// 成员可达数万个，直接构造结构体，避免 map -> JSON -> 结构体的往返
+		proxyOutbound := option.Outbound{
+			Type: C.TypeSelector,
+			Tag:  moduleUtils.TagProxy,
+			Options: &option.SelectorOutboundOptions{
+				Outbounds: proxyNodes,
+				Default:   moduleUtils.TagAuto,
+			},
 		}
-		moduleUtils.ApplyMapToOutbound(&proxyOutbound, proxyOutboundMap)
 		filteredOutbounds = append(filteredOutbounds, proxyOutbound)
 
 		// 8. 添加 auto urltest
-		autoOutbound := option.Outbound{}
-		autoOutboundMap := map[string]any{
-			"type":         "urltest",
-			"tag":          moduleUtils.TagAuto,
-			"outbounds":    actualNodes,
-			"interval":     "3m",
-			"idle_timeout": "24h",
-		}
-		moduleUtils.ApplyMapToOutbound(&autoOutbound, autoOutboundMap)
+		autoOutbound := option.Outbound{
+			Type: C.TypeURLTest,
+			Tag:  moduleUtils.TagAuto,
+			Options: &option.URLTestOutboundOptions{
+				Outbounds:   append([]string(nil), orderedNodes...),
+				Interval:    badoption.Duration(3 * time.Minute),
+				IdleTimeout: badoption.Duration(24 * time.Hour),
+			},
+		}
 		filteredOutbounds = append(filteredOutbounds, autoOutbound)
 	} else {
 		// 无节点时的逻辑：
@@ -121,17 +168,22 @@ func (m *OutboundModule) Apply(opts *option.Options, ctx *BuildContext) error {
 		if generatedByTag[out.Tag] && !userGeneratedTags[out.Tag] {
 			continue
 		}
+		// 用户定义的节点同样可被禁用，并从用户策略组中移除
+		if disabledTags[out.Tag] {
+			continue
+		}
+		removeDisabledMembers(&out, disabledTags)
 
 		// 用户 selector/urltest 的 outbounds 为空数组时，自动填充全部实际节点。
 		if len(actualNodes) > 0 {
 			switch outOpts := out.Options.(type) {
 			case *option.SelectorOutboundOptions:
 				if len(outOpts.Outbounds) == 0 {
-					outOpts.Outbounds = append([]string(nil), actualNodes...)
+					outOpts.Outbounds = append([]string(nil), orderedNodes...)
 				}
 			case *option.URLTestOutboundOptions:
 				if len(outOpts.Outbounds) == 0 {
-					outOpts.Outbounds = append([]string(nil), actualNodes...)
+					outOpts.Outbounds = append([]string(nil), orderedNodes...)
 				}
 			}
 		}
@@ -150,3 +202,78 @@ func (m *OutboundModule) Apply(opts *option.Options, ctx *BuildContext) error {
 
 	return nil
 }
+
+// splitDisabledNodes 按节点 ID 拆分出被禁用的节点。被其他节点用作 detour 的节点无法移除，保持启用
+func splitDisabledNodes(entries []nodeProvider.NodeEntry, settings model.NodeSettings, detours map[string]bool) (kept, disabled []nodeProvider.NodeEntry) {
+	if len(settings.Disabled) == 0 {
+		return entries, nil
+	}
+	kept = make([]nodeProvider.NodeEntry, 0, len(entries))
+	for _, e := range entries {
+		if !settings.IsDisabled(e.ID) {
+			kept = append(kept, e)
+			continue
+		}
+		if detours[e.Tag] || detours[e.Name] || detours[e.Source+"/"+e.Name] {
+			logger.Error("Node is used as a detour and cannot be disabled", "tag", e.Tag, "id", e.ID)
+			kept = append(kept, e)
+			continue
+		}
+		disabled = append(disabled, e)
+	}
+	return kept, disabled
+}
+
+// favoritesFirst 返回收藏节点在前、其余节点在后的 tag 列表，两部分各自保持原有顺序
+func favoritesFirst(tags []string, entries []nodeProvider.NodeEntry, settings model.NodeSettings) []string {
+	if len(settings.Favorites) == 0 {
+		return tags
+	}
+	favorite := make(map[string]bool)
+	for _, e := range entries {
+		if settings.IsFavorite(e.ID) {
+			favorite[e.Tag] = true
+		}
+	}
+	ordered := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if favorite[tag] {
+			ordered = append(ordered, tag)
+		}
+	}
+	for _, tag := range tags {
+		if !favorite[tag] {
+			ordered = append(ordered, tag)
+		}
+	}
+	return ordered
+}
+
+// removeDisabledMembers 从用户 selector/urltest 中移除被禁用的节点
+func removeDisabledMembers(out *option.Outbound, disabledTags map[string]bool) {
+	if len(disabledTags) == 0 {
+		return
+	}
+	filter := func(members []string) []string {
+		kept := make([]string, 0, len(members))
+		for _, m := range members {
+			if !disabledTags[m] {
+				kept = append(kept, m)
+			}
+		}
+		return kept
+	}
+	switch opts := out.Options.(type) {
+	case *option.SelectorOutboundOptions:
+		if len(opts.Outbounds) > 0 {
+			opts.Outbounds = filter(opts.Outbounds)
+		}
+		if disabledTags[opts.Default] {
+			opts.Default = ""
+		}
+	case *option.URLTestOutboundOptions:
+		if len(opts.Outbounds) > 0 {
+			opts.Outbounds = filter(opts.Outbounds)
+		}
+	}
+}
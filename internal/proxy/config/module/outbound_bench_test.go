@@ -0,0 +1,59 @@
+package module
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	"github.com/sagernet/sing-box/option"
+)
+
+// benchNodes 生成 n 个订阅节点，约 5% 与前面的节点重复，用于触发去重
+func benchNodes(n int) []model.Node {
+	nodes := make([]model.Node, 0, n)
+	for i := 0; i < n; i++ {
+		server := i
+		if i%20 == 19 {
+			server = i - 1
+		}
+		nodes = append(nodes, model.Node{
+			Name:           fmt.Sprintf("🇭🇰 Hong Kong %05d", i),
+			Type:           "vless",
+			Source:         fmt.Sprintf("sub-%d", i%4),
+			DedupeStrategy: model.DedupeStrict,
+			Outbound: map[string]any{
+				"tag":         fmt.Sprintf("🇭🇰 Hong Kong %05d", i),
+				"server":      fmt.Sprintf("node-%d.example.com", server),
+				"server_port": float64(443),
+				"uuid":        "11111111-1111-1111-1111-111111111111",
+				"flow":        "xtls-rprx-vision",
+				"tls": map[string]any{
+					"enabled":     true,
+					"server_name": "www.example.com",
+					"utls":        map[string]any{"enabled": true, "fingerprint": "chrome"},
+				},
+			},
+		})
+	}
+	return nodes
+}
+
+func BenchmarkOutboundApply(b *testing.B) {
+	for _, size := range []int{1000, 10000, 50000} {
+		provider := &stubNodeProvider{name: "sub", nodes: benchNodes(size)}
+		apply := func(b *testing.B) {
+			if err := NewOutboundModule(provider).Apply(&option.Options{}, &BuildContext{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+		// 重建（模式切换、reload）时节点未变，已解析的出站可直接复用
+		b.Run(fmt.Sprintf("nodes=%d", size), func(b *testing.B) {
+			apply(b)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				apply(b)
+			}
+		})
+	}
+}
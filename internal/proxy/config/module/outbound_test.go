@@ -1,6 +1,7 @@
 package module
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
@@ -148,3 +149,103 @@ func TestOutboundApply_UserActualOutboundsAreNotDuplicated(t *testing.T) {
 }
 
 var _ nodeProvider.NodeProvider = (*stubNodeProvider)(nil)
+
+func TestOutboundApply_DisabledAndFavoriteNodes(t *testing.T) {
+	node := func(name, server string) model.Node {
+		return model.Node{
+			Name:   name,
+			Type:   "vless",
+			Source: "sub",
+			Outbound: map[string]any{
+				"server":      server,
+				"server_port": 443,
+				"uuid":        "22222222-2222-2222-2222-222222222222",
+			},
+		}
+	}
+	nodes := []model.Node{node("HK", "1.1.1.1"), node("JP", "2.2.2.2"), node("US", "3.3.3.3")}
+
+	var userGroup option.Outbound
+	if err := moduleUtils.ApplyMapToOutbound(&userGroup, map[string]any{
+		"type":      "selector",
+		"tag":       "streaming",
+		"outbounds": []string{"HK", "JP"},
+		"default":   "HK",
+	}); err != nil {
+		t.Fatalf("user group: %v", err)
+	}
+	opts := &option.Options{Outbounds: []option.Outbound{userGroup}}
+
+	ctx := NewBuildContext(&model.RunOptions{})
+	ctx.Settings.Nodes = model.NodeSettings{
+		Disabled:  []string{nodes[0].StableID()},
+		Favorites: []string{nodes[2].StableID()},
+	}
+	if err := NewOutboundModule(&stubNodeProvider{name: "sub", nodes: nodes}).Apply(opts, ctx); err != nil {
+		t.Fatalf("apply outbound: %v", err)
+	}
+
+	var proxy, streaming *option.SelectorOutboundOptions
+	var auto *option.URLTestOutboundOptions
+	for i := range opts.Outbounds {
+		out := &opts.Outbounds[i]
+		switch out.Tag {
+		case "HK":
+			t.Fatalf("disabled node HK should not be generated")
+		case moduleUtils.TagProxy:
+			proxy = out.Options.(*option.SelectorOutboundOptions)
+		case moduleUtils.TagAuto:
+			auto = out.Options.(*option.URLTestOutboundOptions)
+		case "streaming":
+			streaming = out.Options.(*option.SelectorOutboundOptions)
+		}
+	}
+	if proxy == nil || auto == nil || streaming == nil {
+		t.Fatalf("missing expected groups after apply")
+	}
+	if got := strings.Join(proxy.Outbounds, ","); got != "auto,US,JP" {
+		t.Fatalf("expected favorite US first in proxy, got %s", got)
+	}
+	if got := strings.Join(auto.Outbounds, ","); got != "JP,US" {
+		t.Fatalf("expected auto to keep original order without HK, got %s", got)
+	}
+	if got := strings.Join(streaming.Outbounds, ","); got != "JP" || streaming.Default != "" {
+		t.Fatalf("expected HK removed from user group, got %s (default %q)", got, streaming.Default)
+	}
+	if len(ctx.Nodes) != 2 || len(ctx.DisabledNodes) != 1 || ctx.DisabledNodes[0].Tag != "HK" {
+		t.Fatalf("unexpected node entries: nodes=%+v disabled=%+v", ctx.Nodes, ctx.DisabledNodes)
+	}
+}
+
+func TestOutboundApply_GroupOrder(t *testing.T) {
+	node := func(name, server string) model.Node {
+		return model.Node{
+			Name:     name,
+			Type:     "vless",
+			Source:   "sub",
+			Outbound: map[string]any{"server": server, "server_port": 443, "uuid": "22222222-2222-2222-2222-222222222222"},
+		}
+	}
+	nodes := []model.Node{node("HK 10", "1.1.1.1"), node("JP 1", "2.2.2.2"), node("HK 2", "3.3.3.3")}
+
+	opts := &option.Options{}
+	ctx := NewBuildContext(&model.RunOptions{})
+	ctx.Settings.Groups.Order = nodeProvider.OrderName
+	ctx.Settings.Nodes.Favorites = []string{nodes[1].StableID()}
+	if err := NewOutboundModule(&stubNodeProvider{name: "sub", nodes: nodes}).Apply(opts, ctx); err != nil {
+		t.Fatalf("apply outbound: %v", err)
+	}
+
+	for _, out := range opts.Outbounds {
+		switch out.Tag {
+		case moduleUtils.TagProxy:
+			if got := strings.Join(out.Options.(*option.SelectorOutboundOptions).Outbounds, ","); got != "auto,JP 1,HK 2,HK 10" {
+				t.Fatalf("unexpected proxy order: %s", got)
+			}
+		case moduleUtils.TagAuto:
+			if got := strings.Join(out.Options.(*option.URLTestOutboundOptions).Outbounds, ","); got != "HK 2,HK 10,JP 1" {
+				t.Fatalf("unexpected auto order: %s", got)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package module
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
+	"github.com/sagernet/sing-box/option"
+)
+
+// busyPorts 返回把指定端口视为被其他进程占用的检测函数
+func busyPorts(ports ...int) func(string, int) error {
+	busy := make(map[int]bool)
+	for _, p := range ports {
+		busy[p] = true
+	}
+	return func(_ string, port int) error {
+		if busy[port] {
+			return errors.New("address already in use")
+		}
+		return nil
+	}
+}
+
+func TestPortAllocator_ExplicitPortInUseFails(t *testing.T) {
+	ctx := NewBuildContext(&model.RunOptions{})
+	ctx.Ports.SetProbe(busyPorts(7890))
+
+	err := (&MixedModule{ListenAddr: "127.0.0.1", Port: 7890}).Apply(&option.Options{}, ctx)
+	if err == nil || !strings.Contains(err.Error(), "mixed port 7890 is already in use") {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+}
+
+func TestPortAllocator_PreferredPortReusedOrReallocated(t *testing.T) {
+	ranges := map[string]moduleUtils.PortRange{moduleUtils.PortAPI: {Min: 9090, Max: 9092}}
+
+	// 上次分配的端口空闲时原样复用
+	run := &model.RunOptions{APIPort: 9091}
+	ctx := NewBuildContext(run)
+	ctx.Ports.Ranges = ranges
+	ctx.Ports.Preferred = map[string]int{moduleUtils.PortAPI: 9091}
+	ctx.Ports.SetProbe(busyPorts())
+	if err := (&ExperimentalModule{APIPort: run.APIPort}).Apply(&option.Options{}, ctx); err != nil {
+		t.Fatalf("apply experimental: %v", err)
+	}
+	if run.APIPort != 9091 || ctx.Ports.Allocated[moduleUtils.PortAPI] != 9091 {
+		t.Fatalf("expected preferred port reused, got %d (allocated %v)", run.APIPort, ctx.Ports.Allocated)
+	}
+
+	// 被其他进程占用时从范围内重新分配，而不是报错
+	run = &model.RunOptions{APIPort: 9091}
+	ctx = NewBuildContext(run)
+	ctx.Ports.Ranges = ranges
+	ctx.Ports.Preferred = map[string]int{moduleUtils.PortAPI: 9091}
+	ctx.Ports.SetProbe(busyPorts(9090, 9091))
+	if err := (&ExperimentalModule{APIPort: run.APIPort}).Apply(&option.Options{}, ctx); err != nil {
+		t.Fatalf("apply experimental: %v", err)
+	}
+	if run.APIPort != 9092 || ctx.Ports.Allocated[moduleUtils.PortAPI] != 9092 {
+		t.Fatalf("expected reallocation to 9092, got %d (allocated %v)", run.APIPort, ctx.Ports.Allocated)
+	}
+}
+
+func TestPortAllocator_OwnedPortsAndConflicts(t *testing.T) {
+	ctx := NewBuildContext(&model.RunOptions{})
+	// 运行中的 sing-box 自己占用的端口不算冲突
+	ctx.Ports.Owned = map[int]bool{7890: true}
+	ctx.Ports.SetProbe(busyPorts(7890))
+	if port, err := ctx.AllocatePort(moduleUtils.PortMixed, "127.0.0.1", 7890); err != nil || port != 7890 {
+		t.Fatalf("expected owned port to be accepted, got %d, %v", port, err)
+	}
+	// 同一次构建内两个用途不能使用同一端口
+	if _, err := ctx.AllocatePort(moduleUtils.PortAPI, "127.0.0.1", 7890); err == nil || !strings.Contains(err.Error(), "conflicts with the mixed port") {
+		t.Fatalf("expected conflict with mixed port, got %v", err)
+	}
+	// 范围耗尽
+	ctx.Ports.Ranges = map[string]moduleUtils.PortRange{moduleUtils.PortProbe: {Min: 7890, Max: 7890}}
+	if _, err := ctx.AllocatePort(moduleUtils.PortProbe, "127.0.0.1", 0); err == nil || !strings.Contains(err.Error(), "no free probe port") {
+		t.Fatalf("expected exhausted range error, got %v", err)
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	cases := map[string]moduleUtils.PortRange{
+		"":          {},
+		"7890":      {Min: 7890, Max: 7890},
+		"9090-9099": {Min: 9090, Max: 9099},
+	}
+	for in, want := range cases {
+		got, err := moduleUtils.ParsePortRange(in)
+		if err != nil || got != want {
+			t.Fatalf("ParsePortRange(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"0", "9099-9090", "70000", "a-b"} {
+		if _, err := moduleUtils.ParsePortRange(in); err == nil {
+			t.Fatalf("ParsePortRange(%q) should fail", in)
+		}
+	}
+}
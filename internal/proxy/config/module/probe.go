@@ -0,0 +1,88 @@
+package module
+
+import (
+	"context"
+	"crypto/rand"
+
+	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
+	"github.com/sagernet/sing-box/include"
+	"github.com/sagernet/sing-box/option"
+	singboxjson "github.com/sagernet/sing/common/json"
+)
+
+// ProbeModule 测速探针模块
+// 添加仅监听回环地址的 probe-in 入站和 probe selector，并用首条路由规则将
+// probe-in 的流量全部交给 probe。daemon 切换 probe 即可让测速流量经过指定节点，
+// 而不影响用户正在使用的 proxy 组。必须在 OutboundModule 和 RouteModule 之后应用。
+// probe-in 绕过了用户的路由规则，因此要求认证：密码随机生成并回填到 RunOptions，
+// 只有 daemon 知道，本机其他用户无法借它使用代理。
+type ProbeModule struct {
+	Port int
+	Auth string // 认证密码，空表示随机生成
+}
+
+func (m *ProbeModule) Name() string {
+	return "probe"
+}
+
+func (m *ProbeModule) Apply(opts *option.Options, ctx *BuildContext) error {
+	if ctx == nil || len(ctx.Nodes) == 0 {
+		return nil
+	}
+
+	port, err := ctx.AllocatePort(moduleUtils.PortProbe, "127.0.0.1", m.Port)
+	if err != nil {
+		return err
+	}
+	auth := m.Auth
+	if auth == "" {
+		auth = rand.Text()
+	}
+	if ctx.RunOptions != nil {
+		ctx.RunOptions.ProbePort = port
+		ctx.RunOptions.ProbeAuth = auth
+	}
+
+	probeInbound := option.Inbound{}
+	if err := moduleUtils.ApplyMapToInbound(&probeInbound, map[string]any{
+		"type":        "mixed",
+		"tag":         moduleUtils.TagProbeIn,
+		"listen":      "127.0.0.1",
+		"listen_port": port,
+		"users":       []map[string]any{{"username": moduleUtils.ProbeUser, "password": auth}},
+	}); err != nil {
+		return err
+	}
+	opts.Inbounds = append(opts.Inbounds, probeInbound)
+
+	tags := make([]string, 0, len(ctx.Nodes))
+	for _, n := range ctx.Nodes {
+		tags = append(tags, n.Tag)
+	}
+	probeOutbound := option.Outbound{}
+	if err := moduleUtils.ApplyMapToOutbound(&probeOutbound, map[string]any{
+		"type":      "selector",
+		"tag":       moduleUtils.TagProbe,
+		"outbounds": tags,
+	}); err != nil {
+		return err
+	}
+	opts.Outbounds = append(opts.Outbounds, probeOutbound)
+
+	// probe-in 的规则必须排在所有规则之前，避免被分流到其他出站
+	data, err := singboxjson.Marshal(map[string]any{
+		"rules": []map[string]any{{"inbound": []string{moduleUtils.TagProbeIn}, "outbound": moduleUtils.TagProbe}},
+	})
+	if err != nil {
+		return err
+	}
+	var parsed option.RouteOptions
+	if err := singboxjson.UnmarshalContext(include.Context(context.Background()), data, &parsed); err != nil {
+		return err
+	}
+	if opts.Route == nil {
+		opts.Route = &option.RouteOptions{}
+	}
+	opts.Route.Rules = append(parsed.Rules, opts.Route.Rules...)
+	return nil
+}
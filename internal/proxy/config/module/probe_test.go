@@ -0,0 +1,93 @@
+package module
+
+import (
+	"testing"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
+	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
+	"github.com/sagernet/sing-box/option"
+)
+
+func TestProbeApply_AddsLoopbackInboundAndLeadingRule(t *testing.T) {
+	run := &model.RunOptions{}
+	ctx := NewBuildContext(run)
+	ctx.Nodes = []nodeProvider.NodeEntry{{Tag: "HK 01"}, {Tag: "JP 01"}}
+	opts := &option.Options{}
+	if err := (&RouteModule{RouteMode: model.RouteModeRule}).Apply(opts, ctx); err != nil {
+		t.Fatalf("apply route: %v", err)
+	}
+
+	ctx.Ports.SetProbe(func(string, int) error { return nil }) // 与本机端口占用无关
+	if err := (&ProbeModule{Port: 23456}).Apply(opts, ctx); err != nil {
+		t.Fatalf("apply probe: %v", err)
+	}
+	if run.ProbePort != 23456 {
+		t.Fatalf("expected probe port backfilled, got %d", run.ProbePort)
+	}
+
+	var inbound *option.Inbound
+	for i := range opts.Inbounds {
+		if opts.Inbounds[i].Tag == moduleUtils.TagProbeIn {
+			inbound = &opts.Inbounds[i]
+		}
+	}
+	if inbound == nil {
+		t.Fatalf("expected probe-in inbound")
+	}
+	mixed, ok := inbound.Options.(*option.HTTPMixedInboundOptions)
+	if !ok || mixed.Listen == nil || mixed.SetSystemProxy {
+		t.Fatalf("expected loopback mixed inbound without system proxy, got %+v", inbound.Options)
+	}
+	// probe-in 绕过路由规则，必须要求只有 daemon 知道的随机密码
+	if run.ProbeAuth == "" {
+		t.Fatalf("expected a generated probe password backfilled")
+	}
+	if len(mixed.Users) != 1 || mixed.Users[0].Username != moduleUtils.ProbeUser || mixed.Users[0].Password != run.ProbeAuth {
+		t.Fatalf("expected probe-in to require the generated password, got %+v", mixed.Users)
+	}
+
+	var selector *option.SelectorOutboundOptions
+	for _, out := range opts.Outbounds {
+		if out.Tag == moduleUtils.TagProbe {
+			selector, _ = out.Options.(*option.SelectorOutboundOptions)
+		}
+	}
+	if selector == nil || len(selector.Outbounds) != 2 {
+		t.Fatalf("expected probe selector over all nodes, got %+v", selector)
+	}
+
+	first := opts.Route.Rules[0].DefaultOptions
+	if len(first.Inbound) != 1 || first.Inbound[0] != moduleUtils.TagProbeIn {
+		t.Fatalf("expected probe rule first, got %+v", first)
+	}
+}
+
+func TestProbeApply_KeepsPassword(t *testing.T) {
+	// 重新构建时沿用已有密码，相同输入得到相同配置
+	run := &model.RunOptions{}
+	ctx := NewBuildContext(run)
+	ctx.Nodes = []nodeProvider.NodeEntry{{Tag: "HK 01"}}
+	ctx.Ports.SetProbe(func(string, int) error { return nil })
+	opts := &option.Options{}
+	if err := (&ProbeModule{Port: 23456, Auth: "secret"}).Apply(opts, ctx); err != nil {
+		t.Fatalf("apply probe: %v", err)
+	}
+	if run.ProbeAuth != "secret" {
+		t.Fatalf("expected the given password kept, got %q", run.ProbeAuth)
+	}
+	mixed := opts.Inbounds[0].Options.(*option.HTTPMixedInboundOptions)
+	if len(mixed.Users) != 1 || mixed.Users[0].Password != "secret" {
+		t.Fatalf("expected probe-in to use the given password, got %+v", mixed.Users)
+	}
+}
+
+func TestProbeApply_NoNodesSkipsProbe(t *testing.T) {
+	opts := &option.Options{}
+	if err := (&ProbeModule{}).Apply(opts, NewBuildContext(&model.RunOptions{})); err != nil {
+		t.Fatalf("apply probe: %v", err)
+	}
+	if len(opts.Inbounds) != 0 || len(opts.Outbounds) != 0 {
+		t.Fatalf("expected no probe without nodes, got %d inbounds and %d outbounds", len(opts.Inbounds), len(opts.Outbounds))
+	}
+}
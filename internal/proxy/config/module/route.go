@@ -5,6 +5,7 @@ import (
 
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
 	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
+	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/include"
 	"github.com/sagernet/sing-box/option"
 	singboxjson "github.com/sagernet/sing/common/json"
@@ -233,25 +234,22 @@ func metaGeoipRuleSet(name string) map[string]any {
 func keepSniffRules(rules []option.Rule) []option.Rule {
 	kept := rules[:0]
 	for _, rule := range rules {
-		raw, err := singboxjson.Marshal(rule)
-		if err != nil {
+		if rule.Type == C.RuleTypeLogical {
+			switch rule.LogicalOptions.Action {
+			case C.RuleActionTypeSniff, C.RuleActionTypeHijackDNS:
+				kept = append(kept, rule)
+			}
 			continue
 		}
-		var rm map[string]any
-		if err := singboxjson.Unmarshal(raw, &rm); err != nil {
-			continue
-		}
-		action, _ := rm["action"].(string)
-		if action == "sniff" || action == "hijack-dns" {
+		opts := rule.DefaultOptions
+		switch {
+		case opts.Action == C.RuleActionTypeSniff, opts.Action == C.RuleActionTypeHijackDNS:
 			kept = append(kept, rule)
-			continue
-		}
-		// Preserve AliDNS direct-bypass so bootstrap DoH to 223.5.5.5 stays direct.
-		if _, hasCIDR := rm["ip_cidr"]; hasCIDR {
+		case len(opts.IPCIDR) > 0:
+			// Preserve AliDNS direct-bypass so bootstrap DoH to 223.5.5.5 stays direct.
 			kept = append(kept, rule)
-		}
-		// 保留局域网私网直连规则，防止全局代理模式下局域网设备断连
-		if isPrivate, _ := rm["ip_is_private"].(bool); isPrivate {
+		case opts.IPIsPrivate:
+			// 保留局域网私网直连规则，防止全局代理模式下局域网设备断连
 			kept = append(kept, rule)
 		}
 	}
@@ -2,6 +2,8 @@ package module
 
 import (
 	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
+	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
 	"github.com/sagernet/sing-box/option"
 )
 
@@ -18,11 +20,38 @@ type ConfigModule interface {
 type BuildContext struct {
 	// RunOptions 运行时参数
 	RunOptions *model.RunOptions
+	// Settings 用户设置（节点禁用/收藏等），由 Builder 从 settings.json 加载
+	Settings model.Settings
+	// Latency 节点 ID 到历史延迟中位数（毫秒），仅在按延迟排序时由 Builder 加载
+	Latency map[string]int
+	// Nodes 由 OutboundModule 回填：每个生成节点的 tag 及其来源
+	Nodes []nodeProvider.NodeEntry
+	// Merges 由 OutboundModule 回填：被去重合并到已有 tag 的节点
+	Merges []nodeProvider.NodeMerge
+	// DisabledNodes 由 OutboundModule 回填：按 Settings 禁用而未生成出站的节点
+	DisabledNodes []nodeProvider.NodeEntry
+	// Ports 监听端口分配器，Builder 按 settings 设置分配范围
+	Ports *moduleUtils.PortAllocator
+}
+
+// AllocatePort 为 name 分配监听端口，port 为 0 时自动分配
+func (c *BuildContext) AllocatePort(name, listen string, port int) (int, error) {
+	if c == nil {
+		if port > 0 {
+			return port, nil
+		}
+		return moduleUtils.GetFreePort()
+	}
+	if c.Ports == nil {
+		c.Ports = moduleUtils.NewPortAllocator()
+	}
+	return c.Ports.Allocate(name, listen, port)
 }
 
 // NewBuildContext 创建构建上下文
 func NewBuildContext(opts *model.RunOptions) *BuildContext {
 	return &BuildContext{
 		RunOptions: opts,
+		Ports:      moduleUtils.NewPortAllocator(),
 	}
 }
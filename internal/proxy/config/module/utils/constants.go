@@ -7,8 +7,17 @@ const (
 	TagProxy  = "proxy"
 	TagAuto   = "auto"
 	TagDNS    = "dns-out"
+	TagProbe  = "probe" // 测速专用 selector
 )
 
+// Well-known inbound tags
+const (
+	TagProbeIn = "probe-in" // 测速专用回环入站
+)
+
+// ProbeUser 测速入站的认证用户名，密码见 RunOptions.ProbeAuth
+const ProbeUser = "probe"
+
 // Well-known DNS server tags
 const (
 	TagLocalDNS = "local_dns"
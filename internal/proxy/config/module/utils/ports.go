@@ -1,8 +1,19 @@
 package module
 
 import (
+	"fmt"
 	"net"
+	"strconv"
+	"strings"
 )
+
+// 自动分配端口的用途，同时作为 RuntimeState 中记录已分配端口的键
+const (
+	PortAPI   = "api"
+	PortMixed = "mixed"
+	PortProbe = "probe"
+)
+
 // GetFreePort 请求内核分配一个空闲端口
 func GetFreePort() (int, error) {
 	// 监听端口 0，内核会自动分配一个空闲端口
@@ -20,3 +31,155 @@ func GetFreePort() (int, error) {
 	// 返回分配到的端口
 	return l.Addr().(*net.TCPAddr).Port, nil
 }
+
+// PortRange 端口范围（闭区间），零值表示由内核分配
+type PortRange struct {
+	Min int
+	Max int
+}
+
+// IsZero 是否未设置范围
+func (r PortRange) IsZero() bool {
+	return r.Min == 0 && r.Max == 0
+}
+
+// ParsePortRange 解析 "7890" 或 "7890-7899" 形式的端口范围，空字符串返回零值
+func ParsePortRange(s string) (PortRange, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return PortRange{}, nil
+	}
+	lo, hi, isRange := strings.Cut(s, "-")
+	if !isRange {
+		hi = lo
+	}
+	min, err1 := strconv.Atoi(strings.TrimSpace(lo))
+	max, err2 := strconv.Atoi(strings.TrimSpace(hi))
+	if err1 != nil || err2 != nil || min < 1 || max > 65535 || min > max {
+		return PortRange{}, fmt.Errorf("invalid port range %q (expected e.g. 7890 or 7890-7899)", s)
+	}
+	return PortRange{Min: min, Max: max}, nil
+}
+
+// PortAllocator 为一次构建分配监听端口，并在 sing-box 绑定之前发现端口冲突。
+//
+// 明确指定的端口被占用时直接报错；上次自动分配的端口（Preferred）优先复用，
+// 被占用时才重新分配，这样 reload 前后 API 端口保持不变，已连接的监控面板不受影响。
+type PortAllocator struct {
+	// Ranges 各用途自动分配端口的范围，未设置的用途由内核分配
+	Ranges map[string]PortRange
+	// Preferred 上次自动分配的端口；RunOptions 中与之相同的端口视为可替换
+	Preferred map[string]int
+	// Owned 当前运行的 sing-box 已监听的端口，检测冲突时不算被占用
+	Owned map[int]bool
+	// Allocated 本次构建中自动分配（或沿用自动分配）的端口，供调用方持久化
+	Allocated map[string]int
+	// Explicit 本次构建中明确指定的端口
+	Explicit map[string]int
+
+	used  map[int]string // 本次构建已分配的端口 -> 用途
+	probe func(listen string, port int) error
+}
+
+// NewPortAllocator 创建端口分配器
+func NewPortAllocator() *PortAllocator {
+	return &PortAllocator{
+		Allocated: make(map[string]int),
+		Explicit:  make(map[string]int),
+		used:      make(map[int]string),
+		probe:     probePort,
+	}
+}
+
+// SetProbe 替换端口可用性检测（测试用）
+func (a *PortAllocator) SetProbe(probe func(listen string, port int) error) {
+	a.probe = probe
+}
+
+// Allocate 为 name 分配 listen 上的端口。port 为 0 时自动分配
+func (a *PortAllocator) Allocate(name, listen string, port int) (int, error) {
+	if a.used == nil {
+		a.used = make(map[int]string)
+	}
+	if a.Allocated == nil {
+		a.Allocated = make(map[string]int)
+	}
+	if a.Explicit == nil {
+		a.Explicit = make(map[string]int)
+	}
+	if a.probe == nil {
+		a.probe = probePort
+	}
+
+	if port > 0 {
+		sticky := a.Preferred[name] == port
+		err := a.check(name, listen, port)
+		if err == nil {
+			a.take(name, port, sticky)
+			return port, nil
+		}
+		if !sticky {
+			return 0, err
+		}
+	}
+
+	if r, ok := a.Ranges[name]; ok && !r.IsZero() {
+		for p := r.Min; p <= r.Max; p++ {
+			if a.check(name, listen, p) == nil {
+				a.take(name, p, true)
+				return p, nil
+			}
+		}
+		return 0, fmt.Errorf("no free %s port in range %d-%d", name, r.Min, r.Max)
+	}
+
+	// 内核分配的端口可能与本次构建已分配的端口重复，重试几次
+	for i := 0; i < 8; i++ {
+		p, err := GetFreePort()
+		if err != nil {
+			return 0, err
+		}
+		if _, taken := a.used[p]; !taken {
+			a.take(name, p, true)
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("failed to allocate a free %s port", name)
+}
+
+// check 检查端口是否可用：不能与本次构建的其他用途重复，且不能被其他进程占用
+func (a *PortAllocator) check(name, listen string, port int) error {
+	if other, ok := a.used[port]; ok && other != name {
+		return fmt.Errorf("%s port %d conflicts with the %s port", name, port, other)
+	}
+	if a.Owned[port] {
+		return nil
+	}
+	if err := a.probe(listen, port); err != nil {
+		return fmt.Errorf("%s port %d is already in use: %w", name, port, err)
+	}
+	return nil
+}
+
+func (a *PortAllocator) take(name string, port int, auto bool) {
+	a.used[port] = name
+	if auto {
+		a.Allocated[name] = port
+		delete(a.Explicit, name)
+	} else {
+		a.Explicit[name] = port
+		delete(a.Allocated, name)
+	}
+}
+
+// probePort 尝试监听端口以确认其空闲
+func probePort(listen string, port int) error {
+	if listen == "" || listen == "::" || listen == "0.0.0.0" {
+		listen = ""
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort(listen, strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	return l.Close()
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
+)
+
+// NodeIndex maps the generated outbound tags of raw.json back to the nodes they
+// were built from. It is written next to raw.json on every build so the daemon
+// can follow a node when its generated tag changes.
+type NodeIndex struct {
+	Nodes []nodeProvider.NodeEntry `json:"nodes"`
+}
+
+// Lookup returns the entry generated with the given tag.
+func (idx *NodeIndex) Lookup(tag string) (nodeProvider.NodeEntry, bool) {
+	if idx == nil {
+		return nodeProvider.NodeEntry{}, false
+	}
+	for _, n := range idx.Nodes {
+		if n.Tag == tag {
+			return n, true
+		}
+	}
+	return nodeProvider.NodeEntry{}, false
+}
+
+// Resolve returns the current tag of the node identified by key.
+func (idx *NodeIndex) Resolve(key string) (string, bool) {
+	if idx == nil || key == "" {
+		return "", false
+	}
+	for _, n := range idx.Nodes {
+		if n.Matches(key) {
+			return n.Tag, true
+		}
+	}
+	return "", false
+}
+
+// SaveNodeIndex writes the node index to path.
+func SaveNodeIndex(path string, nodes []nodeProvider.NodeEntry) error {
+	if nodes == nil {
+		nodes = []nodeProvider.NodeEntry{}
+	}
+	data, err := json.MarshalIndent(NodeIndex{Nodes: nodes}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal node index: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write node index: %w", err)
+	}
+	return nil
+}
+
+// LoadNodeIndex reads the node index written by BuildConfig.
+func LoadNodeIndex(path string) (*NodeIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var idx NodeIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to parse node index: %w", err)
+	}
+	return &idx, nil
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/module"
+	nodeProvider "github.com/kyson-dev/sing-helm/internal/proxy/config/module/node"
+	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/subscription"
+	"github.com/kyson-dev/sing-helm/internal/sys/paths"
+)
+
+// Rendered is a built config that has not been written to disk yet.
+type Rendered struct {
+	Data      []byte                   // raw.json 内容
+	Nodes     []nodeProvider.NodeEntry // 生成节点，写入 nodes.json
+	InputHash string                   // 构建输入的摘要，见 InputHash
+	Inputs    Inputs                   // 构建输入的可读摘要，随配置版本一起保存
+}
+
+// Inputs records which versions of the user inputs a build read.
+type Inputs struct {
+	Profile       string            `json:"profile"`                 // profile.json 的 sha256
+	Subscriptions map[string]string `json:"subscriptions,omitempty"` // 已启用订阅 -> 缓存版本（修改时间），未缓存为 missing
+}
+
+// Render builds the runtime config (including the probe inbound) in memory.
+// Ports picked during the build are written back to runops; ports may be nil, in
+// which case listening ports are allocated without regard to a running instance.
+func Render(runops *model.RunOptions, ports *moduleUtils.PortAllocator) (*Rendered, error) {
+	if runops == nil {
+		defaultOpts := model.DefaultRunOptions()
+		runops = &defaultOpts
+	}
+	builder := NewBuilder(runops).WithPorts(ports)
+	for _, m := range DefaultModules(runops) {
+		builder.With(m)
+	}
+	// 测速探针仅用于本机运行的配置，不进入 BuildOptions 导出的配置
+	builder.With(&module.ProbeModule{Port: runops.ProbePort, Auth: runops.ProbeAuth})
+
+	// 输入文件在构建前读取：构建期间文件若有变化，摘要对应旧内容，下次比较时必然不同而重新构建
+	sources, inputs, err := hashSources()
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := builder.Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build config: %w", err)
+	}
+	data, err := Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	// 端口等回填后的 RunOptions 才能保证以相同输入重新构建得到相同输出
+	return &Rendered{
+		Data:      data,
+		Nodes:     builder.Context().Nodes,
+		InputHash: hashInputs(sources, runops),
+		Inputs:    inputs,
+	}, nil
+}
+
+// Save writes raw.json and the node index.
+func (r *Rendered) Save(rawPath string) error {
+	if err := WritePrivateFile(rawPath, r.Data); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	if indexPath := paths.Get().NodeIndexFile; indexPath != "" {
+		if err := SaveNodeIndex(indexPath, r.Nodes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WritePrivateFile writes data to path readable only by the owner. Generated
+// configs hold node credentials and the probe inbound password; files left
+// with wider permissions by older versions are tightened too.
+func WritePrivateFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+// InputHash summarizes everything a build reads: profile.json, settings.json, the
+// subscription definitions and the generation (mtime and size) of every enabled cache,
+// the latency history when groups are ordered by latency, and the run options.
+// Building twice with the same hash produces the same config.
+func InputHash(runops *model.RunOptions) (string, error) {
+	if runops == nil {
+		defaultOpts := model.DefaultRunOptions()
+		runops = &defaultOpts
+	}
+	sources, _, err := hashSources()
+	if err != nil {
+		return "", err
+	}
+	return hashInputs(sources, runops), nil
+}
+
+// hashSources 计算除 RunOptions 外所有构建输入文件的摘要，同时返回可读的输入版本
+func hashSources() ([]byte, Inputs, error) {
+	p := paths.Get()
+	h := sha256.New()
+	var inputs Inputs
+
+	for _, path := range []string{p.ConfigFile, p.SettingsFile} {
+		fmt.Fprintf(h, "file %s\n", path)
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, inputs, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		h.Write(data)
+		h.Write([]byte{'\n'})
+		if path == p.ConfigFile {
+			sum := sha256.Sum256(data)
+			inputs.Profile = hex.EncodeToString(sum[:])
+		}
+	}
+
+	sources, err := subscription.LoadSources(p.SubConfigDir)
+	if err != nil {
+		return nil, inputs, err
+	}
+	for _, s := range sources {
+		def, err := json.Marshal(s)
+		if err != nil {
+			return nil, inputs, err
+		}
+		fmt.Fprintf(h, "source %s\n", def)
+		if s.EnabledValue() {
+			gen := writeFileGeneration(h, filepath.Join(p.SubCacheDir, s.Name+".json"))
+			if inputs.Subscriptions == nil {
+				inputs.Subscriptions = make(map[string]string)
+			}
+			inputs.Subscriptions[s.Name] = gen
+		}
+	}
+
+	settings, _ := LoadSettings(p.SettingsFile)
+	if settings.Groups.Order == nodeProvider.OrderLatency {
+		writeFileGeneration(h, p.LatencyFile)
+	}
+	return h.Sum(nil), inputs, nil
+}
+
+// writeFileGeneration 以修改时间和大小标识文件的当前版本，缺失的文件记为 missing；
+// 返回可读的版本（修改时间）
+func writeFileGeneration(w io.Writer, path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		fmt.Fprintf(w, "gen %s missing\n", path)
+		return "missing"
+	}
+	fmt.Fprintf(w, "gen %s %d %d\n", path, info.ModTime().UnixNano(), info.Size())
+	return info.ModTime().UTC().Format(time.RFC3339)
+}
+
+func hashInputs(sources []byte, runops *model.RunOptions) string {
+	h := sha256.New()
+	h.Write(sources)
+	opts, _ := json.Marshal(runops)
+	h.Write(opts)
+	return hex.EncodeToString(h.Sum(nil))
+}
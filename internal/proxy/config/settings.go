@@ -0,0 +1,40 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+)
+
+// LoadSettings 读取 settings.json，文件不存在时返回默认设置
+func LoadSettings(path string) (model.Settings, error) {
+	settings := model.DefaultSettings()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return settings, fmt.Errorf("failed to read settings: %w", err)
+	}
+	if len(data) == 0 {
+		return settings, nil
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return model.DefaultSettings(), fmt.Errorf("failed to parse settings: %w", err)
+	}
+	return settings, nil
+}
+
+// SaveSettings 写入 settings.json
+func SaveSettings(path string, settings model.Settings) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write settings: %w", err)
+	}
+	return nil
+}
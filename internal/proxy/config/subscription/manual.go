@@ -0,0 +1,113 @@
+package subscription
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/model"
+	"github.com/kyson-dev/sing-helm/internal/proxy/config/subscription/adapter"
+)
+
+// ManualSourceName is the local source holding nodes added one by one with `node add`.
+const ManualSourceName = "manual"
+
+// FormatManual marks a source whose nodes live only in its cache file and are never downloaded.
+const FormatManual = "manual"
+
+// ErrManualSource is returned when trying to refresh the manual source.
+var ErrManualSource = errors.New("manual source has no URL to refresh")
+
+// IsManual reports whether the source is the local manual source.
+func (s Source) IsManual() bool {
+	return s.Format == FormatManual
+}
+
+// ParseURI parses a single share link (vless://, ss://, ...) through the adapter registry.
+func ParseURI(uri string) (model.Node, error) {
+	uri = strings.TrimSpace(uri)
+	idx := strings.Index(uri, "://")
+	if idx < 0 {
+		return model.Node{}, fmt.Errorf("invalid node URI: missing scheme")
+	}
+	scheme := strings.ToLower(uri[:idx])
+	a, err := adapter.Get(scheme)
+	if err != nil {
+		return model.Node{}, err
+	}
+	n, err := a.FromURI(uri[idx+3:])
+	if err != nil {
+		return model.Node{}, fmt.Errorf("parse %s URI failed: %w", scheme, err)
+	}
+	return n, nil
+}
+
+// AddManualNode parses uri and appends it to the manual source, creating the source on first use.
+// name overrides the name carried by the link.
+func AddManualNode(configDir, cacheDir, uri, name string) (model.Node, error) {
+	n, err := ParseURI(uri)
+	if err != nil {
+		return model.Node{}, err
+	}
+	if name != "" {
+		n.Name = name
+	}
+	if n.Name == "" {
+		n.Name = fmt.Sprintf("%s-%v:%v", n.Type, n.Outbound["server"], n.Outbound["server_port"])
+	}
+	n.ID = n.StableID()
+
+	source, err := ensureManualSource(configDir)
+	if err != nil {
+		return model.Node{}, err
+	}
+
+	cachePath := filepath.Join(cacheDir, ManualSourceName+".json")
+	cache, err := LoadCache(cachePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return model.Node{}, err
+		}
+		cache = &Cache{}
+	}
+	for _, existing := range cache.Nodes {
+		if existing.ID == n.ID {
+			return model.Node{}, fmt.Errorf("node already exists in %s source: %s", ManualSourceName, existing.Name)
+		}
+	}
+	cache.Source = source
+	cache.UpdatedAt = time.Now().Format(time.RFC3339)
+	cache.Nodes = append(cache.Nodes, n)
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return model.Node{}, fmt.Errorf("create cache dir failed: %w", err)
+	}
+	if err := SaveCache(cachePath, *cache); err != nil {
+		return model.Node{}, err
+	}
+	return n, nil
+}
+
+func ensureManualSource(configDir string) (Source, error) {
+	sources, err := LoadSources(configDir)
+	if err != nil {
+		return Source{}, err
+	}
+	for _, s := range sources {
+		if s.Name == ManualSourceName {
+			if !s.IsManual() {
+				return Source{}, fmt.Errorf("subscription %q already exists and is not a manual source", ManualSourceName)
+			}
+			return s, nil
+		}
+	}
+	source := Source{Name: ManualSourceName, Format: FormatManual}
+	source.NormalizeDefaults(ManualSourceName)
+	if err := SaveSource(configDir, source); err != nil {
+		return Source{}, err
+	}
+	return source, nil
+}
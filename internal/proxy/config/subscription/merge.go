@@ -18,7 +18,7 @@ func LoadNodesFromCache(sources []Source, cacheDir string) ([]model.Node, error)
 		}
 
 		cachePath := filepath.Join(cacheDir, s.Name+".json")
-		cache, err := LoadCache(cachePath)
+		cache, err := loadCacheShared(cachePath)
 		if err != nil {
 			logger.Error("Failed to load cache for source", "name", s.Name, "error", err)
 			continue
@@ -34,10 +34,15 @@ func LoadNodesFromCache(sources []Source, cacheDir string) ([]model.Node, error)
 			nodes = appendTags(nodes, s.Tags)
 		}
 
+		if _, err := model.ParseDedupeStrategy(s.DedupeStrategy); err != nil {
+			logger.Error("Invalid dedupe strategy, using strict", "name", s.Name, "error", err)
+		}
+
 		// Pass dedupe intention to the node level
 		for _, n := range nodes {
 			n.Source = s.Name
 			n.SkipDedupe = !s.DedupeValue()
+			n.DedupeStrategy = s.DedupeStrategyValue()
 			finalNodes = append(finalNodes, n)
 		}
 	}
@@ -45,7 +50,10 @@ func LoadNodesFromCache(sources []Source, cacheDir string) ([]model.Node, error)
 	return finalNodes, nil
 }
 
+// appendTags returns a copy of nodes with the tags appended to their names; the cached
+// slice itself is shared and left untouched.
 func appendTags(nodes []model.Node, tags []string) []model.Node {
+	nodes = append([]model.Node(nil), nodes...)
 	for i := range nodes {
 		for _, tag := range tags {
 			if !strings.Contains(nodes[i].Name, tag) {
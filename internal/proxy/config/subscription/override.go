@@ -0,0 +1,79 @@
+package subscription
+
+import (
+	"fmt"
+
+	moduleUtils "github.com/kyson-dev/sing-helm/internal/proxy/config/module/utils"
+	"github.com/sagernet/sing-box/option"
+)
+
+// MergeOverride deep-merges override into a copy of outbound. Nested objects are merged
+// key by key, any other value replaces the original, and a null value removes the key.
+// Neither input is modified.
+func MergeOverride(outbound, override map[string]any) map[string]any {
+	merged := make(map[string]any, len(outbound)+len(override))
+	for k, v := range outbound {
+		merged[k] = v
+	}
+	for k, v := range override {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		if src, ok := v.(map[string]any); ok {
+			if dst, ok := merged[k].(map[string]any); ok {
+				merged[k] = MergeOverride(dst, src)
+				continue
+			}
+			merged[k] = MergeOverride(nil, src)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// overrideNodeTypes are the outbound types subscriptions provide. An override is
+// checked against them when no fetched node is at hand, e.g. by 'config add'.
+var overrideNodeTypes = []string{
+	"shadowsocks", "vmess", "vless", "trojan", "hysteria", "hysteria2",
+	"tuic", "anytls", "shadowtls", "ssh", "http", "socks",
+}
+
+// ValidateOverride checks that the override cannot change a node's identity and
+// that at least one node type accepts its fields, so typos are reported when the
+// override is written rather than when the next build merges it into the nodes.
+func ValidateOverride(override map[string]any) error {
+	for _, key := range []string{"type", "tag"} {
+		if _, ok := override[key]; ok {
+			return fmt.Errorf("override must not set %q", key)
+		}
+	}
+	if len(override) == 0 {
+		return nil
+	}
+	var first error
+	for _, nodeType := range overrideNodeTypes {
+		err := ValidateOutbound(nodeType, MergeOverride(nil, override))
+		if err == nil {
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return fmt.Errorf("override does not apply to any node type: %w", first)
+}
+
+// ValidateOutbound decodes outbound into sing-box's option types so unknown fields and
+// invalid values are reported instead of being dropped silently.
+func ValidateOutbound(nodeType string, outbound map[string]any) error {
+	m := make(map[string]any, len(outbound)+2)
+	for k, v := range outbound {
+		m[k] = v
+	}
+	m["type"] = nodeType
+	m["tag"] = "override-check"
+	var out option.Outbound
+	return moduleUtils.ApplyMapToOutbound(&out, m)
+}
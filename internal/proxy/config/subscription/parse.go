@@ -170,21 +170,13 @@ func parseBase64URI(content []byte) ([]model.Node, error) {
 			continue
 		}
 
-		idx := strings.Index(line, "://")
-		if idx < 0 {
+		if !strings.Contains(line, "://") {
 			continue
 		}
 
-		scheme := strings.ToLower(line[:idx])
-		a, err := adapter.Get(scheme)
+		n, err := ParseURI(line)
 		if err != nil {
-			logger.Debug("Skipping proxy node", "scheme", scheme, "error", err.Error())
-			continue
-		}
-
-		n, err := a.FromURI(line[idx+3:])
-		if err != nil {
-			logger.Debug("Failed to parse URI node", "scheme", scheme, "error", err.Error())
+			logger.Debug("Skipping URI node", "error", err.Error())
 			continue
 		}
 
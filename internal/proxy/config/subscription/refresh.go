@@ -14,6 +14,9 @@ import (
 
 // Refresh downloads a subscription and updates its cache
 func Refresh(ctx context.Context, source Source, cacheDir string) error {
+	if source.IsManual() {
+		return ErrManualSource
+	}
 	logger.Info("Refreshing subscription", "name", source.Name, "url", source.URL)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", source.URL, nil)
@@ -47,6 +50,10 @@ func Refresh(ctx context.Context, source Source, cacheDir string) error {
 
 	logger.Info("Successfully parsed nodes", "count", len(nodes))
 
+	for i := range nodes {
+		nodes[i].ID = nodes[i].StableID()
+	}
+
 	cache := Cache{
 		Source:    source,
 		UpdatedAt: time.Now().Format(time.RFC3339),
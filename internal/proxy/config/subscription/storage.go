@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // LoadSources reads all .json subscription definitions from the config directory
@@ -85,6 +87,48 @@ func LoadCache(cachePath string) (*Cache, error) {
 	return &cache, nil
 }
 
+// cacheMemo keeps decoded cache files keyed by path, reusing them as long as the file's
+// modification time and size are unchanged. Decoding is the dominant cost of loading
+// large subscriptions, and the files only change on refresh.
+var cacheMemo = struct {
+	sync.Mutex
+	entries map[string]memoizedCache
+}{entries: make(map[string]memoizedCache)}
+
+type memoizedCache struct {
+	modTime time.Time
+	size    int64
+	cache   *Cache
+}
+
+// loadCacheShared is LoadCache backed by cacheMemo. The returned cache is shared
+// between callers and must not be modified.
+func loadCacheShared(cachePath string) (*Cache, error) {
+	info, err := os.Stat(cachePath)
+	if err != nil {
+		cacheMemo.Lock()
+		delete(cacheMemo.entries, cachePath)
+		cacheMemo.Unlock()
+		return nil, fmt.Errorf("read cache file failed: %w", err)
+	}
+
+	cacheMemo.Lock()
+	entry, ok := cacheMemo.entries[cachePath]
+	cacheMemo.Unlock()
+	if ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		return entry.cache, nil
+	}
+
+	cache, err := LoadCache(cachePath)
+	if err != nil {
+		return nil, err
+	}
+	cacheMemo.Lock()
+	cacheMemo.entries[cachePath] = memoizedCache{modTime: info.ModTime(), size: info.Size(), cache: cache}
+	cacheMemo.Unlock()
+	return cache, nil
+}
+
 // SaveCache saves parsed nodes back into the cache
 func SaveCache(cachePath string, cache Cache) error {
 	data, err := json.MarshalIndent(cache, "", "  ")
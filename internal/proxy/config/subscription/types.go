@@ -12,7 +12,12 @@ type Source struct {
 	Enabled  *bool    `json:"enabled"`
 	Priority int      `json:"priority"`
 	Dedupe   *bool    `json:"dedupe"`
-	Tags     []string `json:"tags,omitempty"`
+	// DedupeStrategy: strict (default), endpoint or name
+	DedupeStrategy string   `json:"dedupe_strategy,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	// Override is deep-merged into the outbound of every node from this source,
+	// e.g. {"multiplex": {"enabled": true, "protocol": "h2mux"}, "tcp_fast_open": true}.
+	Override map[string]any `json:"override,omitempty"`
 }
 
 // Cache stores parsed nodes from a subscription source.
@@ -47,6 +52,16 @@ func (s Source) EnabledValue() bool {
 	return *s.Enabled
 }
 
+// DedupeStrategyValue returns the normalized dedupe strategy, falling back to strict
+// when the configured value is unknown.
+func (s Source) DedupeStrategyValue() string {
+	strategy, err := model.ParseDedupeStrategy(s.DedupeStrategy)
+	if err != nil {
+		return model.DedupeStrict
+	}
+	return strategy
+}
+
 func (s Source) DedupeValue() bool {
 	if s.Dedupe == nil {
 		return true
@@ -16,6 +16,8 @@ type instance struct {
 	mu   sync.Mutex
 	once sync.Once
 	box  *box.Box
+
+	onError ErrorHandler
 }
 
 func NewInstance() *instance {
@@ -33,6 +35,13 @@ func (s *instance) Stop() {
 	}
 }
 
+// SetErrorHandler 设置之后启动的实例的 error 级别日志回调
+func (s *instance) SetErrorHandler(h ErrorHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onError = h
+}
+
 // ReloadFromFile 从配置文件重新加载 sing-box
 func (s *instance) ReloadFromFile(ctx context.Context, configPath string) error {
 	if s.box != nil {
@@ -66,6 +75,29 @@ func isAlreadyClosedError(err error) bool {
 	return errStr == "file already closed" || errStr == "use of closed file"
 }
 
+// CheckFromFile 校验配置文件：与 sing-box check 相同，创建 box 实例但不启动，随即关闭。
+// 用于在关闭运行中的实例之前确认新配置可用
+func (s *instance) CheckFromFile(ctx context.Context, configPath string) error {
+	opts, err := config.LoadOptionsWithContext(ctx, configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	checkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	checkBox, err := box.New(box.Options{
+		Context:           include.Context(checkCtx),
+		Options:           *opts,
+		PlatformLogWriter: NewPlatformWriter(),
+	})
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if err := checkBox.Close(); err != nil {
+		logger.Error("Failed to close check box instance", "error", err)
+	}
+	return nil
+}
+
 // StartFromFile 从配置文件启动 sing-box
 func (s *instance) StartFromFile(ctx context.Context, configPath string) error {
 	// 从文件加载配置
@@ -94,7 +126,7 @@ func (s *instance) Start(ctx context.Context, opts *option.Options) error {
 	newBox, err := box.New(box.Options{
 		Context:           tx,
 		Options:           *opts,
-		PlatformLogWriter: NewPlatformWriter(), // 将 sing-box 日志重定向到我们的 logger
+		PlatformLogWriter: &PlatformWriter{onError: s.onError}, // 将 sing-box 日志重定向到我们的 logger
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create box instance: %w", err)
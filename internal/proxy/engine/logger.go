@@ -9,7 +9,13 @@ import (
 
 // PlatformWriter 实现 sing-box 的 log.PlatformWriter 接口
 // 将 sing-box 的日志重定向到我们的 slog logger
-type PlatformWriter struct{}
+type PlatformWriter struct {
+	onError ErrorHandler // 收到 error 级别日志时回调，可为 nil
+}
+
+// ErrorHandler 接收 sing-box 的 error 级别日志（已去除颜色），供 daemon 判断内核健康状况。
+// 在 sing-box 的日志 goroutine 中调用，不能阻塞
+type ErrorHandler func(message string)
 
 var ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
 
@@ -34,6 +40,9 @@ func (p *PlatformWriter) WriteMessage(level log.Level, message string) {
 		logger.Info("[WARN] "+clean, "source", "sing-box")
 	case log.LevelError, log.LevelFatal, log.LevelPanic:
 		logger.Error(clean, "source", "sing-box")
+		if p.onError != nil {
+			p.onError(clean)
+		}
 	default:
 		logger.Info(clean, "source", "sing-box")
 	}
@@ -0,0 +1,196 @@
+// Package history persists node latency probes so reliability can be judged
+// from more than a single sample.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MaxSamples is the number of probes kept per node; older ones are dropped.
+const MaxSamples = 100
+
+// Sample is a single latency probe. Delay is 0 when the probe failed.
+type Sample struct {
+	Time  time.Time `json:"t"`
+	Delay int       `json:"d"`
+}
+
+// OK reports whether the probe succeeded.
+func (s Sample) OK() bool {
+	return s.Delay > 0
+}
+
+// Stats summarises the recorded samples of a node.
+type Stats struct {
+	Samples     int       `json:"samples"`
+	P50         int       `json:"p50"` // milliseconds, over successful probes
+	P95         int       `json:"p95"`
+	SuccessRate float64   `json:"success_rate"` // 0..1
+	LastDelay   int       `json:"last_delay"`   // 0 when the last probe failed
+	LastTested  time.Time `json:"last_tested"`
+	LastAlive   time.Time `json:"last_alive,omitempty"`
+}
+
+// Store keeps the latency samples of every node keyed by its stable ID and
+// writes them to a JSON file.
+type Store struct {
+	mu      sync.Mutex
+	saveMu  sync.Mutex // serialises Save so an older snapshot never replaces a newer one
+	path    string
+	nodes   map[string][]Sample
+	version uint64 // bumped by Record
+	saved   uint64 // version last written to disk
+}
+
+// Open loads the store at path. A missing or unreadable file yields an empty
+// store so a corrupt history never blocks the daemon.
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, nodes: make(map[string][]Sample)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, fmt.Errorf("failed to read latency history: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.nodes); err != nil {
+		s.nodes = make(map[string][]Sample)
+		return s, fmt.Errorf("failed to parse latency history: %w", err)
+	}
+	return s, nil
+}
+
+// Record appends a probe result for key. Pass delay 0 for a failed probe.
+func (s *Store) Record(key string, delay int, at time.Time) {
+	if key == "" {
+		return
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples := append(s.nodes[key], Sample{Time: at, Delay: delay})
+	if len(samples) > MaxSamples {
+		samples = append([]Sample(nil), samples[len(samples)-MaxSamples:]...)
+	}
+	s.nodes[key] = samples
+	s.version++
+}
+
+// Stats returns the summary for key.
+func (s *Store) Stats(key string) (Stats, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	samples, ok := s.nodes[key]
+	if !ok || len(samples) == 0 {
+		return Stats{}, false
+	}
+	return summarize(samples), true
+}
+
+// All returns the summary of every node in the store.
+func (s *Store) All() map[string]Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]Stats, len(s.nodes))
+	for key, samples := range s.nodes {
+		if len(samples) > 0 {
+			out[key] = summarize(samples)
+		}
+	}
+	return out
+}
+
+// Save writes the store to disk atomically. It does nothing when no sample was
+// recorded since the last successful Save.
+func (s *Store) Save() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	if s.version == s.saved {
+		s.mu.Unlock()
+		return nil
+	}
+	version := s.version
+	data, err := json.Marshal(s.nodes)
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal latency history: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.saved = version
+	s.mu.Unlock()
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write latency history: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write latency history: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write latency history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write latency history: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace latency history: %w", err)
+	}
+	return nil
+}
+
+func summarize(samples []Sample) Stats {
+	st := Stats{Samples: len(samples)}
+	var delays []int
+	for _, sample := range samples {
+		if sample.OK() {
+			delays = append(delays, sample.Delay)
+			if sample.Time.After(st.LastAlive) {
+				st.LastAlive = sample.Time
+			}
+		}
+	}
+	last := samples[len(samples)-1]
+	st.LastDelay = last.Delay
+	st.LastTested = last.Time
+	st.SuccessRate = float64(len(delays)) / float64(len(samples))
+	if len(delays) > 0 {
+		sort.Ints(delays)
+		st.P50 = percentile(delays, 50)
+		st.P95 = percentile(delays, 95)
+	}
+	return st
+}
+
+// percentile uses the nearest-rank method on sorted values.
+func percentile(sorted []int, p int) int {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
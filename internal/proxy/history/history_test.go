@@ -0,0 +1,106 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStoreStats(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "latency.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 20; i++ {
+		s.Record("node-a", i*10, base.Add(time.Duration(i)*time.Minute))
+	}
+	s.Record("node-a", 0, base.Add(21*time.Minute))
+	s.Record("node-a", 0, base.Add(22*time.Minute))
+
+	st, ok := s.Stats("node-a")
+	if !ok {
+		t.Fatal("missing stats")
+	}
+	if st.Samples != 22 || st.P50 != 100 || st.P95 != 190 {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+	if st.SuccessRate != 20.0/22.0 {
+		t.Fatalf("success rate = %v", st.SuccessRate)
+	}
+	if st.LastDelay != 0 || !st.LastAlive.Equal(base.Add(20*time.Minute)) || !st.LastTested.Equal(base.Add(22*time.Minute)) {
+		t.Fatalf("unexpected last fields: %+v", st)
+	}
+
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got, _ := reopened.Stats("node-a"); got != st {
+		t.Fatalf("stats changed after reload: %+v vs %+v", got, st)
+	}
+}
+
+func TestStoreKeepsRecentSamples(t *testing.T) {
+	s, _ := Open(filepath.Join(t.TempDir(), "latency.json"))
+	now := time.Now()
+	for i := 0; i < MaxSamples+10; i++ {
+		s.Record("node-a", 0, now)
+	}
+	s.Record("node-a", 50, now)
+	st, _ := s.Stats("node-a")
+	if st.Samples != MaxSamples {
+		t.Fatalf("samples = %d, want %d", st.Samples, MaxSamples)
+	}
+	if _, ok := s.Stats("missing"); ok {
+		t.Fatal("unexpected stats for unknown node")
+	}
+}
+
+func TestStoreConcurrentSaves(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "latency.json")
+	s, _ := Open(path)
+	now := time.Now()
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			s.Record(fmt.Sprintf("node-%d", i), 10+i, now)
+			if err := s.Save(); err != nil {
+				t.Errorf("Save: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got := len(reopened.All()); got != 20 {
+		t.Fatalf("expected all 20 nodes on disk, got %d", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected only latency.json to remain, got %d files", len(entries))
+	}
+
+	// Nothing recorded since the last Save: the file must not be rewritten.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected Save without new samples to skip writing, stat err = %v", err)
+	}
+}
@@ -3,7 +3,9 @@ package ipc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"time"
 )
@@ -13,6 +15,11 @@ type CommandSender interface {
 	Send(ctx context.Context, cmd CommandMessage) (CommandResult, error)
 }
 
+// CommandSubscriber opens streaming commands such as subscribe.
+type CommandSubscriber interface {
+	Subscribe(ctx context.Context, cmd CommandMessage, fn func(json.RawMessage) error) error
+}
+
 // UnixSender dials the unix socket each time Send is invoked.
 type UnixSender struct {
 	Socket  string
@@ -44,14 +51,19 @@ func (s *UnixSender) Send(ctx context.Context, cmd CommandMessage) (CommandResul
 	}
 	defer conn.Close()
 
-	if err := conn.SetDeadline(time.Now().Add(s.Timeout)); err != nil {
+	// 长耗时命令（如批量测速）可通过 ctx 的 deadline 延长等待时间
+	deadline := time.Now().Add(s.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.After(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
 		return CommandResult{}, err
 	}
 
 	encoder := json.NewEncoder(conn)
 	decoder := json.NewDecoder(conn)
 
-	if err := encoder.Encode(cmd); err != nil {
+	if err := encoder.Encode(withProtocol(cmd)); err != nil {
 		return CommandResult{}, fmt.Errorf("ipc: encode command: %w", err)
 	}
 
@@ -62,6 +74,66 @@ func (s *UnixSender) Send(ctx context.Context, cmd CommandMessage) (CommandResul
 	return resp, nil
 }
 
+// Subscribe sends a streaming command and calls fn with every value the daemon
+// pushes, until ctx is cancelled, the daemon closes the stream or fn returns an error.
+// A cancelled ctx ends the subscription without error.
+func (s *UnixSender) Subscribe(ctx context.Context, cmd CommandMessage, fn func(json.RawMessage) error) error {
+	if s == nil || s.Socket == "" {
+		return fmt.Errorf("ipc: invalid unix sender")
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(dialCtx, "unix", s.Socket)
+	if err != nil {
+		return fmt.Errorf("ipc: connect failed: %w", err)
+	}
+	defer conn.Close()
+
+	// ctx 取消时关闭连接以打断阻塞的读取
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	_ = conn.SetDeadline(time.Now().Add(s.Timeout))
+	if err := json.NewEncoder(conn).Encode(withProtocol(cmd)); err != nil {
+		return fmt.Errorf("ipc: encode command: %w", err)
+	}
+	decoder := json.NewDecoder(conn)
+	var resp CommandResult
+	if err := decoder.Decode(&resp); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("ipc: decode response: %w", err)
+	}
+	if err := resp.Err(); err != nil {
+		return fmt.Errorf("daemon error: %w", err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("ipc: stream closed by daemon")
+			}
+			return fmt.Errorf("ipc: read stream: %w", err)
+		}
+		// daemon 结束流之前可能推送一个错误结果
+		var result CommandResult
+		if json.Unmarshal(raw, &result) == nil && result.Status == "error" {
+			return fmt.Errorf("daemon error: %w", result.Err())
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+}
+
 // FakeSender allows CLI tests to inject deterministic responses.
 type FakeSender struct {
 	Response CommandResult
@@ -74,3 +146,17 @@ func (f *FakeSender) Send(ctx context.Context, cmd CommandMessage) (CommandResul
 	}
 	return f.Response, nil
 }
+
+// withProtocol records ProtocolVersion in the message meta without touching the caller's map.
+func withProtocol(cmd CommandMessage) CommandMessage {
+	if _, ok := cmd.Meta[MetaProtocol]; ok {
+		return cmd
+	}
+	meta := make(map[string]any, len(cmd.Meta)+1)
+	for k, v := range cmd.Meta {
+		meta[k] = v
+	}
+	meta[MetaProtocol] = ProtocolVersion
+	cmd.Meta = meta
+	return cmd
+}
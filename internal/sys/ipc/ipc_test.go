@@ -2,6 +2,7 @@ package ipc
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
@@ -84,3 +85,271 @@ func isPermissionDenied(err error) bool {
 	}
 	return false
 }
+
+type streamHandler struct {
+	HandlerFunc
+	values []string
+}
+
+func (h *streamHandler) StreamFor(cmd CommandMessage) StreamFunc {
+	if cmd.Name != "subscribe" {
+		return nil
+	}
+	return func(ctx context.Context, cmd CommandMessage, send func(any) error) error {
+		for _, v := range h.values {
+			if err := send(map[string]string{"value": v}); err != nil {
+				return err
+			}
+		}
+		<-ctx.Done()
+		return nil
+	}
+}
+
+func TestUnixSenderSubscribe(t *testing.T) {
+	dir, err := os.MkdirTemp(".", "ipc-test-")
+	if err != nil {
+		t.Fatalf("create socket dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "test.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := &streamHandler{
+		HandlerFunc: func(ctx context.Context, cmd CommandMessage) CommandResult {
+			return CommandResult{Status: "ok", Data: map[string]any{"received": cmd.Name}}
+		},
+		values: []string{"a", "b", "c"},
+	}
+	ready := make(chan struct{}, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Serve(ctx, socket, handler, &ServerOptions{Ready: ready})
+	}()
+	select {
+	case <-ready:
+	case err := <-errCh:
+		if err != nil {
+			if isPermissionDenied(err) {
+				t.Skipf("unix sockets unavailable: %v", err)
+				return
+			}
+			t.Fatalf("server failed: %v", err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+
+	sender := NewUnixSender(socket)
+
+	// 普通命令仍然是一问一答
+	resp, err := sender.Send(context.Background(), CommandMessage{Name: "status"})
+	if err != nil || resp.Data["received"] != "status" {
+		t.Fatalf("unexpected response %+v, err %v", resp, err)
+	}
+
+	subCtx, subCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer subCancel()
+	var got []string
+	err = sender.Subscribe(subCtx, CommandMessage{Name: "subscribe"}, func(raw json.RawMessage) error {
+		var v struct{ Value string }
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		got = append(got, v.Value)
+		if len(got) == 3 {
+			subCancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	if strings.Join(got, ",") != "a,b,c" {
+		t.Fatalf("unexpected stream values: %v", got)
+	}
+}
+
+func TestProtocolRoundTrip(t *testing.T) {
+	type base struct {
+		Extra []string `json:"extra,omitempty"`
+	}
+	type request struct {
+		Name    string    `json:"name"`
+		Count   int       `json:"count,omitempty"`
+		Enabled *bool     `json:"enabled,omitempty"`
+		When    time.Time `json:"when,omitzero"`
+		Skip    string    `json:"-"`
+		base
+	}
+
+	off := false
+	cmd, err := NewCommand("demo", request{Name: "a", Enabled: &off, Skip: "x", base: base{Extra: []string{"e"}}})
+	if err != nil {
+		t.Fatalf("new command: %v", err)
+	}
+	if _, ok := cmd.Payload["count"]; ok {
+		t.Fatalf("expected omitempty field to be dropped, got %v", cmd.Payload)
+	}
+	if _, ok := cmd.Payload["when"]; ok {
+		t.Fatalf("expected omitzero field to be dropped, got %v", cmd.Payload)
+	}
+	if _, ok := cmd.Payload["Skip"]; ok {
+		t.Fatalf("expected ignored field to be dropped, got %v", cmd.Payload)
+	}
+	if enabled, ok := cmd.Payload["enabled"].(bool); !ok || enabled {
+		t.Fatalf("expected pointer field to be stored dereferenced, got %v", cmd.Payload["enabled"])
+	}
+	if extra, ok := cmd.Payload["extra"].([]string); !ok || len(extra) != 1 {
+		t.Fatalf("expected embedded struct fields to be flattened, got %v", cmd.Payload)
+	}
+
+	// 经过线上编码后解码回结构体
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var wire CommandMessage
+	if err := json.Unmarshal(data, &wire); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	var got request
+	if err := wire.Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Name != "a" || got.Enabled == nil || *got.Enabled || len(got.Extra) != 1 {
+		t.Fatalf("unexpected decoded request: %+v", got)
+	}
+
+	bad := CommandMessage{Name: "demo", Payload: map[string]any{"count": "many"}}
+	if err := bad.Decode(&got); ErrorCode(err) != CodeInvalidRequest {
+		t.Fatalf("expected invalid_request, got %v", err)
+	}
+
+	result := OK(request{Name: "b", Count: 2})
+	if result.Status != "ok" || result.Err() != nil {
+		t.Fatalf("expected ok result, got %+v", result)
+	}
+	if count, ok := result.Data["count"].(int); !ok || count != 2 {
+		t.Fatalf("expected in-process data to keep Go types, got %v", result.Data)
+	}
+	var decoded request
+	if err := result.Decode(&decoded); err != nil || decoded.Name != "b" || decoded.Count != 2 {
+		t.Fatalf("unexpected decoded result: %+v err=%v", decoded, err)
+	}
+}
+
+func TestProtocolErrors(t *testing.T) {
+	notFound := errors.New("missing")
+	result := Fail(Errorf(CodeNotFound, "generation 3: %w", notFound))
+	if result.Status != "error" || result.Code != CodeNotFound {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	err := result.Err()
+	if ErrorCode(err) != CodeNotFound || err.Error() != "generation 3: missing" {
+		t.Fatalf("unexpected error: %v (code %q)", err, ErrorCode(err))
+	}
+
+	if code := Fail(errors.New("boom")).Code; code != CodeInternal {
+		t.Fatalf("expected uncoded errors to be internal, got %q", code)
+	}
+	if err := WithCode(CodeUnavailable, Errorf(CodeTimeout, "slow")); ErrorCode(err) != CodeTimeout {
+		t.Fatalf("expected WithCode to keep the existing code, got %q", ErrorCode(err))
+	}
+
+	// 旧版 daemon 的错误没有错误码
+	legacy := CommandResult{Status: "error", Error: "unknown command: x"}
+	if err := legacy.Err(); err == nil || ErrorCode(err) != "" || err.Error() != "unknown command: x" {
+		t.Fatalf("unexpected legacy error: %v", err)
+	}
+	if err := (CommandResult{}).Err(); err != nil {
+		t.Fatalf("expected empty status to be ok, got %v", err)
+	}
+}
+
+func TestUnixSenderSendsProtocol(t *testing.T) {
+	dir, err := os.MkdirTemp(".", "ipc-test-")
+	if err != nil {
+		t.Fatalf("create socket dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "test.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handler := HandlerFunc(func(ctx context.Context, cmd CommandMessage) CommandResult {
+		return CommandResult{Status: "ok", Data: map[string]any{"protocol": cmd.Protocol()}}
+	})
+	ready := make(chan struct{}, 1)
+	go func() { _ = Serve(ctx, socket, handler, &ServerOptions{Ready: ready}) }()
+	select {
+	case <-ready:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not start")
+	}
+
+	resp, err := NewUnixSender(socket).Send(ctx, CommandMessage{Name: "ping"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if v, _ := AsInt(resp.Data["protocol"]); v != ProtocolVersion {
+		t.Fatalf("expected protocol %d in meta, got %v", ProtocolVersion, resp.Data["protocol"])
+	}
+}
+
+// authHandler 只允许 ping，并回显服务端看到的客户端 uid
+type authHandler struct{}
+
+func (authHandler) Handle(ctx context.Context, cmd CommandMessage) CommandResult {
+	cred, ok := PeerFromContext(ctx)
+	return CommandResult{Status: "ok", Data: map[string]any{"known": ok, "uid": int(cred.UID), "pid": cred.PID}}
+}
+
+func (authHandler) Authorize(ctx context.Context, cmd CommandMessage) error {
+	if cmd.Name != "ping" {
+		return Errorf(CodePermissionDenied, "permission denied: %s", cmd.Name)
+	}
+	return nil
+}
+
+func TestServerAuthorizesWithPeerCredentials(t *testing.T) {
+	dir, err := os.MkdirTemp(".", "ipc-test-")
+	if err != nil {
+		t.Fatalf("create socket dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "test.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ready := make(chan struct{}, 1)
+	go func() { _ = Serve(ctx, socket, authHandler{}, &ServerOptions{Ready: ready}) }()
+	select {
+	case <-ready:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not start")
+	}
+
+	sender := NewUnixSender(socket)
+	resp, err := sender.Send(ctx, CommandMessage{Name: "ping"})
+	if err != nil {
+		t.Fatalf("send ping: %v", err)
+	}
+	if known, _ := resp.Data["known"].(bool); known {
+		if uid, _ := AsInt(resp.Data["uid"]); uid != os.Getuid() {
+			t.Fatalf("expected peer uid %d, got %v", os.Getuid(), resp.Data["uid"])
+		}
+		if pid, _ := AsInt(resp.Data["pid"]); pid != 0 && pid != os.Getpid() {
+			t.Fatalf("expected peer pid %d, got %v", os.Getpid(), resp.Data["pid"])
+		}
+	}
+
+	resp, err = sender.Send(ctx, CommandMessage{Name: "stop"})
+	if err != nil {
+		t.Fatalf("send stop: %v", err)
+	}
+	if resp.Code != CodePermissionDenied || !strings.Contains(resp.Error, "permission denied") {
+		t.Fatalf("expected stop to be denied, got %+v", resp)
+	}
+}
@@ -0,0 +1,56 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// errPeerCredUnsupported is returned on platforms without peer credentials.
+var errPeerCredUnsupported = errors.New("peer credentials are not supported on this platform")
+
+// PeerCred identifies the process on the other end of a Unix socket connection.
+type PeerCred struct {
+	UID uint32
+	GID uint32
+	PID int // 0 when the platform does not report it
+}
+
+func (p PeerCred) String() string {
+	return fmt.Sprintf("uid=%d gid=%d pid=%d", p.UID, p.GID, p.PID)
+}
+
+type peerKey struct{}
+
+// WithPeer returns a context carrying the credentials of the connected client.
+func WithPeer(ctx context.Context, cred PeerCred) context.Context {
+	return context.WithValue(ctx, peerKey{}, cred)
+}
+
+// PeerFromContext returns the client credentials attached by the server. It
+// reports false for in-process calls and when the credentials are unknown.
+func PeerFromContext(ctx context.Context) (PeerCred, bool) {
+	cred, ok := ctx.Value(peerKey{}).(PeerCred)
+	return cred, ok
+}
+
+// peerCredentials reads the credentials of the process connected to conn.
+func peerCredentials(conn net.Conn) (PeerCred, error) {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return PeerCred{}, fmt.Errorf("not a unix socket connection")
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return PeerCred{}, err
+	}
+	var cred PeerCred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = readPeerCred(int(fd))
+	}); err != nil {
+		return PeerCred{}, err
+	}
+	return cred, credErr
+}
@@ -0,0 +1,21 @@
+//go:build darwin
+
+package ipc
+
+import "golang.org/x/sys/unix"
+
+// readPeerCred uses LOCAL_PEERCRED (the getpeereid mechanism) and LOCAL_PEERPID.
+func readPeerCred(fd int) (PeerCred, error) {
+	xucred, err := unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	if err != nil {
+		return PeerCred{}, err
+	}
+	cred := PeerCred{UID: xucred.Uid}
+	if xucred.Ngroups > 0 {
+		cred.GID = xucred.Groups[0]
+	}
+	if pid, err := unix.GetsockoptInt(fd, unix.SOL_LOCAL, unix.LOCAL_PEERPID); err == nil {
+		cred.PID = pid
+	}
+	return cred, nil
+}
@@ -0,0 +1,14 @@
+//go:build linux
+
+package ipc
+
+import "golang.org/x/sys/unix"
+
+// readPeerCred uses SO_PEERCRED, which reports the credentials at connect time.
+func readPeerCred(fd int) (PeerCred, error) {
+	ucred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return PeerCred{}, err
+	}
+	return PeerCred{UID: ucred.Uid, GID: ucred.Gid, PID: int(ucred.Pid)}, nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package ipc
+
+// readPeerCred is not implemented on this platform; callers are treated as unknown.
+func readPeerCred(fd int) (PeerCred, error) {
+	return PeerCred{}, errPeerCredUnsupported
+}
@@ -0,0 +1,227 @@
+package ipc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ProtocolVersion is the version of the command set spoken over the socket.
+// It is bumped only on incompatible changes, such as a removed command or a
+// request/response field that changes meaning. New commands and new optional
+// fields do not bump it; clients discover commands through hello. The version
+// is exchanged by the hello handshake and sent in CommandMessage.Meta.
+const ProtocolVersion = 1
+
+// MetaProtocol is the CommandMessage.Meta key carrying the client's ProtocolVersion.
+const MetaProtocol = "protocol"
+
+// Error codes returned in CommandResult.Code so clients can branch on the
+// failure kind instead of matching error strings.
+const (
+	CodeUnknownCommand   = "unknown_command"   // the daemon does not implement the command
+	CodeInvalidRequest   = "invalid_request"   // missing or malformed request fields
+	CodeNotRunning       = "not_running"       // sing-box (or the daemon) is not running
+	CodeAlreadyRunning   = "already_running"   // sing-box is already running
+	CodeNotFound         = "not_found"         // a node, group or generation does not exist
+	CodeRejected         = "rejected"          // sing-box rejected the config, the previous one keeps running
+	CodeUnavailable      = "unavailable"       // a dependency such as the Clash API is unreachable
+	CodeTimeout          = "timeout"           // the operation did not finish in time
+	CodePermissionDenied = "permission_denied" // the client is not allowed to run the command
+	CodeInternal         = "internal"          // any other failure
+)
+
+// Error is a failure carrying one of the Code* values.
+type Error struct {
+	Code string
+	Err  error
+}
+
+// Errorf formats an error with the given code; %w verbs are preserved for errors.Is/As.
+func Errorf(code, format string, args ...any) error {
+	return &Error{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// WithCode attaches code to err unless err already carries a code.
+func WithCode(code string, err error) error {
+	if err == nil || ErrorCode(err) != "" {
+		return err
+	}
+	return &Error{Code: code, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Code
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns the code carried by err, or "" when there is none.
+func ErrorCode(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// OK builds a successful result whose Data holds the JSON fields of v, a struct
+// (or pointer to one) with json tags. Values keep their Go types, so in-process
+// callers can type-assert them, while the wire format matches json.Marshal(v).
+func OK(v any) CommandResult {
+	data, err := structFields(v)
+	if err != nil {
+		return Fail(Errorf(CodeInternal, "encode response: %w", err))
+	}
+	return CommandResult{Status: "ok", Data: data}
+}
+
+// Fail builds an error result from err, defaulting the code to CodeInternal.
+func Fail(err error) CommandResult {
+	code := ErrorCode(err)
+	if code == "" {
+		code = CodeInternal
+	}
+	return CommandResult{Status: "error", Code: code, Error: err.Error()}
+}
+
+// Err returns nil for a successful result and an *Error otherwise. Results from
+// daemons predating error codes carry an empty code.
+func (r CommandResult) Err() error {
+	if r.Status == "" || r.Status == "ok" {
+		return nil
+	}
+	msg := r.Error
+	if msg == "" {
+		msg = fmt.Sprintf("daemon responded with status %s", r.Status)
+	}
+	return &Error{Code: r.Code, Err: errors.New(msg)}
+}
+
+// Decode unmarshals Data into v, a pointer to a response struct.
+func (r CommandResult) Decode(v any) error {
+	return decodeMap(r.Data, v)
+}
+
+// NewCommand builds a message whose Payload holds the JSON fields of req (nil for none).
+func NewCommand(name string, req any) (CommandMessage, error) {
+	cmd := CommandMessage{Name: name}
+	if req == nil {
+		return cmd, nil
+	}
+	payload, err := structFields(req)
+	if err != nil {
+		return cmd, fmt.Errorf("encode %s request: %w", name, err)
+	}
+	cmd.Payload = payload
+	return cmd, nil
+}
+
+// Decode unmarshals Payload into v, a pointer to a request struct. Unknown fields
+// are ignored so older daemons accept requests from newer clients.
+func (c CommandMessage) Decode(v any) error {
+	if err := decodeMap(c.Payload, v); err != nil {
+		return Errorf(CodeInvalidRequest, "invalid %s request: %w", c.Name, err)
+	}
+	return nil
+}
+
+// Protocol returns the protocol version the client sent, or 0 for clients predating it.
+func (c CommandMessage) Protocol() int {
+	v, _ := AsInt(c.Meta[MetaProtocol])
+	return v
+}
+
+func decodeMap(m map[string]any, v any) error {
+	if len(m) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// structFields flattens the exported fields of a struct into a map keyed by
+// their json names, following encoding/json's rules for omitempty, omitzero, "-" and
+// embedded structs. Non-nil pointers are stored dereferenced.
+func structFields(v any) (map[string]any, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s is not a struct", rv.Type())
+	}
+	out := make(map[string]any)
+	addFields(rv, out)
+	return out, nil
+}
+
+func addFields(rv reflect.Value, out map[string]any) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		fv := rv.Field(i)
+		if field.Anonymous && name == "" {
+			for fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					break
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				addFields(fv, out)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && isEmptyValue(fv) ||
+			strings.Contains(","+opts+",", ",omitzero,") && fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				out[name] = nil
+				continue
+			}
+			fv = fv.Elem()
+		}
+		out[name] = fv.Interface()
+	}
+}
+
+// isEmptyValue reports whether omitempty drops v, as encoding/json does.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
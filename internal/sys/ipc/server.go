@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
@@ -12,7 +13,8 @@ import (
 )
 
 const (
-	unixSocketPerm = 0666 // 允许所有用户读写，以便普通用户可以连接 root 启动的 daemon
+	unixSocketPerm     = 0666             // 允许所有用户读写，以便普通用户可以连接 root 启动的 daemon
+	streamWriteTimeout = 10 * time.Second // 流式连接单次写入的超时
 )
 
 // ServerOptions control Serve behavior.
@@ -99,13 +101,60 @@ func serveConn(ctx context.Context, conn net.Conn, handler CommandHandler, opts
 
 	var cmd CommandMessage
 	if err := decoder.Decode(&cmd); err != nil {
-		encoder.Encode(CommandResult{Status: "error", Error: fmt.Sprintf("decode error: %v", err)})
+		encoder.Encode(Fail(Errorf(CodeInvalidRequest, "decode error: %v", err)))
 		return
 	}
 
+	// 附带客户端身份，供 handler 做权限判断
+	if cred, err := peerCredentials(conn); err == nil {
+		ctx = WithPeer(ctx, cred)
+	}
+	if a, ok := handler.(Authorizer); ok {
+		if err := a.Authorize(ctx, cmd); err != nil {
+			encoder.Encode(Fail(err))
+			return
+		}
+	}
+
+	if sh, ok := handler.(StreamHandler); ok {
+		if stream := sh.StreamFor(cmd); stream != nil {
+			serveStream(ctx, conn, encoder, cmd, stream, opts)
+			return
+		}
+	}
+
 	resp := handler.Handle(ctx, cmd)
 	if resp.Status == "" {
 		resp.Status = "ok"
 	}
 	encoder.Encode(resp)
 }
+
+// serveStream 先回复 ok，然后持续推送 stream 产生的值，直到客户端断开或 daemon 退出
+func serveStream(ctx context.Context, conn net.Conn, encoder *json.Encoder, cmd CommandMessage, stream StreamFunc, opts *ServerOptions) {
+	// 流式连接长期保持，不受请求的读超时限制
+	_ = conn.SetDeadline(time.Time{})
+	writeTimeout := streamWriteTimeout
+	if opts != nil && opts.WriteTimeout > 0 {
+		writeTimeout = opts.WriteTimeout
+	}
+	send := func(v any) error {
+		// 客户端长时间不读取时放弃该连接，避免阻塞推送方
+		_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return encoder.Encode(v)
+	}
+	if err := send(CommandResult{Status: "ok"}); err != nil {
+		return
+	}
+
+	// 客户端订阅后不再发送数据，读到 EOF 即表示已断开
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		cancel()
+	}()
+	if err := stream(streamCtx, cmd, send); err != nil && streamCtx.Err() == nil {
+		_ = send(Fail(err))
+	}
+}
@@ -11,7 +11,8 @@ type CommandMessage struct {
 
 // CommandResult is returned by the daemon to the CLI that issued the CommandMessage.
 type CommandResult struct {
-	Status string                 `json:"status"` // e.g. "ok", "error"
+	Status string                 `json:"status"`         // e.g. "ok", "error"
+	Code   string                 `json:"code,omitempty"` // one of the Code* values when Status is "error"
 	Error  string                 `json:"error,omitempty"`
 	Data   map[string]any         `json:"data,omitempty"`
 	Extra  map[string]interface{} `json:"extra,omitempty"`
@@ -22,6 +23,25 @@ type CommandHandler interface {
 	Handle(ctx context.Context, cmd CommandMessage) CommandResult
 }
 
+// StreamFunc serves a streaming command: it pushes values with send until ctx is
+// cancelled (the client disconnected or the server stopped) or send fails.
+type StreamFunc func(ctx context.Context, cmd CommandMessage, send func(v any) error) error
+
+// StreamHandler is implemented by handlers that support streaming commands, which
+// keep the connection open and push newline-delimited JSON values after the response.
+type StreamHandler interface {
+	// StreamFor returns the stream serving cmd, or nil for request/response commands.
+	StreamFor(cmd CommandMessage) StreamFunc
+}
+
+// Authorizer is implemented by handlers that restrict who may run a command.
+// The server calls Authorize for every command received over the socket, with the
+// client credentials in ctx (see PeerFromContext), and replies with the error
+// instead of dispatching the command when it is not nil.
+type Authorizer interface {
+	Authorize(ctx context.Context, cmd CommandMessage) error
+}
+
 // HandlerFunc is a helper wrapper that lets a function satisfy CommandHandler.
 type HandlerFunc func(ctx context.Context, cmd CommandMessage) CommandResult
 
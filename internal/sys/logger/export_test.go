@@ -8,6 +8,7 @@ import (
 // ResetForTest 重置 logger 实例和 once，仅供测试使用
 func ResetForTest() {
 	instance = nil
+	file = nil
 	once = sync.Once{}
 }
 
@@ -17,6 +17,7 @@ var (
 	instance *slog.Logger
 	config   Config
 	once     sync.Once
+	file     *lumberjack.Logger // 写入日志文件时的 writer，用于 Reopen
 )
 
 type Config struct {
@@ -47,6 +48,7 @@ func Setup(cfg Config) {
 			// 或者你可以用 io.MultiWriter(os.Stdout, fileLogger)
 			// writer = io.MultiWriter(os.Stdout, fileLogger)
 			writer = fileLogger
+			file = fileLogger
 		}
 
 		ops := &slog.HandlerOptions{
@@ -98,6 +100,11 @@ func Info(msg string, args ...any) {
 	logInternal(slog.LevelInfo, msg, args...)
 }
 
+// Warn logs at Warn level with correct source location
+func Warn(msg string, args ...any) {
+	logInternal(slog.LevelWarn, msg, args...)
+}
+
 // Error logs at Error level with correct source location
 func Error(msg string, args ...any) {
 	logInternal(slog.LevelError, msg, args...)
@@ -108,6 +115,15 @@ func Debug(msg string, args ...any) {
 	logInternal(slog.LevelDebug, msg, args...)
 }
 
+// Reopen closes the log file so the next write reopens it at the configured path.
+// Call it after the file was moved away by external rotation (e.g. logrotate).
+func Reopen() error {
+	if file == nil {
+		return nil
+	}
+	return file.Close()
+}
+
 // IsDebug returns whether debug mode is enabled
 func IsDebug() bool {
 	return config.Debug
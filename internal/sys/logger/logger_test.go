@@ -51,3 +51,27 @@ func TestLogger_FileConfig(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, string(content), "test file log content")
 }
+
+func TestLogger_ReopenAfterExternalRotation(t *testing.T) {
+	logger.ResetForTest() // Reset state
+
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "test.log")
+	logger.Setup(logger.Config{FilePath: logPath})
+	logger.Info("before rotation")
+
+	// Simulate logrotate moving the file away
+	rotated := logPath + ".1"
+	require.NoError(t, os.Rename(logPath, rotated))
+	require.NoError(t, logger.Reopen())
+	logger.Info("after rotation")
+
+	content, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "after rotation")
+	assert.NotContains(t, string(content), "before rotation")
+
+	old, err := os.ReadFile(rotated)
+	require.NoError(t, err)
+	assert.NotContains(t, string(old), "after rotation")
+}
@@ -0,0 +1,40 @@
+package netmon
+
+import "context"
+
+// Fake is a Monitor for tests: changes passed to Emit are delivered to the
+// channel returned by Watch.
+type Fake struct {
+	changes chan Change
+}
+
+// NewFake returns a Fake that buffers up to 16 changes before Emit blocks.
+func NewFake() *Fake {
+	return &Fake{changes: make(chan Change, 16)}
+}
+
+// Emit delivers c to the watcher.
+func (f *Fake) Emit(c Change) {
+	f.changes <- c
+}
+
+// Watch implements Monitor.
+func (f *Fake) Watch(ctx context.Context) (<-chan Change, error) {
+	out := make(chan Change)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case c := <-f.changes:
+				select {
+				case out <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
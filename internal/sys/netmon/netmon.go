@@ -0,0 +1,37 @@
+// Package netmon reports changes of the host network: interfaces going up or
+// down, addresses being added or removed and default route changes.
+package netmon
+
+import (
+	"context"
+	"fmt"
+)
+
+// Kind describes what part of the network changed.
+type Kind string
+
+const (
+	KindLink    Kind = "link"    // an interface appeared, disappeared or went up/down
+	KindAddress Kind = "address" // an address was added to or removed from an interface
+	KindRoute   Kind = "route"   // a default route was added or removed
+)
+
+// Change is a single network change.
+type Change struct {
+	Kind      Kind   `json:"kind"`
+	Interface string `json:"interface,omitempty"` // empty when unknown
+}
+
+func (c Change) String() string {
+	if c.Interface == "" {
+		return string(c.Kind)
+	}
+	return fmt.Sprintf("%s %s", c.Kind, c.Interface)
+}
+
+// Monitor watches the host network for changes.
+type Monitor interface {
+	// Watch starts watching and returns a channel that receives changes until
+	// ctx is cancelled, after which the channel is closed.
+	Watch(ctx context.Context) (<-chan Change, error)
+}
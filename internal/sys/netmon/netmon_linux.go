@@ -0,0 +1,263 @@
+//go:build linux
+
+package netmon
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// New returns the platform monitor. On Linux it listens to rtnetlink and falls
+// back to polling when the netlink socket cannot be opened.
+func New() Monitor {
+	return &netlinkMonitor{}
+}
+
+// netlinkMonitor subscribes to the rtnetlink link, address and route groups.
+type netlinkMonitor struct{}
+
+// Watch implements Monitor.
+func (m *netlinkMonitor) Watch(ctx context.Context) (<-chan Change, error) {
+	f, err := openNetlink()
+	if err != nil {
+		return NewPoller(DefaultPollInterval).Watch(ctx)
+	}
+	// 先订阅再读取当前状态，两者之间发生的变化不会遗漏（重复的消息不会产生 Change）
+	st := newNetlinkState()
+	if err := st.seed(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	context.AfterFunc(ctx, func() { f.Close() })
+
+	out := make(chan Change)
+	go func() {
+		defer close(out)
+		buf := make([]byte, 1<<16)
+		for {
+			n, err := f.Read(buf)
+			if errors.Is(err, unix.ENOBUFS) {
+				// 接收缓冲区溢出丢失了消息，重新读取当前状态并报告一次未知变化
+				if st.seed() == nil && !send(ctx, out, Change{Kind: KindLink}) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				return
+			}
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+			for _, msg := range msgs {
+				if c, ok := st.apply(msg); ok && !send(ctx, out, c) {
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func send(ctx context.Context, out chan<- Change, c Change) bool {
+	select {
+	case out <- c:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// openNetlink opens a non-blocking rtnetlink socket wrapped in an *os.File, so
+// reads go through the runtime poller and Close unblocks a pending Read.
+func openNetlink() (*os.File, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("open netlink socket: %w", err)
+	}
+	groups := uint32(unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR |
+		unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE)
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: groups}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("bind netlink socket: %w", err)
+	}
+	return os.NewFile(uintptr(fd), "netlink"), nil
+}
+
+type linkState struct {
+	name string
+	up   bool
+}
+
+// netlinkState tracks links, addresses and default routes so that repeated
+// notifications (IPv6 lifetime refreshes, link statistics) are not reported.
+type netlinkState struct {
+	links  map[int32]linkState
+	addrs  map[string]bool
+	routes map[string]bool
+}
+
+func newNetlinkState() *netlinkState {
+	return &netlinkState{
+		links:  make(map[int32]linkState),
+		addrs:  make(map[string]bool),
+		routes: make(map[string]bool),
+	}
+}
+
+// seed replaces the state with a dump of the current links, addresses and routes.
+func (s *netlinkState) seed() error {
+	fresh := newNetlinkState()
+	for _, proto := range []int{unix.RTM_GETLINK, unix.RTM_GETADDR, unix.RTM_GETROUTE} {
+		rib, err := syscall.NetlinkRIB(proto, unix.AF_UNSPEC)
+		if err != nil {
+			return fmt.Errorf("dump netlink state: %w", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(rib)
+		if err != nil {
+			return fmt.Errorf("parse netlink dump: %w", err)
+		}
+		for _, msg := range msgs {
+			fresh.apply(msg)
+		}
+	}
+	*s = *fresh
+	return nil
+}
+
+// apply updates the state with msg and reports whether it was a change.
+func (s *netlinkState) apply(msg syscall.NetlinkMessage) (Change, bool) {
+	switch msg.Header.Type {
+	case unix.RTM_NEWLINK, unix.RTM_DELLINK:
+		return s.applyLink(msg)
+	case unix.RTM_NEWADDR, unix.RTM_DELADDR:
+		return s.applyAddr(msg)
+	case unix.RTM_NEWROUTE, unix.RTM_DELROUTE:
+		return s.applyRoute(msg)
+	}
+	return Change{}, false
+}
+
+func (s *netlinkState) applyLink(msg syscall.NetlinkMessage) (Change, bool) {
+	if len(msg.Data) < unix.SizeofIfInfomsg {
+		return Change{}, false
+	}
+	index := int32(binary.NativeEndian.Uint32(msg.Data[4:8]))
+	flags := binary.NativeEndian.Uint32(msg.Data[8:12])
+	old, known := s.links[index]
+	if msg.Header.Type == unix.RTM_DELLINK {
+		delete(s.links, index)
+		return Change{Kind: KindLink, Interface: old.name}, known
+	}
+	cur := linkState{name: old.name, up: flags&unix.IFF_UP != 0 && flags&unix.IFF_RUNNING != 0}
+	if attrs, err := syscall.ParseNetlinkRouteAttr(&msg); err == nil {
+		for _, attr := range attrs {
+			if attr.Attr.Type == unix.IFLA_IFNAME {
+				cur.name = strings.TrimRight(string(attr.Value), "\x00")
+			}
+		}
+	}
+	s.links[index] = cur
+	return Change{Kind: KindLink, Interface: cur.name}, !known || old.up != cur.up
+}
+
+func (s *netlinkState) applyAddr(msg syscall.NetlinkMessage) (Change, bool) {
+	if len(msg.Data) < unix.SizeofIfAddrmsg {
+		return Change{}, false
+	}
+	// 链路本地地址（如 fe80::/64）随接口自动生成，不影响出站
+	if msg.Data[3] == unix.RT_SCOPE_LINK {
+		return Change{}, false
+	}
+	index := int32(binary.NativeEndian.Uint32(msg.Data[4:8]))
+	attrs, err := syscall.ParseNetlinkRouteAttr(&msg)
+	if err != nil {
+		return Change{}, false
+	}
+	var ip net.IP
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case unix.IFA_LOCAL:
+			ip = net.IP(attr.Value)
+		case unix.IFA_ADDRESS:
+			if ip == nil {
+				ip = net.IP(attr.Value)
+			}
+		}
+	}
+	if ip == nil {
+		return Change{}, false
+	}
+	key := fmt.Sprintf("%d/%s", index, ip)
+	return Change{Kind: KindAddress, Interface: s.name(index)}, s.set(s.addrs, key, msg.Header.Type == unix.RTM_NEWADDR)
+}
+
+func (s *netlinkState) applyRoute(msg syscall.NetlinkMessage) (Change, bool) {
+	if len(msg.Data) < unix.SizeofRtMsg {
+		return Change{}, false
+	}
+	family, dstLen, table, typ := msg.Data[0], msg.Data[1], uint32(msg.Data[4]), msg.Data[7]
+	attrs, err := syscall.ParseNetlinkRouteAttr(&msg)
+	if err != nil {
+		return Change{}, false
+	}
+	var oif int32
+	var gateway net.IP
+	var priority uint32
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case unix.RTA_TABLE:
+			if len(attr.Value) >= 4 {
+				table = binary.NativeEndian.Uint32(attr.Value)
+			}
+		case unix.RTA_OIF:
+			if len(attr.Value) >= 4 {
+				oif = int32(binary.NativeEndian.Uint32(attr.Value))
+			}
+		case unix.RTA_GATEWAY:
+			gateway = net.IP(attr.Value)
+		case unix.RTA_PRIORITY:
+			if len(attr.Value) >= 4 {
+				priority = binary.NativeEndian.Uint32(attr.Value)
+			}
+		}
+	}
+	// 只关心 main 表的默认路由；TUN 的 auto_route 使用独立的路由表
+	if dstLen != 0 || table != unix.RT_TABLE_MAIN || typ != unix.RTN_UNICAST {
+		return Change{}, false
+	}
+	key := fmt.Sprintf("%d/%d/%s/%d", family, oif, gateway, priority)
+	return Change{Kind: KindRoute, Interface: s.name(oif)}, s.set(s.routes, key, msg.Header.Type == unix.RTM_NEWROUTE)
+}
+
+// set adds or removes key and reports whether the set changed.
+func (s *netlinkState) set(m map[string]bool, key string, present bool) bool {
+	if m[key] == present {
+		return false
+	}
+	if present {
+		m[key] = true
+	} else {
+		delete(m, key)
+	}
+	return true
+}
+
+func (s *netlinkState) name(index int32) string {
+	if link, ok := s.links[index]; ok && link.name != "" {
+		return link.name
+	}
+	if iface, err := net.InterfaceByIndex(int(index)); err == nil {
+		return iface.Name
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package netmon
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// routeMessage builds an rtnetlink route message for a route to dst/dstLen via oif.
+func routeMessage(typ uint16, dstLen uint8, table uint8, oif uint32) syscall.NetlinkMessage {
+	data := make([]byte, unix.SizeofRtMsg)
+	data[0] = unix.AF_INET
+	data[1] = dstLen
+	data[4] = table
+	data[7] = unix.RTN_UNICAST
+	data = appendAttr(data, unix.RTA_OIF, binary.NativeEndian.AppendUint32(nil, oif))
+	data = appendAttr(data, unix.RTA_GATEWAY, net.IPv4(192, 168, 1, 1).To4())
+	return syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: typ}, Data: data}
+}
+
+func addrMessage(typ uint16, scope uint8, index uint32, ip net.IP) syscall.NetlinkMessage {
+	data := make([]byte, unix.SizeofIfAddrmsg)
+	data[0] = unix.AF_INET
+	data[3] = scope
+	binary.NativeEndian.PutUint32(data[4:8], index)
+	data = appendAttr(data, unix.IFA_LOCAL, ip.To4())
+	return syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: typ}, Data: data}
+}
+
+func appendAttr(data []byte, typ uint16, value []byte) []byte {
+	size := unix.SizeofRtAttr + len(value)
+	data = binary.NativeEndian.AppendUint16(data, uint16(size))
+	data = binary.NativeEndian.AppendUint16(data, typ)
+	data = append(data, value...)
+	for len(data)%4 != 0 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+func TestNetlinkStateReportsOnlyChanges(t *testing.T) {
+	s := newNetlinkState()
+	s.links[7] = linkState{name: "wlan0", up: true}
+
+	steps := []struct {
+		name string
+		msg  syscall.NetlinkMessage
+		want bool
+	}{
+		{"new default route", routeMessage(unix.RTM_NEWROUTE, 0, unix.RT_TABLE_MAIN, 7), true},
+		{"refreshed default route", routeMessage(unix.RTM_NEWROUTE, 0, unix.RT_TABLE_MAIN, 7), false},
+		{"non-default route", routeMessage(unix.RTM_NEWROUTE, 24, unix.RT_TABLE_MAIN, 7), false},
+		{"tun route table", routeMessage(unix.RTM_NEWROUTE, 0, 200, 7), false},
+		{"removed default route", routeMessage(unix.RTM_DELROUTE, 0, unix.RT_TABLE_MAIN, 7), true},
+		{"new address", addrMessage(unix.RTM_NEWADDR, unix.RT_SCOPE_UNIVERSE, 7, net.IPv4(192, 168, 1, 5)), true},
+		{"refreshed address", addrMessage(unix.RTM_NEWADDR, unix.RT_SCOPE_UNIVERSE, 7, net.IPv4(192, 168, 1, 5)), false},
+		{"link-local address", addrMessage(unix.RTM_NEWADDR, unix.RT_SCOPE_LINK, 7, net.IPv4(169, 254, 0, 5)), false},
+		{"removed address", addrMessage(unix.RTM_DELADDR, unix.RT_SCOPE_UNIVERSE, 7, net.IPv4(192, 168, 1, 5)), true},
+	}
+	for _, step := range steps {
+		c, changed := s.apply(step.msg)
+		if changed != step.want {
+			t.Fatalf("%s: expected changed=%v, got %v", step.name, step.want, changed)
+		}
+		if changed && c.Interface != "wlan0" {
+			t.Fatalf("%s: expected interface wlan0, got %q", step.name, c.Interface)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package netmon
+
+// New returns the platform monitor. Outside Linux this polls net.Interfaces.
+func New() Monitor {
+	return NewPoller(DefaultPollInterval)
+}
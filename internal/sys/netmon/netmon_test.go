@@ -0,0 +1,54 @@
+package netmon
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	prev := map[string]ifaceState{
+		"en0":  {up: true, addrs: "192.168.1.2/24"},
+		"en1":  {up: true, addrs: "10.0.0.2/24"},
+		"utun": {up: true},
+	}
+	next := map[string]ifaceState{
+		"en0": {up: true, addrs: "192.168.1.2/24"},
+		"en1": {up: true, addrs: "10.0.0.3/24"},
+		"en2": {up: false},
+	}
+	got := diff(prev, next)
+	want := []Change{
+		{Kind: KindAddress, Interface: "en1"},
+		{Kind: KindLink, Interface: "en2"},
+		{Kind: KindLink, Interface: "utun"},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if changes := diff(next, next); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+}
+
+func TestFake(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	f := NewFake()
+	ch, err := f.Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Emit(Change{Kind: KindRoute, Interface: "wlan0"})
+	select {
+	case c := <-ch:
+		if c.String() != "route wlan0" {
+			t.Fatalf("unexpected change %v", c)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("change not delivered")
+	}
+	cancel()
+	for range ch {
+	}
+}
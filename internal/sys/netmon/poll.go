@@ -0,0 +1,117 @@
+package netmon
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultPollInterval is how often a Poller compares interface snapshots.
+const DefaultPollInterval = 5 * time.Second
+
+// Poller detects interface and address changes by comparing snapshots of
+// net.Interfaces. It works on every platform but cannot see route changes and
+// only notices changes after up to one interval.
+type Poller struct {
+	Interval time.Duration
+}
+
+// NewPoller returns a Poller that takes a snapshot every interval.
+func NewPoller(interval time.Duration) *Poller {
+	return &Poller{Interval: interval}
+}
+
+// Watch implements Monitor.
+func (p *Poller) Watch(ctx context.Context) (<-chan Change, error) {
+	prev, err := snapshot()
+	if err != nil {
+		return nil, err
+	}
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	out := make(chan Change)
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			next, err := snapshot()
+			if err != nil {
+				continue
+			}
+			for _, c := range diff(prev, next) {
+				select {
+				case out <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+			prev = next
+		}
+	}()
+	return out, nil
+}
+
+// ifaceState is what a Poller compares for each interface.
+type ifaceState struct {
+	up    bool
+	addrs string // sorted, comma separated
+}
+
+func snapshot() (map[string]ifaceState, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]ifaceState, len(ifaces))
+	for _, iface := range ifaces {
+		var addrs []string
+		if list, err := iface.Addrs(); err == nil {
+			for _, addr := range list {
+				addrs = append(addrs, addr.String())
+			}
+		}
+		slices.Sort(addrs)
+		states[iface.Name] = ifaceState{
+			up:    iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagRunning != 0,
+			addrs: strings.Join(addrs, ","),
+		}
+	}
+	return states, nil
+}
+
+// diff returns the changes between two snapshots, ordered by interface name.
+func diff(prev, next map[string]ifaceState) []Change {
+	names := make([]string, 0, len(prev)+len(next))
+	for name := range prev {
+		names = append(names, name)
+	}
+	for name := range next {
+		if _, ok := prev[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var changes []Change
+	for _, name := range names {
+		old, hadOld := prev[name]
+		cur, hasCur := next[name]
+		switch {
+		case hadOld != hasCur || old.up != cur.up:
+			changes = append(changes, Change{Kind: KindLink, Interface: name})
+		case old.addrs != cur.addrs:
+			changes = append(changes, Change{Kind: KindAddress, Interface: name})
+		}
+	}
+	return changes
+}
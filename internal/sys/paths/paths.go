@@ -12,7 +12,14 @@ type Paths struct {
 	RuntimeDir      string // 运行时目录 (socket/lock/log/state)
 	RuntimeMetaFile string // runtime.json
 	ConfigFile      string // profile.json (用户配置)
+	SettingsFile    string // settings.json (daemon 行为设置)
 	RawConfigFile   string // raw.json (生成的完整配置)
+	NodeIndexFile   string // nodes.json (生成节点的 tag 与来源映射)
+	GenerationsDir  string // generations 目录 (最近生效的若干版本配置)
+	SpeedTestFile   string // speedtest.json (节点测速结果)
+	LatencyFile     string // latency.json (节点延迟历史)
+	PolicyFile      string // policy.json (IPC 命令的访问控制)
+	HTTPTokenFile   string // http.token (HTTP 控制接口的访问令牌)
 	SubConfigDir    string // subscriptions 目录
 	SubCacheDir     string // subscriptions cache 目录
 	LogDir          string // log 目录
@@ -90,7 +97,14 @@ func getPath(home string, runtimeDir string, logDir string) Paths {
 		RuntimeDir:      runtimeDir,
 		RuntimeMetaFile: GetRuntimeMetaFileWithDir(runtimeDir),
 		ConfigFile:      filepath.Join(home, "profile.json"),
+		SettingsFile:    filepath.Join(home, "settings.json"),
 		RawConfigFile:   filepath.Join(runtimeDir, "raw.json"),
+		NodeIndexFile:   filepath.Join(runtimeDir, "nodes.json"),
+		GenerationsDir:  filepath.Join(runtimeDir, "generations"),
+		SpeedTestFile:   filepath.Join(runtimeDir, "speedtest.json"),
+		LatencyFile:     filepath.Join(runtimeDir, "latency.json"),
+		PolicyFile:      filepath.Join(runtimeDir, "policy.json"),
+		HTTPTokenFile:   filepath.Join(runtimeDir, "http.token"),
 		SubConfigDir:    filepath.Join(home, "subscriptions"),
 		SubCacheDir:     filepath.Join(home, "subscriptions", "cache"),
 		LogDir:          logDir,